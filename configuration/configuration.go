@@ -43,6 +43,7 @@ const (
 	varPostgresConnectionMaxIdle    = "postgres.connection.maxidle"
 	varPostgresConnectionMaxOpen    = "postgres.connection.maxopen"
 	varPopulateCommonTypes          = "populate.commontypes"
+	varWorkItemTypeMaxFields        = "workitemtype.maxfields"
 	varHTTPAddress                  = "http.address"
 	varDeveloperModeEnabled         = "developer.mode.enabled"
 	varGithubAuthToken              = "github.auth.token"
@@ -233,6 +234,14 @@ func (c *ConfigurationData) GetPopulateCommonTypes() bool {
 	return c.v.GetBool(varPopulateCommonTypes)
 }
 
+// GetWorkItemTypeMaxFields returns the maximum number of fields (including
+// inherited and system fields) a work item type may have (as set via config
+// file or environment variable). It returns 0 if no limit is configured, in
+// which case workitem.DefaultMaxFieldCount applies.
+func (c *ConfigurationData) GetWorkItemTypeMaxFields() int {
+	return c.v.GetInt(varWorkItemTypeMaxFields)
+}
+
 // GetHTTPAddress returns the HTTP address (as set via default, config file, or environment variable)
 // that the alm server binds to (e.g. "0.0.0.0:8080")
 func (c *ConfigurationData) GetHTTPAddress() string {
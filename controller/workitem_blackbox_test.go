@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"html"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -1020,9 +1021,12 @@ func (s *WorkItem2Suite) TestWI2SuccessCreateWithAssigneesRelation() {
 		},
 	}
 	_, wiu := test.UpdateWorkitemOK(s.T(), s.svc.Context, s.svc, s.wi2Ctrl, *wi.Data.ID, &update)
-	assert.Len(s.T(), wiu.Data.Relationships.Assignees.Data, 2)
-	assert.Equal(s.T(), newUser2.ID.String(), *wiu.Data.Relationships.Assignees.Data[0].ID)
-	assert.Equal(s.T(), newUser3.ID.String(), *wiu.Data.Relationships.Assignees.Data[1].ID)
+	// assignees are returned in canonical order
+	require.Len(s.T(), wiu.Data.Relationships.Assignees.Data, 2)
+	expectedIDs := []string{newUser2.ID.String(), newUser3.ID.String()}
+	sort.Strings(expectedIDs)
+	assert.Equal(s.T(), expectedIDs[0], *wiu.Data.Relationships.Assignees.Data[0].ID)
+	assert.Equal(s.T(), expectedIDs[1], *wiu.Data.Relationships.Assignees.Data[1].ID)
 }
 
 func (s *WorkItem2Suite) TestWI2ListByAssigneeFilter() {
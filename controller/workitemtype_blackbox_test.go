@@ -112,10 +112,15 @@ func (s *workItemTypeSuite) TearDownTest() {
 var (
 	animalID = uuid.FromStringOrNil("729431f2-bca4-4062-9087-c751807b569f")
 	personID = uuid.FromStringOrNil("22a1e4f1-7e9d-4ce8-ac87-fe7c79356b16")
+
+	// every work item type must define a title and a state
+	titleFieldDef = app.FieldDefinition{Required: true, Type: &app.FieldType{Kind: "string"}}
+	stateFieldDef = app.FieldDefinition{Required: true, Type: &app.FieldType{Kind: "string"}}
 )
 
 // createWorkItemTypeAnimal defines a work item type "animal" that consists of
-// two fields ("animal-type" and "color"). The type is mandatory but the color is not.
+// two fields ("animal-type" and "color") besides the mandatory system fields.
+// The type is mandatory but the color is not.
 func (s *workItemTypeSuite) createWorkItemTypeAnimal() (http.ResponseWriter, *app.WorkItemTypeSingle) {
 
 	// Create an enumeration of animal names
@@ -158,8 +163,10 @@ func (s *workItemTypeSuite) createWorkItemTypeAnimal() (http.ResponseWriter, *ap
 				Description: &desc,
 				Icon:        "fa-hand-lizard-o",
 				Fields: map[string]*app.FieldDefinition{
-					"animal_type": &typeFieldDef,
-					"color":       &colorFieldDef,
+					workitem.SystemTitle: &titleFieldDef,
+					workitem.SystemState: &stateFieldDef,
+					"animal_type":        &typeFieldDef,
+					"color":              &colorFieldDef,
 				},
 			},
 		},
@@ -174,7 +181,7 @@ func (s *workItemTypeSuite) createWorkItemTypeAnimal() (http.ResponseWriter, *ap
 }
 
 // createWorkItemTypePerson defines a work item type "person" that consists of
-// a required "name" field.
+// a required "name" field besides the mandatory system fields.
 func (s *workItemTypeSuite) createWorkItemTypePerson() (http.ResponseWriter, *app.WorkItemTypeSingle) {
 	// Create the type for the "color" field
 	nameFieldDef := app.FieldDefinition{
@@ -196,7 +203,9 @@ func (s *workItemTypeSuite) createWorkItemTypePerson() (http.ResponseWriter, *ap
 				Description: &desc,
 				Icon:        "fa-user",
 				Fields: map[string]*app.FieldDefinition{
-					"name": &nameFieldDef,
+					workitem.SystemTitle: &titleFieldDef,
+					workitem.SystemState: &stateFieldDef,
+					"name":               &nameFieldDef,
 				},
 			},
 		},
@@ -63,6 +63,22 @@ For example, if a bug blocks a user story, the reverse name name is "blocked by"
 	a.Attribute("topology", d.String, `The topology determines the restrictions placed on the usage of each work item link type.`, func() {
 		a.Enum("network")
 	})
+	a.Attribute("deprecated_at", d.DateTime, `When the work item link type was deprecated (optional).
+Deprecated link types are hidden from pickers but can still be listed.`)
+	a.Attribute("max_source_count", d.Integer, `The maximum number of sources a work item can be linked to as target with this link type (optional).
+If not set, the number is unlimited.`, func() {
+		a.Minimum(1)
+	})
+	a.Attribute("max_target_count", d.Integer, `The maximum number of targets a work item can be linked to as source with this link type (optional).
+If not set, the number is unlimited.`, func() {
+		a.Minimum(1)
+	})
+	a.Attribute("display_order", d.Integer, `The position of the work item link type in pickers (optional).
+Link types are listed by ascending display order.`, func() {
+		a.Example(1)
+	})
+	a.Attribute("is_default", d.Boolean, `Whether the work item link type is preselected when creating a link in its space (optional).
+A space has at most one default link type.`)
 
 	// IMPORTANT: We cannot require any field here because these "attributes" will be used
 	// during the creation as well as the update of a work item link type.
@@ -203,6 +219,7 @@ var _ = a.Resource("work-item-link-type", func() {
 		})
 		a.Response(d.OK)
 		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.Conflict, JSONAPIErrors)
 		a.Response(d.InternalServerError, JSONAPIErrors)
 		a.Response(d.NotFound, JSONAPIErrors)
 		a.Response(d.Unauthorized, JSONAPIErrors)
@@ -29,6 +29,27 @@ var fieldDefinition = a.Type("fieldDefinition", func() {
 		a.Example("The iteration field tells to which iteration a work item belongs.")
 		a.MinLength(1)
 	})
+	a.Attribute("unit", d.String, "An optional unit of a numeric or duration field", func() {
+		a.Example("hours")
+	})
+	a.Attribute("deprecated", d.Boolean, "Whether the field is phased out and should no longer be offered for new work items")
+	a.Attribute("position", d.Integer, "The position of the field when the fields are shown in order; fields without a position come last", func() {
+		a.Example(1)
+		a.Minimum(0)
+	})
+	a.Attribute("pattern", d.String, "An optional regular expression that the complete value of a string field must match", func() {
+		a.Example("[A-Z]+-\\d+")
+	})
+	a.Attribute("value_labels", a.HashOf(d.String, d.String), "Optional display labels of the values of an enum field, keyed by value")
+	a.Attribute("precision", d.Integer, "The optional maximum number of digits of the value of a numeric field", func() {
+		a.Example(10)
+		a.Minimum(1)
+	})
+	a.Attribute("scale", d.Integer, "The optional maximum number of digits after the decimal point of the value of a numeric field", func() {
+		a.Example(2)
+		a.Minimum(0)
+	})
+	a.Attribute("state_transitions", a.HashOf(d.String, a.ArrayOf(d.String)), "Optional transitions of an enum field, mapping each value to the values it may be changed to; if not set, all changes are allowed")
 	a.Required("required", "type", "label", "description")
 })
 
@@ -1,11 +1,18 @@
 package errors
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 const (
 	stBadParameterErrorMsg         = "Bad value for parameter '%s': '%v'"
 	stBadParameterErrorExpectedMsg = "Bad value for parameter '%s': '%v' (expected: '%v')"
 	stNotFoundErrorMsg             = "%s with id '%s' not found"
+	stReferencedNotFoundErrorMsg   = "Referenced %s with id '%s' not found"
+	stInUseErrorMsg                = "%s with id '%s' is still in use by %d %s"
+	stTopologyErrorMsg             = "Invalid topology '%s' (expected one of: '%s')"
+	stTopologyErrorLinkTypeMsg     = "Invalid topology '%s' for work item link type '%s' (expected one of: '%s')"
 )
 
 // Constants that can be used to identify internal server errors
@@ -104,3 +111,67 @@ func (err NotFoundError) Error() string {
 func NewNotFoundError(entity string, id string) NotFoundError {
 	return NotFoundError{entity: entity, ID: id}
 }
+
+// ReferencedEntityNotFoundError means that an entity referenced by the object
+// of the operation does not exist. Kind names the kind of the missing entity
+// (e.g. "space") so that callers can tell which reference is broken.
+type ReferencedEntityNotFoundError struct {
+	Kind string
+	ID   string
+}
+
+// Error implements the error interface
+func (err ReferencedEntityNotFoundError) Error() string {
+	return fmt.Sprintf(stReferencedNotFoundErrorMsg, err.Kind, err.ID)
+}
+
+// NewReferencedEntityNotFoundError returns the custom defined error of type ReferencedEntityNotFoundError.
+func NewReferencedEntityNotFoundError(kind string, id string) ReferencedEntityNotFoundError {
+	return ReferencedEntityNotFoundError{Kind: kind, ID: id}
+}
+
+// InUseError means that an entity cannot be removed because other entities
+// still depend on it. Count is the number of dependents of the given kind.
+type InUseError struct {
+	Kind           string
+	ID             string
+	Count          int
+	DependentsKind string
+}
+
+// Error implements the error interface
+func (err InUseError) Error() string {
+	return fmt.Sprintf(stInUseErrorMsg, err.Kind, err.ID, err.Count, err.DependentsKind)
+}
+
+// NewInUseError returns the custom defined error of type InUseError.
+func NewInUseError(kind string, id string, count int, dependentsKind string) InUseError {
+	return InUseError{Kind: kind, ID: id, Count: count, DependentsKind: dependentsKind}
+}
+
+// TopologyError means that a work item link type topology is not valid
+type TopologyError struct {
+	Topology        string
+	ValidTopologies []string
+	LinkTypeID      string
+}
+
+// Error implements the error interface
+func (err TopologyError) Error() string {
+	valid := strings.Join(err.ValidTopologies, "|")
+	if err.LinkTypeID != "" {
+		return fmt.Sprintf(stTopologyErrorLinkTypeMsg, err.Topology, err.LinkTypeID, valid)
+	}
+	return fmt.Sprintf(stTopologyErrorMsg, err.Topology, valid)
+}
+
+// WithLinkTypeID sets the optional ID of the work item link type on the TopologyError
+func (err TopologyError) WithLinkTypeID(linkTypeID string) TopologyError {
+	err.LinkTypeID = linkTypeID
+	return err
+}
+
+// NewTopologyError returns the custom defined error of type TopologyError.
+func NewTopologyError(topology string, validTopologies []string) TopologyError {
+	return TopologyError{Topology: topology, ValidTopologies: validTopologies}
+}
@@ -48,6 +48,25 @@ func TestNewNotFoundError(t *testing.T) {
 	assert.Equal(t, fmt.Sprintf("%s with id '%s' not found", param, value), err.Error())
 }
 
+func TestNewReferencedEntityNotFoundError(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	err := errors.NewReferencedEntityNotFoundError("space", "10")
+	assert.Equal(t, "space", err.Kind)
+	assert.Equal(t, "10", err.ID)
+	assert.Equal(t, "Referenced space with id '10' not found", err.Error())
+}
+
+func TestNewInUseError(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	err := errors.NewInUseError("work item type", "10", 3, "work items")
+	assert.Equal(t, "work item type", err.Kind)
+	assert.Equal(t, "10", err.ID)
+	assert.Equal(t, 3, err.Count)
+	assert.Equal(t, "work item type with id '10' is still in use by 3 work items", err.Error())
+}
+
 func TestNewUnauthorizedError(t *testing.T) {
 	t.Parallel()
 	resource.Require(t, resource.UnitTest)
@@ -56,3 +75,18 @@ func TestNewUnauthorizedError(t *testing.T) {
 
 	assert.Equal(t, msg, err.Error())
 }
+
+func TestNewTopologyError(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	valid := []string{"network", "tree"}
+	err := errors.NewTopologyError("foo", valid)
+	assert.Equal(t, "foo", err.Topology)
+	assert.Equal(t, valid, err.ValidTopologies)
+	assert.Equal(t, "", err.LinkTypeID)
+	assert.Equal(t, "Invalid topology 'foo' (expected one of: 'network|tree')", err.Error())
+
+	err = err.WithLinkTypeID("1234")
+	assert.Equal(t, "1234", err.LinkTypeID)
+	assert.Equal(t, "Invalid topology 'foo' for work item link type '1234' (expected one of: 'network|tree')", err.Error())
+}
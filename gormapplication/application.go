@@ -47,12 +47,13 @@ var x application.Application = &GormDB{}
 var y application.Application = &GormTransaction{}
 
 func NewGormDB(db *gorm.DB) *GormDB {
-	return &GormDB{GormBase{db}, ""}
+	return &GormDB{GormBase{db: db}, ""}
 }
 
 // GormBase is a base struct for gorm implementations of db & transaction
 type GormBase struct {
-	db *gorm.DB
+	db                    *gorm.DB
+	workItemTypeMaxFields int
 }
 
 type GormTransaction struct {
@@ -69,7 +70,7 @@ func (g *GormBase) WorkItems() workitem.WorkItemRepository {
 }
 
 func (g *GormBase) WorkItemTypes() workitem.WorkItemTypeRepository {
-	return workitem.NewWorkItemTypeRepository(g.db)
+	return workitem.NewWorkItemTypeRepositoryWithMaxFieldCount(g.db, g.workItemTypeMaxFields)
 }
 
 func (g *GormBase) Spaces() space.Repository {
@@ -149,6 +150,13 @@ func (g *GormDB) SetTransactionIsolationLevel(level TXIsoLevel) error {
 	return nil
 }
 
+// SetWorkItemTypeMaxFields sets the maximum number of fields of the work item
+// types created through this application and the transactions begun on it.
+// Values smaller than 1 select workitem.DefaultMaxFieldCount.
+func (g *GormDB) SetWorkItemTypeMaxFields(n int) {
+	g.workItemTypeMaxFields = n
+}
+
 // Begin implements TransactionSupport
 func (g *GormDB) BeginTransaction() (application.Transaction, error) {
 	tx := g.db.Begin()
@@ -160,9 +168,9 @@ func (g *GormDB) BeginTransaction() (application.Transaction, error) {
 		if tx.Error != nil {
 			return nil, tx.Error
 		}
-		return &GormTransaction{GormBase{tx}}, nil
+		return &GormTransaction{GormBase{tx, g.workItemTypeMaxFields}}, nil
 	}
-	return &GormTransaction{GormBase{tx}}, nil
+	return &GormTransaction{GormBase{tx, g.workItemTypeMaxFields}}, nil
 }
 
 // Commit implements TransactionSupport
@@ -12,8 +12,11 @@ import (
 
 const (
 	ErrorCodeNotFound          = "not_found"
+	ErrorCodeReferenceNotFound = "reference_not_found"
 	ErrorCodeBadParameter      = "bad_parameter"
+	ErrorCodeTopologyError     = "topology_error"
 	ErrorCodeVersionConflict   = "version_conflict"
+	ErrorCodeInUse             = "in_use"
 	ErrorCodeUnknownError      = "unknown_error"
 	ErrorCodeConversionError   = "conversion_error"
 	ErrorCodeInternalError     = "internal_error"
@@ -36,6 +39,10 @@ func ErrorToJSONAPIError(err error) (app.JSONAPIError, int) {
 		code = ErrorCodeNotFound
 		title = "Not found error"
 		statusCode = http.StatusNotFound
+	case errors.ReferencedEntityNotFoundError:
+		code = ErrorCodeReferenceNotFound
+		title = "Referenced entity not found error"
+		statusCode = http.StatusBadRequest
 	case errors.ConversionError:
 		code = ErrorCodeConversionError
 		title = "Conversion error"
@@ -44,10 +51,18 @@ func ErrorToJSONAPIError(err error) (app.JSONAPIError, int) {
 		code = ErrorCodeBadParameter
 		title = "Bad parameter error"
 		statusCode = http.StatusBadRequest
+	case errors.TopologyError:
+		code = ErrorCodeTopologyError
+		title = "Topology error"
+		statusCode = http.StatusBadRequest
 	case errors.VersionConflictError:
 		code = ErrorCodeVersionConflict
 		title = "Version conflict error"
 		statusCode = http.StatusBadRequest
+	case errors.InUseError:
+		code = ErrorCodeInUse
+		title = "Entity in use error"
+		statusCode = http.StatusConflict
 	case errors.InternalError:
 		code = ErrorCodeInternalError
 		title = "Internal error"
@@ -115,6 +130,11 @@ type Forbidden interface {
 	Forbidden(*app.JSONAPIErrors) error
 }
 
+// Conflict represent a Context that can return a Conflict HTTP status
+type Conflict interface {
+	Conflict(*app.JSONAPIErrors) error
+}
+
 // JSONErrorResponse auto maps the provided error to the correct response type
 // If all else fails, InternalServerError is returned
 func JSONErrorResponse(x InternalServerError, err error) error {
@@ -136,6 +156,10 @@ func JSONErrorResponse(x InternalServerError, err error) error {
 		if ctx, ok := x.(Forbidden); ok {
 			return errs.WithStack(ctx.Forbidden(jsonErr))
 		}
+	case http.StatusConflict:
+		if ctx, ok := x.(Conflict); ok {
+			return errs.WithStack(ctx.Conflict(jsonErr))
+		}
 	default:
 		return errs.WithStack(x.InternalServerError(jsonErr))
 	}
@@ -127,7 +127,7 @@ func main() {
 		ctx := migration.NewMigrationContext(context.Background())
 
 		if err := models.Transactional(db, func(tx *gorm.DB) error {
-			return migration.PopulateCommonTypes(ctx, tx, workitem.NewWorkItemTypeRepository(tx))
+			return migration.PopulateCommonTypes(ctx, tx, workitem.NewWorkItemTypeRepositoryWithMaxFieldCount(tx, configuration.GetWorkItemTypeMaxFields()))
 		}); err != nil {
 			log.Panic(ctx, map[string]interface{}{
 				"err": err,
@@ -185,6 +185,7 @@ func main() {
 	}
 
 	appDB := gormapplication.NewGormDB(db)
+	appDB.SetWorkItemTypeMaxFields(configuration.GetWorkItemTypeMaxFields())
 
 	loginService := login.NewKeycloakOAuthProvider(oauth, identityRepository, userRepository, tokenManager, appDB)
 	loginCtrl := controller.NewLoginController(service, loginService, tokenManager, configuration)
@@ -229,6 +229,27 @@ func getMigrations() migrations {
 	// Version 38
 	m = append(m, steps{executeSQLFile("038-comments-history.sql")})
 
+	// Version 39
+	m = append(m, steps{executeSQLFile("039-add-deprecated-at-to-wilt.sql")})
+
+	// Version 40
+	m = append(m, steps{executeSQLFile("040-add-multiplicity-to-wilt.sql")})
+
+	// Version 41
+	m = append(m, steps{executeSQLFile("041-add-display-order-to-wilt.sql")})
+
+	// Version 42
+	m = append(m, steps{executeSQLFile("042-work-item-link-type-versions.sql")})
+
+	// Version 43
+	m = append(m, steps{executeSQLFile("043-add-is-default-to-wilt.sql")})
+
+	// Version 44
+	m = append(m, steps{executeSQLFile("044-add-space-id-to-wilc.sql", space.SystemSpace.String())})
+
+	// Version 45
+	m = append(m, steps{executeSQLFile("045-add-space-id-to-wit.sql", space.SystemSpace.String())})
+
 	// Version N
 	//
 	// In order to add an upgrade, simply append an array of MigrationFunc to the
@@ -480,8 +501,7 @@ func createOrUpdateWorkItemLinkType(ctx context.Context, linkCatRepo *link.GormW
 	cause := errs.Cause(err)
 	switch cause.(type) {
 	case errors.NotFoundError:
-		_, err := linkTypeRepo.Create(ctx, lt.Name, lt.Description, lt.SourceTypeID, lt.TargetTypeID, lt.ForwardName, lt.ReverseName, lt.Topology, lt.LinkCategoryID, lt.SpaceID)
-		if err != nil {
+		if err := linkTypeRepo.CreateSystemType(ctx, &lt); err != nil {
 			return errs.WithStack(err)
 		}
 	case nil:
@@ -622,6 +642,9 @@ func createOrUpdateType(typeID uuid.UUID, name string, description string, exten
 		}
 		wit.Fields = convertedFields
 		wit.Path = path
+		if err := wit.CheckFieldCount(witr.MaxFieldCount()); err != nil {
+			return errs.WithStack(err)
+		}
 		db = db.Save(wit)
 		return db.Error
 	}
@@ -632,17 +655,15 @@ func loadFields(ctx context.Context, wit *workitem.WorkItemType, into workitem.F
 	// copy fields from wit
 	for key, value := range wit.Fields {
 		// do not overwrite already defined fields in the map
-		if _, exist := into[key]; !exist {
+		existing, exist := into[key]
+		if !exist {
 			into[key] = value
-		} else {
-			// If field already exist, overwrite only the label and description
-			into[key] = workitem.FieldDefinition{
-				Label:       value.Label,
-				Description: value.Description,
-				Required:    into[key].Required,
-				Type:        into[key].Type,
-			}
+			continue
 		}
+		// If field already exist, overwrite only the label and description
+		existing.Label = value.Label
+		existing.Description = value.Description
+		into[key] = existing
 	}
 
 	return nil
@@ -0,0 +1,2 @@
+-- deprecated link types are hidden from pickers but can still be listed
+ALTER TABLE work_item_link_types ADD COLUMN deprecated_at timestamp with time zone;
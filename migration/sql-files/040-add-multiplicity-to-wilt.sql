@@ -0,0 +1,3 @@
+-- optional limits for the number of links per source and target work item
+ALTER TABLE work_item_link_types ADD COLUMN max_source_count integer;
+ALTER TABLE work_item_link_types ADD COLUMN max_target_count integer;
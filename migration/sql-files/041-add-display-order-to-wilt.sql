@@ -0,0 +1,2 @@
+-- position of the link types in pickers
+ALTER TABLE work_item_link_types ADD COLUMN display_order integer NOT NULL DEFAULT 0;
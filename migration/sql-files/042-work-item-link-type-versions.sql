@@ -0,0 +1,8 @@
+-- past versions of work item link types
+CREATE TABLE work_item_link_type_versions (
+    link_type_id    uuid NOT NULL REFERENCES work_item_link_types(id) ON DELETE CASCADE,
+    version         integer NOT NULL,
+    version_time    timestamp with time zone NOT NULL,
+    link_type       jsonb NOT NULL,
+    PRIMARY KEY (link_type_id, version)
+);
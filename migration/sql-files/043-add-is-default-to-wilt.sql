@@ -0,0 +1,3 @@
+-- the link type that is preselected when creating a link in a space
+ALTER TABLE work_item_link_types ADD COLUMN is_default boolean NOT NULL DEFAULT FALSE;
+CREATE UNIQUE INDEX work_item_link_types_default_per_space_idx ON work_item_link_types (space_id) WHERE is_default AND deleted_at IS NULL;
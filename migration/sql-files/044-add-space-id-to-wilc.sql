@@ -0,0 +1,10 @@
+-- link categories belong to a space; the existing categories are moved to
+-- the system space, whose categories are shared by all spaces
+ALTER TABLE work_item_link_categories ADD space_id uuid DEFAULT '{{index . 0}}' NOT NULL;
+-- Once we set the values to the default. We drop this default constraint
+ALTER TABLE work_item_link_categories ALTER space_id DROP DEFAULT;
+
+ALTER TABLE work_item_link_categories ADD FOREIGN KEY (space_id) REFERENCES spaces(id) ON DELETE CASCADE;
+
+-- Create indexes
+CREATE INDEX ix_wilc_space_id ON work_item_link_categories USING btree (space_id);
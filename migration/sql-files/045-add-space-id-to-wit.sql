@@ -0,0 +1,10 @@
+-- work item types belong to a space; the existing types are moved to the
+-- system space, whose types are shared by all spaces
+ALTER TABLE work_item_types ADD space_id uuid DEFAULT '{{index . 0}}' NOT NULL;
+-- Once we set the values to the default. We drop this default constraint
+ALTER TABLE work_item_types ALTER space_id DROP DEFAULT;
+
+ALTER TABLE work_item_types ADD FOREIGN KEY (space_id) REFERENCES spaces(id) ON DELETE CASCADE;
+
+-- Create indexes
+CREATE INDEX ix_wit_space_id ON work_item_types USING btree (space_id);
@@ -1,6 +1,7 @@
 package remoteworkitem
 
 import (
+	"sort"
 	"testing"
 
 	"golang.org/x/net/context"
@@ -231,8 +232,10 @@ func (s *TrackerItemRepositorySuite) TestConvertExistingWorkItem() {
 	assert.Equal(s.T(), "linking", workItem.Fields[workitem.SystemTitle])
 	assert.Equal(s.T(), identity0.ID.String(), workItem.Fields[workitem.SystemCreator])
 	require.NotEmpty(s.T(), workItem.Fields[workitem.SystemAssignees])
-	assert.Equal(s.T(), identity1.ID.String(), workItem.Fields[workitem.SystemAssignees].([]interface{})[0])
-	assert.Equal(s.T(), identity2.ID.String(), workItem.Fields[workitem.SystemAssignees].([]interface{})[1])
+	// list values are returned in canonical order
+	expectedAssignees := []string{identity1.ID.String(), identity2.ID.String()}
+	sort.Strings(expectedAssignees)
+	assert.Equal(s.T(), []interface{}{expectedAssignees[0], expectedAssignees[1]}, workItem.Fields[workitem.SystemAssignees])
 	assert.Equal(s.T(), "closed", workItem.Fields[workitem.SystemState])
 	// given
 	s.T().Log("Updating the existing work item when it's reimported.")
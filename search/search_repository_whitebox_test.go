@@ -72,7 +72,7 @@ func (s *searchRepositoryWhiteboxTest) TestSearchByText() {
 					workitem.SystemTitle:       "test sbose title '12345678asdfgh'",
 					workitem.SystemDescription: rendering.NewMarkupContentFromLegacy(`"description" for search test`),
 					workitem.SystemCreator:     "sbose78",
-					workitem.SystemAssignees:   []string{"pranav"},
+					workitem.SystemAssignees:   []string{"6a1c6e7e-4b3a-4d6f-9a1e-2f0d3b4c5a6e"},
 					workitem.SystemState:       "closed",
 				},
 			},
@@ -85,7 +85,7 @@ func (s *searchRepositoryWhiteboxTest) TestSearchByText() {
 					workitem.SystemTitle:       "add new error types in models/errors.go'",
 					workitem.SystemDescription: rendering.NewMarkupContentFromLegacy(`Make sure remoteworkitem can access..`),
 					workitem.SystemCreator:     "sbose78",
-					workitem.SystemAssignees:   []string{"pranav"},
+					workitem.SystemAssignees:   []string{"6a1c6e7e-4b3a-4d6f-9a1e-2f0d3b4c5a6e"},
 					workitem.SystemState:       "closed",
 				},
 			},
@@ -98,7 +98,7 @@ func (s *searchRepositoryWhiteboxTest) TestSearchByText() {
 					workitem.SystemTitle:       "test sbose title '12345678asdfgh'",
 					workitem.SystemDescription: rendering.NewMarkupContentFromLegacy(`"description" for search test`),
 					workitem.SystemCreator:     "sbose78",
-					workitem.SystemAssignees:   []string{"pranav"},
+					workitem.SystemAssignees:   []string{"6a1c6e7e-4b3a-4d6f-9a1e-2f0d3b4c5a6e"},
 					workitem.SystemState:       "closed",
 				},
 			},
@@ -111,7 +111,7 @@ func (s *searchRepositoryWhiteboxTest) TestSearchByText() {
 				Fields: map[string]interface{}{
 					workitem.SystemTitle:     "test nofield sbose title '12345678asdfgh'",
 					workitem.SystemCreator:   "sbose78",
-					workitem.SystemAssignees: []string{"pranav"},
+					workitem.SystemAssignees: []string{"6a1c6e7e-4b3a-4d6f-9a1e-2f0d3b4c5a6e"},
 					workitem.SystemState:     "closed",
 				},
 			},
@@ -124,7 +124,7 @@ func (s *searchRepositoryWhiteboxTest) TestSearchByText() {
 				Fields: map[string]interface{}{
 					workitem.SystemTitle:     "test should return 0 results'",
 					workitem.SystemCreator:   "sbose78",
-					workitem.SystemAssignees: []string{"pranav"},
+					workitem.SystemAssignees: []string{"6a1c6e7e-4b3a-4d6f-9a1e-2f0d3b4c5a6e"},
 					workitem.SystemState:     "closed",
 				},
 			},
@@ -136,7 +136,7 @@ func (s *searchRepositoryWhiteboxTest) TestSearchByText() {
 				Fields: map[string]interface{}{
 					workitem.SystemTitle:     "Bug reported by administrator for input = (value)",
 					workitem.SystemCreator:   "pgore",
-					workitem.SystemAssignees: []string{"pranav"},
+					workitem.SystemAssignees: []string{"6a1c6e7e-4b3a-4d6f-9a1e-2f0d3b4c5a6e"},
 					workitem.SystemState:     "new",
 				},
 			},
@@ -148,7 +148,7 @@ func (s *searchRepositoryWhiteboxTest) TestSearchByText() {
 				Fields: map[string]interface{}{
 					workitem.SystemTitle:     "trial for braces (pranav) {shoubhik} [aslak]",
 					workitem.SystemCreator:   "pgore",
-					workitem.SystemAssignees: []string{"pranav"},
+					workitem.SystemAssignees: []string{"6a1c6e7e-4b3a-4d6f-9a1e-2f0d3b4c5a6e"},
 					workitem.SystemState:     "new",
 				},
 			},
@@ -262,7 +262,7 @@ func (s *searchRepositoryWhiteboxTest) TestSearchByID() {
 			workitem.SystemTitle:       "Search Test Sbose",
 			workitem.SystemDescription: rendering.NewMarkupContentFromLegacy("Description"),
 			workitem.SystemCreator:     "sbose78",
-			workitem.SystemAssignees:   []string{"pranav"},
+			workitem.SystemAssignees:   []string{"6a1c6e7e-4b3a-4d6f-9a1e-2f0d3b4c5a6e"},
 			workitem.SystemState:       "closed",
 		}
 
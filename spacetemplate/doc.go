@@ -0,0 +1,4 @@
+// Package spacetemplate provides functions to export the work item types,
+// work item link categories and work item link types of a space as a
+// portable template and to import such a template into another space.
+package spacetemplate
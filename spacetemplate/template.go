@@ -0,0 +1,338 @@
+package spacetemplate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/almighty/almighty-core/errors"
+	"github.com/almighty/almighty-core/models"
+	"github.com/almighty/almighty-core/space"
+	"github.com/almighty/almighty-core/workitem"
+	"github.com/almighty/almighty-core/workitem/link"
+
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+	satoriuuid "github.com/satori/go.uuid"
+	"golang.org/x/net/context"
+)
+
+// SpaceTemplate is a portable representation of the work item types, work
+// item link categories and work item link types of a space. All references
+// between the elements use local references instead of IDs so that the
+// template can be imported into another space.
+type SpaceTemplate struct {
+	WorkItemTypes  []WorkItemTypeTemplate `json:"work_item_types"`
+	LinkCategories []LinkCategoryTemplate `json:"link_categories"`
+	LinkTypes      []LinkTypeTemplate     `json:"link_types"`
+}
+
+// WorkItemTypeTemplate describes a work item type inside a SpaceTemplate
+type WorkItemTypeTemplate struct {
+	// Ref is the local reference of this work item type
+	Ref string `json:"ref"`
+	// ExtendedTypeRef is the local reference of the work item type this type
+	// extends or empty if it doesn't extend another type.
+	ExtendedTypeRef string                    `json:"extended_type_ref,omitempty"`
+	Name            string                    `json:"name"`
+	Description     *string                   `json:"description,omitempty"`
+	Icon            string                    `json:"icon"`
+	Fields          workitem.FieldDefinitions `json:"fields"`
+}
+
+// LinkCategoryTemplate describes a work item link category inside a SpaceTemplate
+type LinkCategoryTemplate struct {
+	// Ref is the local reference of this link category
+	Ref         string  `json:"ref"`
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+}
+
+// LinkTypeTemplate describes a work item link type inside a SpaceTemplate
+type LinkTypeTemplate struct {
+	// Ref is the local reference of this link type
+	Ref             string  `json:"ref"`
+	Name            string  `json:"name"`
+	Description     *string `json:"description,omitempty"`
+	ForwardName     string  `json:"forward_name"`
+	ReverseName     string  `json:"reverse_name"`
+	Topology        string  `json:"topology"`
+	SourceTypeRef   string  `json:"source_type_ref"`
+	TargetTypeRef   string  `json:"target_type_ref"`
+	LinkCategoryRef string  `json:"link_category_ref"`
+	MaxSourceCount  *int    `json:"max_source_count,omitempty"`
+	MaxTargetCount  *int    `json:"max_target_count,omitempty"`
+	// DeprecatedAt is the time the link type was deprecated or nil if it
+	// isn't deprecated.
+	DeprecatedAt *time.Time `json:"deprecated_at,omitempty"`
+	DisplayOrder int        `json:"display_order,omitempty"`
+	IsDefault    bool       `json:"is_default,omitempty"`
+}
+
+// Repository encapsulates the export and import of space templates
+type Repository interface {
+	ExportTemplate(ctx context.Context, spaceID satoriuuid.UUID) (*SpaceTemplate, error)
+	ImportTemplate(ctx context.Context, spaceID satoriuuid.UUID, tmpl *SpaceTemplate) (map[string]satoriuuid.UUID, error)
+}
+
+// NewRepository creates a new space template repository based on gorm
+func NewRepository(db *gorm.DB) *GormRepository {
+	return NewRepositoryWithMaxFieldCount(db, workitem.DefaultMaxFieldCount)
+}
+
+// NewRepositoryWithMaxFieldCount creates a new space template repository
+// based on gorm that imports work item types with at most maxFieldCount
+// fields. Values smaller than 1 fall back to workitem.DefaultMaxFieldCount.
+func NewRepositoryWithMaxFieldCount(db *gorm.DB, maxFieldCount int) *GormRepository {
+	if maxFieldCount < 1 {
+		maxFieldCount = workitem.DefaultMaxFieldCount
+	}
+	return &GormRepository{db: db, maxFieldCount: maxFieldCount}
+}
+
+// GormRepository implements Repository using gorm
+type GormRepository struct {
+	db            *gorm.DB
+	maxFieldCount int
+}
+
+// ExportTemplate gathers the work item types, the work item link types of
+// the given space and the link categories they use into a SpaceTemplate.
+// Besides the work item types of the space, the ones of the system space are
+// exported, because they are shared by all spaces and may be extended or
+// referenced by the space's types and link types.
+// Soft-deleted elements are excluded.
+// returns NotFoundError, ConversionError or InternalError
+func (r *GormRepository) ExportTemplate(ctx context.Context, spaceID satoriuuid.UUID) (*SpaceTemplate, error) {
+	if _, err := space.NewRepository(r.db).Load(ctx, spaceID); err != nil {
+		return nil, errs.WithStack(err)
+	}
+	tmpl := SpaceTemplate{
+		WorkItemTypes:  []WorkItemTypeTemplate{},
+		LinkCategories: []LinkCategoryTemplate{},
+		LinkTypes:      []LinkTypeTemplate{},
+	}
+
+	// Ordering by path makes sure that a type is always exported after the
+	// type it extends.
+	var wits []workitem.WorkItemType
+	if err := r.db.Where("space_id IN (?)", []satoriuuid.UUID{space.SystemSpace, spaceID}).Order("path").Find(&wits).Error; err != nil {
+		return nil, errors.NewInternalError(err.Error())
+	}
+	witRefs := map[satoriuuid.UUID]string{}
+	ltreeRefs := map[string]string{}
+	for i, wit := range wits {
+		ref := fmt.Sprintf("wit-%d", i)
+		witRefs[wit.ID] = ref
+		ltreeRefs[wit.LtreeSafeID()] = ref
+		extendedTypeRef := ""
+		path := strings.Split(wit.Path, workitem.GetTypePathSeparator())
+		if len(path) > 1 {
+			parentRef, ok := ltreeRefs[path[len(path)-2]]
+			if !ok {
+				return nil, errors.NewConversionError(fmt.Sprintf("work item type %s extends unknown work item type %s", wit.ID, path[len(path)-2]))
+			}
+			extendedTypeRef = parentRef
+		}
+		tmpl.WorkItemTypes = append(tmpl.WorkItemTypes, WorkItemTypeTemplate{
+			Ref:             ref,
+			ExtendedTypeRef: extendedTypeRef,
+			Name:            wit.Name,
+			Description:     wit.Description,
+			Icon:            wit.Icon,
+			Fields:          wit.Fields,
+		})
+	}
+
+	var linkTypes []link.WorkItemLinkType
+	if err := r.db.Where("space_id = ?", spaceID).Order("name").Find(&linkTypes).Error; err != nil {
+		return nil, errors.NewInternalError(err.Error())
+	}
+	var categoryIDs []satoriuuid.UUID
+	for _, lt := range linkTypes {
+		categoryIDs = append(categoryIDs, lt.LinkCategoryID)
+	}
+	categoryRefs := map[satoriuuid.UUID]string{}
+	if len(categoryIDs) > 0 {
+		var categories []link.WorkItemLinkCategory
+		if err := r.db.Where("id IN (?)", categoryIDs).Order("name").Find(&categories).Error; err != nil {
+			return nil, errors.NewInternalError(err.Error())
+		}
+		for i, cat := range categories {
+			ref := fmt.Sprintf("category-%d", i)
+			categoryRefs[cat.ID] = ref
+			tmpl.LinkCategories = append(tmpl.LinkCategories, LinkCategoryTemplate{
+				Ref:         ref,
+				Name:        cat.Name,
+				Description: cat.Description,
+			})
+		}
+	}
+
+	for i, lt := range linkTypes {
+		sourceTypeRef, ok := witRefs[lt.SourceTypeID]
+		if !ok {
+			return nil, errors.NewConversionError(fmt.Sprintf("work item link type %s references unknown source type %s", lt.ID, lt.SourceTypeID))
+		}
+		targetTypeRef, ok := witRefs[lt.TargetTypeID]
+		if !ok {
+			return nil, errors.NewConversionError(fmt.Sprintf("work item link type %s references unknown target type %s", lt.ID, lt.TargetTypeID))
+		}
+		categoryRef, ok := categoryRefs[lt.LinkCategoryID]
+		if !ok {
+			return nil, errors.NewConversionError(fmt.Sprintf("work item link type %s references unknown link category %s", lt.ID, lt.LinkCategoryID))
+		}
+		tmpl.LinkTypes = append(tmpl.LinkTypes, LinkTypeTemplate{
+			Ref:             fmt.Sprintf("linktype-%d", i),
+			Name:            lt.Name,
+			Description:     lt.Description,
+			ForwardName:     lt.ForwardName,
+			ReverseName:     lt.ReverseName,
+			Topology:        lt.Topology,
+			SourceTypeRef:   sourceTypeRef,
+			TargetTypeRef:   targetTypeRef,
+			LinkCategoryRef: categoryRef,
+			MaxSourceCount:  lt.MaxSourceCount,
+			MaxTargetCount:  lt.MaxTargetCount,
+			DeprecatedAt:    lt.DeprecatedAt,
+			DisplayOrder:    lt.DisplayOrder,
+			IsDefault:       lt.IsDefault,
+		})
+	}
+	return &tmpl, nil
+}
+
+// ImportTemplate creates the work item types, link categories and link types
+// of the given template in the given space. The local references of the
+// template are resolved to freshly generated IDs and the mapping from local
+// reference to ID is returned. The work item types belong to the given space.
+// Link categories are created in the system space, which shares them with
+// all spaces, and their names are unique, so an existing category with the
+// same name is reused instead of creating a new one. The import runs in its own
+// transaction and nothing is created if any element of the template is
+// invalid.
+// returns NotFoundError, BadParameterError or InternalError
+func (r *GormRepository) ImportTemplate(ctx context.Context, spaceID satoriuuid.UUID, tmpl *SpaceTemplate) (map[string]satoriuuid.UUID, error) {
+	if tmpl == nil {
+		return nil, errors.NewBadParameterError("template", nil).Expected("not <nil>")
+	}
+	mapping := map[string]satoriuuid.UUID{}
+	err := models.Transactional(r.db, func(tx *gorm.DB) error {
+		if _, err := space.NewRepository(tx).Load(ctx, spaceID); err != nil {
+			return errs.WithStack(err)
+		}
+
+		// Work item types must be listed after the type they extend. Like
+		// the work item type repository's Create, a type inherits the fields
+		// of the type it extends and is validated by CheckValidForCreation.
+		witIDs := map[string]satoriuuid.UUID{}
+		wits := map[string]workitem.WorkItemType{}
+		for _, witTmpl := range tmpl.WorkItemTypes {
+			if _, exists := mapping[witTmpl.Ref]; exists || witTmpl.Ref == "" {
+				return errors.NewBadParameterError("ref", witTmpl.Ref).Expected("unique reference")
+			}
+			if witTmpl.Name == "" {
+				return errors.NewBadParameterError("name", witTmpl.Name)
+			}
+			id := satoriuuid.NewV4()
+			wit := workitem.WorkItemType{
+				ID:          id,
+				Name:        witTmpl.Name,
+				Description: witTmpl.Description,
+				Icon:        witTmpl.Icon,
+				Path:        workitem.LtreeSafeID(id),
+				Fields:      workitem.FieldDefinitions{},
+				SpaceID:     spaceID,
+			}
+			for name, def := range witTmpl.Fields {
+				wit.Fields[name] = def
+			}
+			if witTmpl.ExtendedTypeRef != "" {
+				parent, ok := wits[witTmpl.ExtendedTypeRef]
+				if !ok {
+					return errors.NewBadParameterError("extended_type_ref", witTmpl.ExtendedTypeRef).Expected("reference to a preceding work item type")
+				}
+				if err := wit.CheckCompatibleWithParent(parent); err != nil {
+					return errs.WithStack(err)
+				}
+				for name, def := range parent.Fields {
+					if _, overridden := wit.Fields[name]; !overridden {
+						wit.Fields[name] = def
+					}
+				}
+				wit.Path = parent.Path + workitem.GetTypePathSeparator() + wit.Path
+			}
+			if err := wit.CheckValidForCreation(r.maxFieldCount); err != nil {
+				return errs.WithStack(err)
+			}
+			if err := tx.Create(&wit).Error; err != nil {
+				return errors.NewInternalError(err.Error())
+			}
+			mapping[witTmpl.Ref] = id
+			witIDs[witTmpl.Ref] = id
+			wits[witTmpl.Ref] = wit
+		}
+
+		categoryIDs := map[string]satoriuuid.UUID{}
+		for _, catTmpl := range tmpl.LinkCategories {
+			if _, exists := mapping[catTmpl.Ref]; exists || catTmpl.Ref == "" {
+				return errors.NewBadParameterError("ref", catTmpl.Ref).Expected("unique reference")
+			}
+			if catTmpl.Name == "" {
+				return errors.NewBadParameterError("name", catTmpl.Name)
+			}
+			cat := link.WorkItemLinkCategory{}
+			db := tx.Where("name = ?", catTmpl.Name).First(&cat)
+			if db.RecordNotFound() {
+				cat = link.WorkItemLinkCategory{
+					Name:        catTmpl.Name,
+					Description: catTmpl.Description,
+					SpaceID:     link.ReservedSpaceID,
+				}
+				db = tx.Create(&cat)
+			}
+			if db.Error != nil {
+				return errors.NewInternalError(db.Error.Error())
+			}
+			mapping[catTmpl.Ref] = cat.ID
+			categoryIDs[catTmpl.Ref] = cat.ID
+		}
+
+		linkTypeRepo := link.NewWorkItemLinkTypeRepository(tx)
+		for _, ltTmpl := range tmpl.LinkTypes {
+			if _, exists := mapping[ltTmpl.Ref]; exists || ltTmpl.Ref == "" {
+				return errors.NewBadParameterError("ref", ltTmpl.Ref).Expected("unique reference")
+			}
+			// Unresolved references result in nil IDs which are rejected by
+			// CheckValidForCreation.
+			lt := link.WorkItemLinkType{
+				ID:             satoriuuid.NewV4(),
+				Name:           ltTmpl.Name,
+				Description:    ltTmpl.Description,
+				ForwardName:    ltTmpl.ForwardName,
+				ReverseName:    ltTmpl.ReverseName,
+				Topology:       ltTmpl.Topology,
+				SourceTypeID:   witIDs[ltTmpl.SourceTypeRef],
+				TargetTypeID:   witIDs[ltTmpl.TargetTypeRef],
+				LinkCategoryID: categoryIDs[ltTmpl.LinkCategoryRef],
+				SpaceID:        spaceID,
+				DeprecatedAt:   ltTmpl.DeprecatedAt,
+				MaxSourceCount: ltTmpl.MaxSourceCount,
+				MaxTargetCount: ltTmpl.MaxTargetCount,
+				DisplayOrder:   ltTmpl.DisplayOrder,
+				IsDefault:      ltTmpl.IsDefault,
+			}
+			if err := linkTypeRepo.CreateFromModel(ctx, &lt); err != nil {
+				return errs.WithStack(err)
+			}
+			mapping[ltTmpl.Ref] = lt.ID
+		}
+		return nil
+	})
+	if err != nil {
+		// the rolled back work item types may have been cached meanwhile
+		workitem.ClearGlobalWorkItemTypeCache()
+		return nil, errs.WithStack(err)
+	}
+	return mapping, nil
+}
@@ -0,0 +1,274 @@
+package spacetemplate_test
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/almighty/almighty-core/errors"
+	"github.com/almighty/almighty-core/gormsupport"
+	"github.com/almighty/almighty-core/gormsupport/cleaner"
+	"github.com/almighty/almighty-core/migration"
+	"github.com/almighty/almighty-core/models"
+	"github.com/almighty/almighty-core/resource"
+	"github.com/almighty/almighty-core/space"
+	"github.com/almighty/almighty-core/spacetemplate"
+	"github.com/almighty/almighty-core/workitem"
+	"github.com/almighty/almighty-core/workitem/link"
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+	satoriuuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type spaceTemplateRepoBlackBoxTest struct {
+	gormsupport.DBTestSuite
+	clean func()
+	repo  *spacetemplate.GormRepository
+}
+
+func TestRunSpaceTemplateRepoBlackBoxTest(t *testing.T) {
+	suite.Run(t, &spaceTemplateRepoBlackBoxTest{DBTestSuite: gormsupport.NewDBTestSuite("../config.yaml")})
+}
+
+// SetupSuite overrides the DBTestSuite's function but calls it before doing anything else
+// The SetupSuite method will run before the tests in the suite are run.
+// It sets up a database connection for all the tests in this suite without polluting global space.
+func (s *spaceTemplateRepoBlackBoxTest) SetupSuite() {
+	s.DBTestSuite.SetupSuite()
+
+	// Make sure the database is populated with the correct types (e.g. bug etc.)
+	if _, c := os.LookupEnv(resource.Database); c != false {
+		if err := models.Transactional(s.DB, func(tx *gorm.DB) error {
+			return migration.PopulateCommonTypes(context.Background(), tx, workitem.NewWorkItemTypeRepository(tx))
+		}); err != nil {
+			panic(err.Error())
+		}
+	}
+}
+
+func (s *spaceTemplateRepoBlackBoxTest) SetupTest() {
+	s.clean = cleaner.DeleteCreatedEntities(s.DB)
+	s.repo = spacetemplate.NewRepository(s.DB)
+}
+
+func (s *spaceTemplateRepoBlackBoxTest) TearDownTest() {
+	s.clean()
+}
+
+// createSpace creates a new space with a random name
+func (s *spaceTemplateRepoBlackBoxTest) createSpace() satoriuuid.UUID {
+	sp, err := space.NewRepository(s.DB).Create(context.Background(), &space.Space{
+		Name: satoriuuid.NewV4().String(),
+	})
+	require.Nil(s.T(), err)
+	return sp.ID
+}
+
+func (s *spaceTemplateRepoBlackBoxTest) TestExportTemplate() {
+	ctx := context.Background()
+	spaceID := s.createSpace()
+
+	// Create a work item type that extends the bug type
+	witRepo := workitem.NewWorkItemTypeRepository(s.DB)
+	extendedTypeID := satoriuuid.NewV4()
+	_, err := witRepo.Create(ctx, &extendedTypeID, &workitem.SystemBug, "test-export-bug", nil, "fa-bug", nil)
+	require.Nil(s.T(), err)
+
+	// Create a link category and two link types in the space
+	categoryName := "test-export-category"
+	cat, err := link.NewWorkItemLinkCategoryRepository(s.DB).Create(ctx, &categoryName, nil)
+	require.Nil(s.T(), err)
+	linkTypeRepo := link.NewWorkItemLinkTypeRepository(s.DB)
+	exported, err := linkTypeRepo.Create(ctx, "test-export-link", nil, workitem.SystemBug, extendedTypeID, "test-blocks", "test-blocked by", link.TopologyNetwork, *cat.Data.ID, spaceID)
+	require.Nil(s.T(), err)
+	db := s.DB.Model(&link.WorkItemLinkType{}).Where("id = ?", *exported.Data.ID).Update("max_target_count", 2)
+	require.Nil(s.T(), db.Error)
+	deleted, err := linkTypeRepo.Create(ctx, "test-export-deleted", nil, workitem.SystemBug, workitem.SystemBug, "test-fwd", "test-rev", link.TopologyNetwork, *cat.Data.ID, spaceID)
+	require.Nil(s.T(), err)
+	require.Nil(s.T(), linkTypeRepo.Delete(ctx, *deleted.Data.ID))
+
+	// Create a work item type in another space
+	otherTypeID := satoriuuid.NewV4()
+	otherType := workitem.WorkItemType{ID: otherTypeID, Name: "test-export-other-space", Path: workitem.LtreeSafeID(otherTypeID), Fields: workitem.FieldDefinitions{}, SpaceID: s.createSpace()}
+	require.Nil(s.T(), s.DB.Create(&otherType).Error)
+
+	// Export and round-trip the template through JSON
+	tmpl, err := s.repo.ExportTemplate(ctx, spaceID)
+	require.Nil(s.T(), err)
+	bytes, err := json.Marshal(tmpl)
+	require.Nil(s.T(), err)
+	loaded := spacetemplate.SpaceTemplate{}
+	require.Nil(s.T(), json.Unmarshal(bytes, &loaded))
+
+	// Check the work item types
+	witsByRef := map[string]spacetemplate.WorkItemTypeTemplate{}
+	witsByName := map[string]spacetemplate.WorkItemTypeTemplate{}
+	for _, wit := range loaded.WorkItemTypes {
+		witsByRef[wit.Ref] = wit
+		witsByName[wit.Name] = wit
+	}
+	bug, ok := witsByName["Bug"]
+	require.True(s.T(), ok)
+	extended, ok := witsByName["test-export-bug"]
+	require.True(s.T(), ok)
+	require.Equal(s.T(), bug.Ref, extended.ExtendedTypeRef)
+	require.Contains(s.T(), extended.Fields, workitem.SystemTitle)
+	_, ok = witsByName["test-export-other-space"]
+	require.False(s.T(), ok)
+
+	// Check the link categories and link types; the deleted link type must
+	// not be exported.
+	require.Len(s.T(), loaded.LinkCategories, 1)
+	require.Equal(s.T(), categoryName, loaded.LinkCategories[0].Name)
+	require.Len(s.T(), loaded.LinkTypes, 1)
+	lt := loaded.LinkTypes[0]
+	require.Equal(s.T(), "test-export-link", lt.Name)
+	require.Equal(s.T(), "test-blocks", lt.ForwardName)
+	require.Equal(s.T(), "test-blocked by", lt.ReverseName)
+	require.Equal(s.T(), link.TopologyNetwork, lt.Topology)
+	require.Equal(s.T(), bug.Ref, lt.SourceTypeRef)
+	require.Equal(s.T(), extended.Ref, lt.TargetTypeRef)
+	require.Equal(s.T(), loaded.LinkCategories[0].Ref, lt.LinkCategoryRef)
+	require.Nil(s.T(), lt.MaxSourceCount)
+	require.NotNil(s.T(), lt.MaxTargetCount)
+	require.Equal(s.T(), 2, *lt.MaxTargetCount)
+
+	// Check that exporting an unknown space fails
+	_, err = s.repo.ExportTemplate(ctx, satoriuuid.NewV4())
+	require.NotNil(s.T(), err)
+}
+
+// newTemplate returns a small template with two work item types, one link
+// category and one link type.
+func newTemplate(prefix string) *spacetemplate.SpaceTemplate {
+	maxSourceCount := 1
+	return &spacetemplate.SpaceTemplate{
+		WorkItemTypes: []spacetemplate.WorkItemTypeTemplate{
+			{
+				Ref:  "wit-0",
+				Name: prefix + "-base",
+				Icon: "fa-question",
+				Fields: workitem.FieldDefinitions{
+					workitem.SystemTitle: {Required: true, Type: workitem.SimpleType{Kind: workitem.KindString}},
+					workitem.SystemState: {Required: true, Type: workitem.SimpleType{Kind: workitem.KindString}},
+				},
+			},
+			{
+				Ref:             "wit-1",
+				ExtendedTypeRef: "wit-0",
+				Name:            prefix + "-extended",
+				Icon:            "fa-bug",
+				Fields: workitem.FieldDefinitions{
+					"severity": {Type: workitem.SimpleType{Kind: workitem.KindString}},
+				},
+			},
+		},
+		LinkCategories: []spacetemplate.LinkCategoryTemplate{
+			{Ref: "category-0", Name: prefix + "-category"},
+		},
+		LinkTypes: []spacetemplate.LinkTypeTemplate{
+			{
+				Ref:             "linktype-0",
+				Name:            prefix + "-link",
+				ForwardName:     "blocks",
+				ReverseName:     "blocked by",
+				Topology:        link.TopologyNetwork,
+				SourceTypeRef:   "wit-0",
+				TargetTypeRef:   "wit-1",
+				LinkCategoryRef: "category-0",
+				MaxSourceCount:  &maxSourceCount,
+				DisplayOrder:    3,
+				IsDefault:       true,
+			},
+		},
+	}
+}
+
+func (s *spaceTemplateRepoBlackBoxTest) TestImportTemplate() {
+	ctx := context.Background()
+	spaceID := s.createSpace()
+
+	mapping, err := s.repo.ImportTemplate(ctx, spaceID, newTemplate("test-import"))
+	require.Nil(s.T(), err)
+	require.Len(s.T(), mapping, 4)
+
+	// Check the work item types and their hierarchy
+	witRepo := workitem.NewWorkItemTypeRepository(s.DB)
+	base, err := witRepo.LoadTypeFromDB(ctx, mapping["wit-0"])
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), "test-import-base", base.Name)
+	extended, err := witRepo.LoadTypeFromDB(ctx, mapping["wit-1"])
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), "test-import-extended", extended.Name)
+	require.True(s.T(), extended.IsTypeOrSubtypeOf(base.ID))
+	require.Contains(s.T(), extended.Fields, workitem.SystemTitle)
+	require.Contains(s.T(), extended.Fields, "severity")
+
+	// Check the link type references the remapped IDs
+	lt, err := link.NewWorkItemLinkTypeRepository(s.DB).LoadTypeFromDBByID(ctx, mapping["linktype-0"])
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), spaceID, lt.SpaceID)
+	require.Equal(s.T(), base.ID, lt.SourceTypeID)
+	require.Equal(s.T(), extended.ID, lt.TargetTypeID)
+	require.Equal(s.T(), mapping["category-0"], lt.LinkCategoryID)
+	require.NotNil(s.T(), lt.MaxSourceCount)
+	require.Equal(s.T(), 1, *lt.MaxSourceCount)
+	require.Nil(s.T(), lt.MaxTargetCount)
+	require.Equal(s.T(), 3, lt.DisplayOrder)
+	require.True(s.T(), lt.IsDefault)
+	require.Nil(s.T(), lt.DeprecatedAt)
+}
+
+func (s *spaceTemplateRepoBlackBoxTest) TestImportTemplateValidates() {
+	ctx := context.Background()
+
+	// Check a root work item type without the mandatory system fields
+	tmpl := newTemplate("test-validate-fields")
+	delete(tmpl.WorkItemTypes[0].Fields, workitem.SystemState)
+	_, err := s.repo.ImportTemplate(ctx, s.createSpace(), tmpl)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+	require.Contains(s.T(), err.Error(), workitem.SystemState)
+
+	// Check field keys that only differ in case
+	tmpl = newTemplate("test-validate-keys")
+	tmpl.WorkItemTypes[1].Fields["Severity"] = workitem.FieldDefinition{Type: workitem.SimpleType{Kind: workitem.KindString}}
+	_, err = s.repo.ImportTemplate(ctx, s.createSpace(), tmpl)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+	require.Contains(s.T(), err.Error(), "severity")
+
+	// Check a second default link type in the space
+	tmpl = newTemplate("test-validate-default")
+	second := tmpl.LinkTypes[0]
+	second.Ref = "linktype-1"
+	second.Name = "test-validate-default-second"
+	tmpl.LinkTypes = append(tmpl.LinkTypes, second)
+	_, err = s.repo.ImportTemplate(ctx, s.createSpace(), tmpl)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+
+	// Check the maximum number of fields of the repository; the extended
+	// type has three fields
+	_, err = spacetemplate.NewRepositoryWithMaxFieldCount(s.DB, 2).ImportTemplate(ctx, s.createSpace(), newTemplate("test-validate-max-fields"))
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+	_, err = spacetemplate.NewRepositoryWithMaxFieldCount(s.DB, 3).ImportTemplate(ctx, s.createSpace(), newTemplate("test-validate-max-fields"))
+	require.Nil(s.T(), err)
+}
+
+func (s *spaceTemplateRepoBlackBoxTest) TestImportTemplateRollsBack() {
+	ctx := context.Background()
+	spaceID := s.createSpace()
+	tmpl := newTemplate("test-rollback")
+	tmpl.LinkTypes[0].Topology = "foo"
+
+	_, err := s.repo.ImportTemplate(ctx, spaceID, tmpl)
+	require.NotNil(s.T(), err)
+
+	// Check that none of the work item types was created
+	var count int
+	db := s.DB.Model(&workitem.WorkItemType{}).Where("name LIKE ?", "test-rollback-%").Count(&count)
+	require.Nil(s.T(), db.Error)
+	require.Equal(s.T(), 0, count)
+}
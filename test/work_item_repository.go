@@ -98,6 +98,51 @@ type WorkItemRepository struct {
 		result1 map[string]workitem.WICountsPerIteration
 		result2 error
 	}
+	CountWithFieldStub        func(ctx context.Context, typeID uuid.UUID, fieldKey string) (int, error)
+	countWithFieldMutex       sync.RWMutex
+	countWithFieldArgsForCall []struct {
+		ctx      context.Context
+		typeID   uuid.UUID
+		fieldKey string
+	}
+	countWithFieldReturns struct {
+		result1 int
+		result2 error
+	}
+	CountByTypeStub        func(ctx context.Context, typeID uuid.UUID) (int, error)
+	countByTypeMutex       sync.RWMutex
+	countByTypeArgsForCall []struct {
+		ctx    context.Context
+		typeID uuid.UUID
+	}
+	countByTypeReturns struct {
+		result1 int
+		result2 error
+	}
+	CountSubtypesStub        func(ctx context.Context, typeID uuid.UUID) (int, error)
+	countSubtypesMutex       sync.RWMutex
+	countSubtypesArgsForCall []struct {
+		ctx    context.Context
+		typeID uuid.UUID
+	}
+	countSubtypesReturns struct {
+		result1 int
+		result2 error
+	}
+	ConvertFieldValuesStub        func(ctx context.Context, typeID uuid.UUID, fieldKey string, batchSize int, convert func(interface{}) (interface{}, error)) (int, int, error)
+	convertFieldValuesMutex       sync.RWMutex
+	convertFieldValuesArgsForCall []struct {
+		ctx       context.Context
+		typeID    uuid.UUID
+		fieldKey  string
+		batchSize int
+		convert   func(interface{}) (interface{}, error)
+	}
+	convertFieldValuesReturns struct {
+		result1 int
+		result2 int
+		result3 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -380,6 +425,147 @@ func (fake *WorkItemRepository) GetCountsForIterationReturns(result1 map[string]
 	}{result1, result2}
 }
 
+func (fake *WorkItemRepository) CountWithField(ctx context.Context, typeID uuid.UUID, fieldKey string) (int, error) {
+	fake.countWithFieldMutex.Lock()
+	fake.countWithFieldArgsForCall = append(fake.countWithFieldArgsForCall, struct {
+		ctx      context.Context
+		typeID   uuid.UUID
+		fieldKey string
+	}{ctx, typeID, fieldKey})
+	fake.recordInvocation("CountWithField", []interface{}{ctx, typeID, fieldKey})
+	fake.countWithFieldMutex.Unlock()
+	if fake.CountWithFieldStub != nil {
+		return fake.CountWithFieldStub(ctx, typeID, fieldKey)
+	}
+	return fake.countWithFieldReturns.result1, fake.countWithFieldReturns.result2
+}
+
+func (fake *WorkItemRepository) CountWithFieldCallCount() int {
+	fake.countWithFieldMutex.RLock()
+	defer fake.countWithFieldMutex.RUnlock()
+	return len(fake.countWithFieldArgsForCall)
+}
+
+func (fake *WorkItemRepository) CountWithFieldArgsForCall(i int) (context.Context, uuid.UUID, string) {
+	fake.countWithFieldMutex.RLock()
+	defer fake.countWithFieldMutex.RUnlock()
+	return fake.countWithFieldArgsForCall[i].ctx, fake.countWithFieldArgsForCall[i].typeID, fake.countWithFieldArgsForCall[i].fieldKey
+}
+
+func (fake *WorkItemRepository) CountWithFieldReturns(result1 int, result2 error) {
+	fake.CountWithFieldStub = nil
+	fake.countWithFieldReturns = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *WorkItemRepository) CountByType(ctx context.Context, typeID uuid.UUID) (int, error) {
+	fake.countByTypeMutex.Lock()
+	fake.countByTypeArgsForCall = append(fake.countByTypeArgsForCall, struct {
+		ctx    context.Context
+		typeID uuid.UUID
+	}{ctx, typeID})
+	fake.recordInvocation("CountByType", []interface{}{ctx, typeID})
+	fake.countByTypeMutex.Unlock()
+	if fake.CountByTypeStub != nil {
+		return fake.CountByTypeStub(ctx, typeID)
+	}
+	return fake.countByTypeReturns.result1, fake.countByTypeReturns.result2
+}
+
+func (fake *WorkItemRepository) CountByTypeCallCount() int {
+	fake.countByTypeMutex.RLock()
+	defer fake.countByTypeMutex.RUnlock()
+	return len(fake.countByTypeArgsForCall)
+}
+
+func (fake *WorkItemRepository) CountByTypeArgsForCall(i int) (context.Context, uuid.UUID) {
+	fake.countByTypeMutex.RLock()
+	defer fake.countByTypeMutex.RUnlock()
+	return fake.countByTypeArgsForCall[i].ctx, fake.countByTypeArgsForCall[i].typeID
+}
+
+func (fake *WorkItemRepository) CountByTypeReturns(result1 int, result2 error) {
+	fake.CountByTypeStub = nil
+	fake.countByTypeReturns = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *WorkItemRepository) CountSubtypes(ctx context.Context, typeID uuid.UUID) (int, error) {
+	fake.countSubtypesMutex.Lock()
+	fake.countSubtypesArgsForCall = append(fake.countSubtypesArgsForCall, struct {
+		ctx    context.Context
+		typeID uuid.UUID
+	}{ctx, typeID})
+	fake.recordInvocation("CountSubtypes", []interface{}{ctx, typeID})
+	fake.countSubtypesMutex.Unlock()
+	if fake.CountSubtypesStub != nil {
+		return fake.CountSubtypesStub(ctx, typeID)
+	}
+	return fake.countSubtypesReturns.result1, fake.countSubtypesReturns.result2
+}
+
+func (fake *WorkItemRepository) CountSubtypesCallCount() int {
+	fake.countSubtypesMutex.RLock()
+	defer fake.countSubtypesMutex.RUnlock()
+	return len(fake.countSubtypesArgsForCall)
+}
+
+func (fake *WorkItemRepository) CountSubtypesArgsForCall(i int) (context.Context, uuid.UUID) {
+	fake.countSubtypesMutex.RLock()
+	defer fake.countSubtypesMutex.RUnlock()
+	return fake.countSubtypesArgsForCall[i].ctx, fake.countSubtypesArgsForCall[i].typeID
+}
+
+func (fake *WorkItemRepository) CountSubtypesReturns(result1 int, result2 error) {
+	fake.CountSubtypesStub = nil
+	fake.countSubtypesReturns = struct {
+		result1 int
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *WorkItemRepository) ConvertFieldValues(ctx context.Context, typeID uuid.UUID, fieldKey string, batchSize int, convert func(interface{}) (interface{}, error)) (int, int, error) {
+	fake.convertFieldValuesMutex.Lock()
+	fake.convertFieldValuesArgsForCall = append(fake.convertFieldValuesArgsForCall, struct {
+		ctx       context.Context
+		typeID    uuid.UUID
+		fieldKey  string
+		batchSize int
+		convert   func(interface{}) (interface{}, error)
+	}{ctx, typeID, fieldKey, batchSize, convert})
+	fake.recordInvocation("ConvertFieldValues", []interface{}{ctx, typeID, fieldKey, batchSize, convert})
+	fake.convertFieldValuesMutex.Unlock()
+	if fake.ConvertFieldValuesStub != nil {
+		return fake.ConvertFieldValuesStub(ctx, typeID, fieldKey, batchSize, convert)
+	}
+	return fake.convertFieldValuesReturns.result1, fake.convertFieldValuesReturns.result2, fake.convertFieldValuesReturns.result3
+}
+
+func (fake *WorkItemRepository) ConvertFieldValuesCallCount() int {
+	fake.convertFieldValuesMutex.RLock()
+	defer fake.convertFieldValuesMutex.RUnlock()
+	return len(fake.convertFieldValuesArgsForCall)
+}
+
+func (fake *WorkItemRepository) ConvertFieldValuesArgsForCall(i int) (context.Context, uuid.UUID, string, int, func(interface{}) (interface{}, error)) {
+	fake.convertFieldValuesMutex.RLock()
+	defer fake.convertFieldValuesMutex.RUnlock()
+	return fake.convertFieldValuesArgsForCall[i].ctx, fake.convertFieldValuesArgsForCall[i].typeID, fake.convertFieldValuesArgsForCall[i].fieldKey, fake.convertFieldValuesArgsForCall[i].batchSize, fake.convertFieldValuesArgsForCall[i].convert
+}
+
+func (fake *WorkItemRepository) ConvertFieldValuesReturns(result1 int, result2 int, result3 error) {
+	fake.ConvertFieldValuesStub = nil
+	fake.convertFieldValuesReturns = struct {
+		result1 int
+		result2 int
+		result3 error
+	}{result1, result2, result3}
+}
+
 func (fake *WorkItemRepository) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -399,6 +585,14 @@ func (fake *WorkItemRepository) Invocations() map[string][][]interface{} {
 	defer fake.getCountsPerIterationMutex.RUnlock()
 	fake.getCountsForIterationMutex.RLock()
 	defer fake.getCountsForIterationMutex.RUnlock()
+	fake.countWithFieldMutex.RLock()
+	defer fake.countWithFieldMutex.RUnlock()
+	fake.countByTypeMutex.RLock()
+	defer fake.countByTypeMutex.RUnlock()
+	fake.countSubtypesMutex.RLock()
+	defer fake.countSubtypesMutex.RUnlock()
+	fake.convertFieldValuesMutex.RLock()
+	defer fake.convertFieldValuesMutex.RUnlock()
 	return fake.invocations
 }
 
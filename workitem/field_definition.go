@@ -3,12 +3,16 @@ package workitem
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
-
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/almighty/almighty-core/convert"
-	"github.com/pkg/errors"
+	"github.com/almighty/almighty-core/errors"
+	errs "github.com/pkg/errors"
 )
 
 // constants for describing possible field types
@@ -18,6 +22,7 @@ const (
 	KindFloat             Kind = "float"
 	KindInstant           Kind = "instant"
 	KindDuration          Kind = "duration"
+	KindDateTime          Kind = "datetime"
 	KindURL               Kind = "url"
 	KindIteration         Kind = "iteration"
 	KindWorkitemReference Kind = "workitem"
@@ -27,11 +32,36 @@ const (
 	KindMarkup            Kind = "markup"
 	KindArea              Kind = "area"
 	KindCodebase          Kind = "codebase"
+	KindBoolean           Kind = "boolean"
 )
 
 // Kind is the kind of field type
 type Kind string
 
+// FieldKindRegistry knows the field kinds that work item types may use
+type FieldKindRegistry struct {
+	kinds map[Kind]bool
+}
+
+// NewFieldKindRegistry returns a FieldKindRegistry that supports the given
+// kinds.
+func NewFieldKindRegistry(kinds ...Kind) *FieldKindRegistry {
+	r := &FieldKindRegistry{kinds: map[Kind]bool{}}
+	for _, kind := range kinds {
+		r.kinds[kind] = true
+	}
+	return r
+}
+
+// IsSupported returns true if the given kind is known to the registry
+func (r *FieldKindRegistry) IsSupported(kind string) bool {
+	return r.kinds[Kind(kind)]
+}
+
+// SupportedFieldKinds is the registry of all field kinds for which a
+// FieldType exists.
+var SupportedFieldKinds = NewFieldKindRegistry(KindString, KindInteger, KindFloat, KindInstant, KindDuration, KindDateTime, KindURL, KindIteration, KindWorkitemReference, KindUser, KindEnum, KindList, KindMarkup, KindArea, KindCodebase, KindBoolean)
+
 // FieldType describes the possible values of a FieldDefinition
 func (k Kind) isSimpleType() bool {
 	return k != KindEnum && k != KindList
@@ -50,10 +80,44 @@ type FieldType interface {
 
 // FieldDefinition describes type & other restrictions of a field
 type FieldDefinition struct {
-	Required    bool
-	Label       string
-	Description string
-	Type        FieldType
+	Required     bool
+	ReadOnly     bool
+	Label        string
+	Description  string
+	Type         FieldType
+	DefaultValue interface{} `json:",omitempty"`
+	MinValue     *float64    `json:",omitempty"`
+	MaxValue     *float64    `json:",omitempty"`
+	// AllowedSchemes restricts the schemes of a field of kind "url". If empty,
+	// DefaultAllowedURLSchemes is used.
+	AllowedSchemes []string `json:",omitempty"`
+	// Hidden fields are stored but omitted from the REST representation of a
+	// work item unless they are explicitly requested.
+	Hidden bool `json:",omitempty"`
+	// Unit optionally describes the unit of a numeric or duration field, e.g.
+	// "hours" or "points".
+	Unit string `json:",omitempty"`
+	// Deprecated fields are still converted but should no longer be offered
+	// when creating work items (see WorkItemType.ActiveFields).
+	Deprecated bool `json:",omitempty"`
+	// Position determines the order of the field (see
+	// FieldDefinitions.OrderedNames). Zero means the field has no position.
+	Position int `json:",omitempty"`
+	// Pattern optionally restricts the values of a string field to those
+	// matching this regular expression in full (see ValidatePattern).
+	Pattern string `json:",omitempty"`
+	// ValueLabels optionally maps the values of an enum field to display
+	// labels (see ValidateValueLabels).
+	ValueLabels map[string]string `json:",omitempty"`
+	// Precision optionally restricts the total number of digits of a numeric
+	// field and Scale the number of digits after the decimal point (see
+	// ValidatePrecision).
+	Precision *int `json:",omitempty"`
+	Scale     *int `json:",omitempty"`
+	// StateTransitions optionally maps each value of an enum field (usually
+	// "system.state") to the values it may be changed to (see
+	// WorkItemType.CheckStateTransition). If empty, all changes are allowed.
+	StateTransitions map[string][]string `json:",omitempty"`
 }
 
 // Ensure FieldDefinition implements the Equaler interface
@@ -69,21 +133,444 @@ func (f FieldDefinition) Equal(u convert.Equaler) bool {
 	if f.Required != other.Required {
 		return false
 	}
+	if f.ReadOnly != other.ReadOnly {
+		return false
+	}
+	if f.Hidden != other.Hidden {
+		return false
+	}
+	if f.Unit != other.Unit {
+		return false
+	}
+	if f.Deprecated != other.Deprecated {
+		return false
+	}
+	if f.Position != other.Position {
+		return false
+	}
+	if f.Pattern != other.Pattern {
+		return false
+	}
+	if !reflect.DeepEqual(f.ValueLabels, other.ValueLabels) {
+		return false
+	}
+	if !intPtrIsNilOrContentIsEqual(f.Precision, other.Precision) {
+		return false
+	}
+	if !intPtrIsNilOrContentIsEqual(f.Scale, other.Scale) {
+		return false
+	}
+	if !reflect.DeepEqual(f.StateTransitions, other.StateTransitions) {
+		return false
+	}
 	if f.Label != other.Label {
 		return false
 	}
 	if f.Description != other.Description {
 		return false
 	}
+	if !reflect.DeepEqual(f.DefaultValue, other.DefaultValue) {
+		return false
+	}
+	if !floatPtrIsNilOrContentIsEqual(f.MinValue, other.MinValue) {
+		return false
+	}
+	if !floatPtrIsNilOrContentIsEqual(f.MaxValue, other.MaxValue) {
+		return false
+	}
+	if !reflect.DeepEqual(f.AllowedSchemes, other.AllowedSchemes) {
+		return false
+	}
 	return f.Type.Equal(other.Type)
 }
 
-// ConvertToModel converts a field value for use in the persistence layer
+// returns true if the left hand and right hand side float
+// pointers either both point to nil or reference the same
+// content; otherwise false is returned.
+func floatPtrIsNilOrContentIsEqual(l, r *float64) bool {
+	if l == nil || r == nil {
+		return l == nil && r == nil
+	}
+	return *l == *r
+}
+
+// returns true if the left hand and right hand side int pointers either both
+// point to nil or reference the same content; otherwise false is returned.
+func intPtrIsNilOrContentIsEqual(l, r *int) bool {
+	if l == nil || r == nil {
+		return l == nil && r == nil
+	}
+	return *l == *r
+}
+
+// Validate returns a BadParameterError naming the first constraint of the
+// field definition that doesn't fit the field's kind or contradicts another
+// constraint. It is the single entry point for all checks of a field
+// definition and is called for every field of a work item type when the
+// type is created, updated or loaded.
+func (f FieldDefinition) Validate() error {
+	if len(f.AllowedSchemes) > 0 && f.Type.GetKind() != KindURL {
+		return errors.NewBadParameterError("allowed_schemes", f.AllowedSchemes).Expected(fmt.Sprintf("no allowed schemes for a field of kind %s", f.Type.GetKind()))
+	}
+	validators := []func() error{
+		f.ValidateBounds,
+		f.ValidateUnit,
+		f.ValidatePattern,
+		f.ValidateEnum,
+		f.ValidateValueLabels,
+		f.ValidatePrecision,
+		f.ValidateStateTransitions,
+		f.ValidateDefault,
+	}
+	for _, validate := range validators {
+		if err := validate(); err != nil {
+			return errs.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// ValidateBounds returns a BadParameterError if the field definition declares
+// a minimum or maximum value but is not numeric or if the minimum exceeds the
+// maximum.
+func (f FieldDefinition) ValidateBounds() error {
+	if f.MinValue == nil && f.MaxValue == nil {
+		return nil
+	}
+	switch f.Type.GetKind() {
+	case KindInteger, KindFloat:
+	default:
+		return errors.NewBadParameterError("min_value", f.MinValue).Expected(fmt.Sprintf("no minimum or maximum value for a field of kind %s", f.Type.GetKind()))
+	}
+	if f.MinValue != nil && f.MaxValue != nil && *f.MinValue > *f.MaxValue {
+		return errors.NewBadParameterError("min_value", *f.MinValue).Expected(fmt.Sprintf("<= max_value %v", *f.MaxValue))
+	}
+	return nil
+}
+
+// ValidateDefault returns a BadParameterError if the default value of the
+// field definition is not one of the allowed values of an enum field or if
+// it violates the min/max bounds of a numeric field. Field definitions
+// without a default value are always valid.
+func (f FieldDefinition) ValidateDefault() error {
+	if f.DefaultValue == nil {
+		return nil
+	}
+	switch t := f.Type.(type) {
+	case EnumType:
+		if _, err := t.ConvertToModel(f.DefaultValue); err != nil {
+			return errors.NewBadParameterError("default", f.DefaultValue).Expected(fmt.Sprintf("one of %v", t.Values))
+		}
+	case SimpleType:
+		if t.Kind != KindInteger && t.Kind != KindFloat {
+			return nil
+		}
+		value, ok := toFloat64(f.DefaultValue)
+		if !ok {
+			return errors.NewBadParameterError("default", f.DefaultValue).Expected("numeric value")
+		}
+		if f.MinValue != nil && value < *f.MinValue {
+			return errors.NewBadParameterError("default", f.DefaultValue).Expected(fmt.Sprintf(">= %v", *f.MinValue))
+		}
+		if f.MaxValue != nil && value > *f.MaxValue {
+			return errors.NewBadParameterError("default", f.DefaultValue).Expected(fmt.Sprintf("<= %v", *f.MaxValue))
+		}
+	}
+	return nil
+}
+
+// ValidateUnit returns a BadParameterError if the field definition declares a
+// unit but its kind is neither numeric nor a duration.
+func (f FieldDefinition) ValidateUnit() error {
+	if f.Unit == "" {
+		return nil
+	}
+	switch f.Type.GetKind() {
+	case KindInteger, KindFloat, KindDuration:
+		return nil
+	}
+	return errors.NewBadParameterError("unit", f.Unit).Expected(fmt.Sprintf("no unit for a field of kind %s", f.Type.GetKind()))
+}
+
+// ValidatePattern returns a BadParameterError if the field definition declares
+// a pattern that is not a valid regular expression or if the field is not of
+// kind string.
+func (f FieldDefinition) ValidatePattern() error {
+	if f.Pattern == "" {
+		return nil
+	}
+	if f.Type.GetKind() != KindString {
+		return errors.NewBadParameterError("pattern", f.Pattern).Expected(fmt.Sprintf("no pattern for a field of kind %s", f.Type.GetKind()))
+	}
+	if _, err := compilePattern(f.Pattern); err != nil {
+		return errors.NewBadParameterError("pattern", f.Pattern).Expected("valid regular expression")
+	}
+	return nil
+}
+
+// compiledPatterns caches the compiled patterns of field definitions, so that
+// a pattern is compiled once and not for every value that is converted.
+var compiledPatterns = struct {
+	sync.RWMutex
+	m map[string]*regexp.Regexp
+}{m: map[string]*regexp.Regexp{}}
+
+// compilePattern compiles the given pattern so that it only matches complete
+// values.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	compiledPatterns.RLock()
+	re, ok := compiledPatterns.m[pattern]
+	compiledPatterns.RUnlock()
+	if ok {
+		return re, nil
+	}
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, err
+	}
+	compiledPatterns.Lock()
+	defer compiledPatterns.Unlock()
+	compiledPatterns.m[pattern] = re
+	return re, nil
+}
+
+// ValidatePrecision returns a BadParameterError if the field definition
+// declares a precision or scale but is not numeric, if the precision is not
+// positive, if the scale is negative or if the scale exceeds the precision.
+func (f FieldDefinition) ValidatePrecision() error {
+	if f.Precision == nil && f.Scale == nil {
+		return nil
+	}
+	switch f.Type.GetKind() {
+	case KindInteger, KindFloat:
+	default:
+		return errors.NewBadParameterError("precision", f.Precision).Expected(fmt.Sprintf("no precision or scale for a field of kind %s", f.Type.GetKind()))
+	}
+	if f.Precision != nil && *f.Precision < 1 {
+		return errors.NewBadParameterError("precision", *f.Precision).Expected(">= 1")
+	}
+	if f.Scale != nil && *f.Scale < 0 {
+		return errors.NewBadParameterError("scale", *f.Scale).Expected(">= 0")
+	}
+	if f.Precision != nil && f.Scale != nil && *f.Scale > *f.Precision {
+		return errors.NewBadParameterError("scale", *f.Scale).Expected(fmt.Sprintf("<= precision %d", *f.Precision))
+	}
+	return nil
+}
+
+// ValidateValueLabels returns a BadParameterError if the field definition
+// declares value labels but is not an enum or if a label is given for a value
+// that is not one of the enum values.
+func (f FieldDefinition) ValidateValueLabels() error {
+	if len(f.ValueLabels) == 0 {
+		return nil
+	}
+	enumType, ok := f.Type.(EnumType)
+	if !ok {
+		return errors.NewBadParameterError("value_labels", f.ValueLabels).Expected(fmt.Sprintf("no value labels for a field of kind %s", f.Type.GetKind()))
+	}
+	for value := range f.ValueLabels {
+		if !containsFormatted(enumType.Values, value) {
+			return errors.NewBadParameterError("value_labels", value).Expected(fmt.Sprintf("one of the enum values %v", enumType.Values))
+		}
+	}
+	return nil
+}
+
+// ValidateEnum returns a BadParameterError if the field definition is an
+// enum without any values or with duplicate values. Values are compared
+// case-sensitively.
+func (f FieldDefinition) ValidateEnum() error {
+	enumType, ok := f.Type.(EnumType)
+	if !ok {
+		return nil
+	}
+	if len(enumType.Values) == 0 {
+		return errors.NewBadParameterError("values", enumType.Values).Expected("at least one enum value")
+	}
+	for i, v := range enumType.Values {
+		if contains(enumType.Values[:i], v) {
+			return errors.NewBadParameterError("values", v).Expected(fmt.Sprintf("unique enum values, but %v is given more than once", v))
+		}
+	}
+	return nil
+}
+
+// ValidateStateTransitions returns a BadParameterError if the field
+// definition declares state transitions but is not an enum or if a
+// transition starts or ends at a value that is not one of the enum values.
+func (f FieldDefinition) ValidateStateTransitions() error {
+	if len(f.StateTransitions) == 0 {
+		return nil
+	}
+	enumType, ok := f.Type.(EnumType)
+	if !ok {
+		return errors.NewBadParameterError("state_transitions", f.StateTransitions).Expected(fmt.Sprintf("no state transitions for a field of kind %s", f.Type.GetKind()))
+	}
+	for from, targets := range f.StateTransitions {
+		if !containsFormatted(enumType.Values, from) {
+			return errors.NewBadParameterError("state_transitions", from).Expected(fmt.Sprintf("one of the enum values %v", enumType.Values))
+		}
+		for _, to := range targets {
+			if !containsFormatted(enumType.Values, to) {
+				return errors.NewBadParameterError("state_transitions", to).Expected(fmt.Sprintf("one of the enum values %v", enumType.Values))
+			}
+		}
+	}
+	return nil
+}
+
+// containsFormatted returns true if the default format of one of the given
+// values equals s.
+func containsFormatted(values []interface{}, s string) bool {
+	for _, v := range values {
+		if fmt.Sprint(v) == s {
+			return true
+		}
+	}
+	return false
+}
+
+// LabeledValue is the REST representation of the value of an enum field with
+// value labels.
+type LabeledValue struct {
+	Value interface{} `json:"value"`
+	Label string      `json:"label"`
+}
+
+// toFloat64 returns the given numeric value as a float64 and true; if the
+// value is not numeric, false is returned.
+func toFloat64(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// FieldDiffType describes how a field differs between two FieldDefinitions
+type FieldDiffType string
+
+// constants for describing the possible field differences
+const (
+	FieldAdded   FieldDiffType = "added"
+	FieldRemoved FieldDiffType = "removed"
+	FieldChanged FieldDiffType = "changed"
+)
+
+// FieldDiff describes a single difference between two FieldDefinitions.
+// OldKind is empty for added fields and NewKind is empty for removed fields.
+type FieldDiff struct {
+	Name    string
+	Type    FieldDiffType
+	OldKind Kind
+	NewKind Kind
+}
+
+// Diff returns the differences between the field definitions fd and other
+// sorted by field name. A field that exists in other but not in fd is
+// reported as added, a field that exists in fd but not in other as removed,
+// and a field whose definition differs as changed.
+func (fd FieldDefinitions) Diff(other FieldDefinitions) []FieldDiff {
+	names := make([]string, 0, len(fd)+len(other))
+	for name := range fd {
+		names = append(names, name)
+	}
+	for name := range other {
+		if _, found := fd[name]; !found {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var diffs []FieldDiff
+	for _, name := range names {
+		def, found := fd[name]
+		otherDef, otherFound := other[name]
+		switch {
+		case !found:
+			diffs = append(diffs, FieldDiff{Name: name, Type: FieldAdded, NewKind: otherDef.Type.GetKind()})
+		case !otherFound:
+			diffs = append(diffs, FieldDiff{Name: name, Type: FieldRemoved, OldKind: def.Type.GetKind()})
+		case !def.Equal(otherDef):
+			diffs = append(diffs, FieldDiff{Name: name, Type: FieldChanged, OldKind: def.Type.GetKind(), NewKind: otherDef.Type.GetKind()})
+		}
+	}
+	return diffs
+}
+
+// OrderedNames returns the names of the field definitions ordered by their
+// position. Fields without a position come after all positioned fields and
+// are ordered by name, as are fields with the same position.
+func (fd FieldDefinitions) OrderedNames() []string {
+	names := make([]string, 0, len(fd))
+	for name := range fd {
+		names = append(names, name)
+	}
+	sort.Sort(fieldNamesByPosition{names, fd})
+	return names
+}
+
+// fieldNamesByPosition implements sort.Interface for OrderedNames
+type fieldNamesByPosition struct {
+	names []string
+	fd    FieldDefinitions
+}
+
+func (s fieldNamesByPosition) Len() int      { return len(s.names) }
+func (s fieldNamesByPosition) Swap(i, j int) { s.names[i], s.names[j] = s.names[j], s.names[i] }
+func (s fieldNamesByPosition) Less(i, j int) bool {
+	pi, pj := s.fd[s.names[i]].Position, s.fd[s.names[j]].Position
+	if pi != pj {
+		if pi == 0 || pj == 0 {
+			return pj == 0
+		}
+		return pi < pj
+	}
+	return s.names[i] < s.names[j]
+}
+
+// Ensure FieldDefinitions implements the Equaler interface
+var _ convert.Equaler = FieldDefinitions{}
+var _ convert.Equaler = (*FieldDefinitions)(nil)
+
+// Equal returns true if two FieldDefinitions objects are equal; otherwise false is returned.
+func (fd FieldDefinitions) Equal(u convert.Equaler) bool {
+	other, ok := u.(FieldDefinitions)
+	if !ok {
+		return false
+	}
+	return len(fd.Diff(other)) == 0
+}
+
+// ConvertToModel converts a field value for use in the persistence layer.
+// Values that ConvertFromModel would reject later on, e.g. URLs with a scheme
+// that is not allowed, strings not matching the pattern or numbers exceeding
+// the precision or scale, are rejected here so that they are never stored.
 func (f FieldDefinition) ConvertToModel(name string, value interface{}) (interface{}, error) {
 	if f.Required && (value == nil || (f.Type.GetKind() == KindString && strings.TrimSpace(value.(string)) == "")) {
 		return nil, fmt.Errorf("Value %s is required", name)
 	}
-	return f.Type.ConvertToModel(value)
+	converted, err := f.Type.ConvertToModel(value)
+	if err != nil || converted == nil {
+		return converted, err
+	}
+	if f.Type.GetKind() == KindURL {
+		return convertURLFromModel(converted, f.AllowedSchemes)
+	}
+	if f.Pattern != "" {
+		return convertPatternFromModel(name, converted, f.Pattern)
+	}
+	if f.Precision != nil || f.Scale != nil {
+		return f.convertDecimalFromModel(name, converted)
+	}
+	return converted, nil
 }
 
 // ConvertFromModel converts a field value for use in the REST API layer
@@ -91,14 +578,155 @@ func (f FieldDefinition) ConvertFromModel(name string, value interface{}) (inter
 	if f.Required && value == nil {
 		return nil, fmt.Errorf("Value %s is required", name)
 	}
+	if value == nil && f.Type.GetKind() == KindBoolean && f.DefaultValue != nil {
+		return convertBooleanFromModel(f.DefaultValue)
+	}
+	if value != nil && f.Type.GetKind() == KindURL {
+		return convertURLFromModel(value, f.AllowedSchemes)
+	}
+	if value != nil && f.Pattern != "" {
+		return convertPatternFromModel(name, value, f.Pattern)
+	}
+	if value != nil && len(f.ValueLabels) > 0 {
+		return f.convertLabeledFromModel(value)
+	}
+	if value != nil && (f.Precision != nil || f.Scale != nil) {
+		return f.convertDecimalFromModel(name, value)
+	}
+	if value != nil && f.Type.GetKind() == KindList {
+		converted, err := f.Type.ConvertFromModel(value)
+		if err != nil {
+			return nil, errs.WithStack(err)
+		}
+		return f.NormalizeListValue(converted)
+	}
 	return f.Type.ConvertFromModel(value)
 }
 
+// NormalizeListValue returns the elements of the given list value without
+// duplicates and sorted into a canonical order, so that lists that only
+// differ in the order or repetition of their elements become equal. A
+// ConversionError is returned if the field is not a list or the value is
+// not an array or slice.
+func (f FieldDefinition) NormalizeListValue(v interface{}) (interface{}, error) {
+	if f.Type.GetKind() != KindList {
+		return nil, errors.NewConversionError(fmt.Sprintf("field of kind %s has no list value", f.Type.GetKind()))
+	}
+	if v == nil {
+		return nil, nil
+	}
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Array && value.Kind() != reflect.Slice {
+		return nil, errors.NewConversionError(fmt.Sprintf(stErrorNotArrayOrSlice, v, value.Type().Name()))
+	}
+	// Elements are identified and ordered by their type and default format,
+	// so that e.g. the string "1" and the number 1 are kept apart.
+	elements := map[string]interface{}{}
+	keys := []string{}
+	for i := 0; i < value.Len(); i++ {
+		element := value.Index(i).Interface()
+		key := fmt.Sprintf("%T:%v", element, element)
+		if _, ok := elements[key]; ok {
+			continue
+		}
+		elements[key] = element
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	res := make([]interface{}, len(keys))
+	for i, key := range keys {
+		res[i] = elements[key]
+	}
+	return res, nil
+}
+
+// convertDecimalFromModel converts a numeric value and returns a
+// ConversionError if it has more digits after the decimal point than the
+// scale allows or more digits in total than the precision allows. Float
+// values are rounded to the scale to get rid of representation errors.
+func (f FieldDefinition) convertDecimalFromModel(name string, value interface{}) (interface{}, error) {
+	v, ok := toFloat64(value)
+	if !ok {
+		return nil, errors.NewConversionError(fmt.Sprintf("value %v of field %s is not numeric", value, name))
+	}
+	scale := 0
+	if f.Scale != nil {
+		scale = *f.Scale
+	}
+	rounded := roundToScale(v, scale)
+	if f.Scale != nil && math.Abs(rounded-v) > 1e-9*math.Max(1, math.Abs(v)) {
+		return nil, errors.NewConversionError(fmt.Sprintf("value %v of field %s has more than %d decimal places", value, name, scale))
+	}
+	if f.Precision != nil && math.Abs(rounded) >= math.Pow10(*f.Precision-scale) {
+		return nil, errors.NewConversionError(fmt.Sprintf("value %v of field %s has more than %d digits", value, name, *f.Precision))
+	}
+	if f.Type.GetKind() == KindFloat {
+		return rounded, nil
+	}
+	return f.Type.ConvertFromModel(value)
+}
+
+// roundToScale rounds v half away from zero to the given number of digits
+// after the decimal point.
+func roundToScale(v float64, scale int) float64 {
+	factor := math.Pow10(scale)
+	if v < 0 {
+		return -math.Floor(-v*factor+0.5) / factor
+	}
+	return math.Floor(v*factor+0.5) / factor
+}
+
+// convertPatternFromModel converts a string value and returns a
+// ConversionError if it doesn't match the given pattern.
+func convertPatternFromModel(name string, value interface{}, pattern string) (interface{}, error) {
+	str, ok := value.(string)
+	if !ok {
+		return nil, errors.NewConversionError(fmt.Sprintf("value %v of field %s is not a string", value, name))
+	}
+	re, err := compilePattern(pattern)
+	if err != nil {
+		return nil, errors.NewConversionError(fmt.Sprintf("invalid pattern %s of field %s: %s", pattern, name, err.Error()))
+	}
+	if !re.MatchString(str) {
+		return nil, errors.NewConversionError(fmt.Sprintf("value %s of field %s does not match pattern %s", str, name, pattern))
+	}
+	return str, nil
+}
+
+// convertLabeledFromModel converts an enum value and returns it together with
+// its label. The raw value is used as label if no label is defined for it.
+func (f FieldDefinition) convertLabeledFromModel(value interface{}) (interface{}, error) {
+	converted, err := f.Type.ConvertFromModel(value)
+	if err != nil {
+		return nil, errs.WithStack(err)
+	}
+	raw := fmt.Sprint(converted)
+	label, ok := f.ValueLabels[raw]
+	if !ok {
+		label = raw
+	}
+	return LabeledValue{Value: converted, Label: label}, nil
+}
+
 type rawFieldDef struct {
-	Required    bool
-	Label       string
-	Description string
-	Type        *json.RawMessage
+	Required         bool
+	ReadOnly         bool
+	Label            string
+	Description      string
+	Type             *json.RawMessage
+	DefaultValue     interface{}         `json:",omitempty"`
+	MinValue         *float64            `json:",omitempty"`
+	MaxValue         *float64            `json:",omitempty"`
+	AllowedSchemes   []string            `json:",omitempty"`
+	Hidden           bool                `json:",omitempty"`
+	Unit             string              `json:",omitempty"`
+	Deprecated       bool                `json:",omitempty"`
+	Position         int                 `json:",omitempty"`
+	Pattern          string              `json:",omitempty"`
+	ValueLabels      map[string]string   `json:",omitempty"`
+	Precision        *int                `json:",omitempty"`
+	Scale            *int                `json:",omitempty"`
+	StateTransitions map[string][]string `json:",omitempty"`
 }
 
 // Ensure rawFieldDef implements the Equaler interface
@@ -114,12 +742,54 @@ func (f rawFieldDef) Equal(u convert.Equaler) bool {
 	if f.Required != other.Required {
 		return false
 	}
+	if f.ReadOnly != other.ReadOnly {
+		return false
+	}
+	if f.Hidden != other.Hidden {
+		return false
+	}
+	if f.Unit != other.Unit {
+		return false
+	}
+	if f.Deprecated != other.Deprecated {
+		return false
+	}
+	if f.Position != other.Position {
+		return false
+	}
+	if f.Pattern != other.Pattern {
+		return false
+	}
+	if !reflect.DeepEqual(f.ValueLabels, other.ValueLabels) {
+		return false
+	}
+	if !intPtrIsNilOrContentIsEqual(f.Precision, other.Precision) {
+		return false
+	}
+	if !intPtrIsNilOrContentIsEqual(f.Scale, other.Scale) {
+		return false
+	}
+	if !reflect.DeepEqual(f.StateTransitions, other.StateTransitions) {
+		return false
+	}
 	if f.Label != other.Label {
 		return false
 	}
 	if f.Description != other.Description {
 		return false
 	}
+	if !reflect.DeepEqual(f.DefaultValue, other.DefaultValue) {
+		return false
+	}
+	if !floatPtrIsNilOrContentIsEqual(f.MinValue, other.MinValue) {
+		return false
+	}
+	if !floatPtrIsNilOrContentIsEqual(f.MaxValue, other.MaxValue) {
+		return false
+	}
+	if !reflect.DeepEqual(f.AllowedSchemes, other.AllowedSchemes) {
+		return false
+	}
 	if f.Type == nil && other.Type == nil {
 		return true
 	}
@@ -135,7 +805,7 @@ func (f *FieldDefinition) UnmarshalJSON(bytes []byte) error {
 
 	err := json.Unmarshal(bytes, &temp)
 	if err != nil {
-		return errors.WithStack(err)
+		return errs.WithStack(err)
 	}
 	rawType := map[string]interface{}{}
 	json.Unmarshal(*temp.Type, &rawType)
@@ -143,30 +813,26 @@ func (f *FieldDefinition) UnmarshalJSON(bytes []byte) error {
 	kind, err := convertAnyToKind(rawType["Kind"])
 
 	if err != nil {
-		return errors.WithStack(err)
+		return errs.WithStack(err)
 	}
+	var theType FieldType
 	switch *kind {
 	case KindList:
-		theType := ListType{}
-		err = json.Unmarshal(*temp.Type, &theType)
-		if err != nil {
-			return errors.WithStack(err)
-		}
-		*f = FieldDefinition{Type: theType, Required: temp.Required, Label: temp.Label, Description: temp.Description}
+		listType := ListType{}
+		err = json.Unmarshal(*temp.Type, &listType)
+		theType = listType
 	case KindEnum:
-		theType := EnumType{}
-		err = json.Unmarshal(*temp.Type, &theType)
-		if err != nil {
-			return errors.WithStack(err)
-		}
-		*f = FieldDefinition{Type: theType, Required: temp.Required, Label: temp.Label, Description: temp.Description}
+		enumType := EnumType{}
+		err = json.Unmarshal(*temp.Type, &enumType)
+		theType = enumType
 	default:
-		theType := SimpleType{}
-		err = json.Unmarshal(*temp.Type, &theType)
-		if err != nil {
-			return errors.WithStack(err)
-		}
-		*f = FieldDefinition{Type: theType, Required: temp.Required, Label: temp.Label, Description: temp.Description}
+		simpleType := SimpleType{}
+		err = json.Unmarshal(*temp.Type, &simpleType)
+		theType = simpleType
 	}
-	return nil
+	if err != nil {
+		return errs.WithStack(err)
+	}
+	*f = FieldDefinition{Type: theType, Required: temp.Required, ReadOnly: temp.ReadOnly, Hidden: temp.Hidden, Label: temp.Label, Description: temp.Description, DefaultValue: temp.DefaultValue, MinValue: temp.MinValue, MaxValue: temp.MaxValue, AllowedSchemes: temp.AllowedSchemes, Unit: temp.Unit, Deprecated: temp.Deprecated, Position: temp.Position, Pattern: temp.Pattern, ValueLabels: temp.ValueLabels, Precision: temp.Precision, Scale: temp.Scale, StateTransitions: temp.StateTransitions}
+	return f.Validate()
 }
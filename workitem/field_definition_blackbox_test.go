@@ -4,10 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/almighty/almighty-core/errors"
 	"github.com/almighty/almighty-core/resource"
 	. "github.com/almighty/almighty-core/workitem"
+	errs "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestListFieldDefMarshalling(t *testing.T) {
@@ -36,3 +40,390 @@ func TestListFieldDefMarshalling(t *testing.T) {
 		t.Errorf("field should be %v, but is %v", def, unmarshalled)
 	}
 }
+
+func TestFieldDefinitionsDiff(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	a := FieldDefinitions{
+		"foo": {Type: SimpleType{Kind: KindString}},
+		"bar": {Type: SimpleType{Kind: KindInteger}},
+	}
+
+	// Test equal definitions
+	b := FieldDefinitions{
+		"foo": {Type: SimpleType{Kind: KindString}},
+		"bar": {Type: SimpleType{Kind: KindInteger}},
+	}
+	assert.Empty(t, a.Diff(b))
+	assert.True(t, a.Equal(b))
+
+	// Test added field
+	b["cake"] = FieldDefinition{Type: SimpleType{Kind: KindFloat}}
+	assert.Equal(t, []FieldDiff{{Name: "cake", Type: FieldAdded, NewKind: KindFloat}}, a.Diff(b))
+	assert.False(t, a.Equal(b))
+
+	// Test removed field
+	delete(b, "cake")
+	delete(b, "bar")
+	assert.Equal(t, []FieldDiff{{Name: "bar", Type: FieldRemoved, OldKind: KindInteger}}, a.Diff(b))
+	assert.False(t, a.Equal(b))
+
+	// Test changed kind
+	b["bar"] = FieldDefinition{Type: SimpleType{Kind: KindFloat}}
+	assert.Equal(t, []FieldDiff{{Name: "bar", Type: FieldChanged, OldKind: KindInteger, NewKind: KindFloat}}, a.Diff(b))
+	assert.False(t, a.Equal(b))
+}
+
+func TestFieldDefinitionValidateDefault(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	enumType := EnumType{
+		SimpleType: SimpleType{Kind: KindEnum},
+		BaseType:   SimpleType{Kind: KindString},
+		Values:     []interface{}{"new", "open", "closed"},
+	}
+
+	// Test valid enum default
+	def := FieldDefinition{Type: enumType, DefaultValue: "open"}
+	assert.Nil(t, def.ValidateDefault())
+
+	// Test enum default that is not an allowed value
+	def = FieldDefinition{Type: enumType, DefaultValue: "resolved"}
+	err := def.ValidateDefault()
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(err))
+
+	// Test numeric default within bounds
+	min, max := 1.0, 10.0
+	def = FieldDefinition{Type: SimpleType{Kind: KindInteger}, DefaultValue: 5, MinValue: &min, MaxValue: &max}
+	assert.Nil(t, def.ValidateDefault())
+
+	// Test numeric default out of bounds
+	def.DefaultValue = 11
+	err = def.ValidateDefault()
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(err))
+	def.DefaultValue = 0.5
+	err = def.ValidateDefault()
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(err))
+
+	// Test misconfigured default is rejected when loading the definition
+	bytes := []byte(`{"Required":false,"Type":{"Kind":"enum","BaseType":{"Kind":"string"},"Values":["new","open"]},"DefaultValue":"closed"}`)
+	loaded := FieldDefinition{}
+	err = json.Unmarshal(bytes, &loaded)
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(err))
+}
+
+func TestFieldDefinitionValidateUnit(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	// Test numeric field with a unit
+	def := FieldDefinition{Type: SimpleType{Kind: KindFloat}, Unit: "hours"}
+	assert.Nil(t, def.ValidateUnit())
+	bytes, err := json.Marshal(def)
+	assert.Nil(t, err)
+	loaded := FieldDefinition{}
+	assert.Nil(t, json.Unmarshal(bytes, &loaded))
+	assert.Equal(t, "hours", loaded.Unit)
+	assert.True(t, def.Equal(loaded))
+
+	// Test string field that declares a unit
+	def = FieldDefinition{Type: SimpleType{Kind: KindString}, Unit: "hours"}
+	err = def.ValidateUnit()
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(err))
+
+	// Test that the misconfigured unit is rejected when loading the definition
+	bytes = []byte(`{"Required":false,"Type":{"Kind":"string"},"Unit":"points"}`)
+	loaded = FieldDefinition{}
+	err = json.Unmarshal(bytes, &loaded)
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(err))
+}
+
+func TestFieldDefinitionPattern(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	def := FieldDefinition{Type: SimpleType{Kind: KindString}, Pattern: `[A-Z]+-\d+`}
+	assert.Nil(t, def.ValidatePattern())
+
+	// Test value matching the pattern
+	val, err := def.ConvertFromModel("ticket", "ABC-123")
+	assert.Nil(t, err)
+	assert.Equal(t, "ABC-123", val)
+
+	// Test values not matching the pattern, also not in full
+	_, err = def.ConvertFromModel("ticket", "abc-123")
+	assert.IsType(t, errors.ConversionError{}, errs.Cause(err))
+	_, err = def.ConvertFromModel("ticket", "see ABC-123")
+	assert.IsType(t, errors.ConversionError{}, errs.Cause(err))
+
+	// Test values not matching the pattern are rejected on write
+	val, err = def.ConvertToModel("ticket", "ABC-123")
+	assert.Nil(t, err)
+	assert.Equal(t, "ABC-123", val)
+	_, err = def.ConvertToModel("ticket", "abc-123")
+	assert.IsType(t, errors.ConversionError{}, errs.Cause(err))
+
+	// Test the pattern survives a JSON round trip
+	bytes, err := json.Marshal(def)
+	assert.Nil(t, err)
+	loaded := FieldDefinition{}
+	assert.Nil(t, json.Unmarshal(bytes, &loaded))
+	assert.True(t, def.Equal(loaded))
+
+	// Test invalid regular expression in the definition
+	bytes = []byte(`{"Required":false,"Type":{"Kind":"string"},"Pattern":"[A-Z+"}`)
+	loaded = FieldDefinition{}
+	err = json.Unmarshal(bytes, &loaded)
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(err))
+
+	// Test pattern on a field that is not a string
+	def = FieldDefinition{Type: SimpleType{Kind: KindInteger}, Pattern: `\d+`}
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(def.ValidatePattern()))
+}
+
+func TestFieldDefinitionValueLabels(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	def := FieldDefinition{
+		Type: EnumType{
+			SimpleType: SimpleType{Kind: KindEnum},
+			BaseType:   SimpleType{Kind: KindString},
+			Values:     []interface{}{"new", "in progress", "closed"},
+		},
+		ValueLabels: map[string]string{
+			"new":         "New",
+			"in progress": "In Progress",
+		},
+	}
+	assert.Nil(t, def.ValidateValueLabels())
+
+	// Test labeled value
+	val, err := def.ConvertFromModel(SystemState, "in progress")
+	assert.Nil(t, err)
+	assert.Equal(t, LabeledValue{Value: "in progress", Label: "In Progress"}, val)
+
+	// Test unlabeled value falls back to the raw value
+	val, err = def.ConvertFromModel(SystemState, "closed")
+	assert.Nil(t, err)
+	assert.Equal(t, LabeledValue{Value: "closed", Label: "closed"}, val)
+
+	// Test the labels survive a JSON round trip
+	bytes, err := json.Marshal(def)
+	assert.Nil(t, err)
+	loaded := FieldDefinition{}
+	assert.Nil(t, json.Unmarshal(bytes, &loaded))
+	assert.True(t, def.Equal(loaded))
+
+	// Test label for a value that is not allowed
+	bytes = []byte(`{"Required":false,"Type":{"Kind":"enum","BaseType":{"Kind":"string"},"Values":["new","closed"]},"ValueLabels":{"open":"Open"}}`)
+	loaded = FieldDefinition{}
+	err = json.Unmarshal(bytes, &loaded)
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(err))
+
+	// Test labels on a field that is not an enum
+	def = FieldDefinition{Type: SimpleType{Kind: KindString}, ValueLabels: map[string]string{"new": "New"}}
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(def.ValidateValueLabels()))
+}
+
+func TestFieldDefinitionValidate(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	min, max := 1.0, 10.0
+	enumType := EnumType{
+		SimpleType: SimpleType{Kind: KindEnum},
+		BaseType:   SimpleType{Kind: KindString},
+		Values:     []interface{}{"new", "closed"},
+	}
+
+	// Test consistent string field
+	def := FieldDefinition{Type: SimpleType{Kind: KindString}, Pattern: `[a-z]+`, DefaultValue: "abc"}
+	assert.Nil(t, def.Validate())
+
+	// Test consistent numeric field
+	def = FieldDefinition{Type: SimpleType{Kind: KindFloat}, MinValue: &min, MaxValue: &max, Unit: "hours"}
+	assert.Nil(t, def.Validate())
+
+	// Test numeric field with enum constraints
+	def = FieldDefinition{Type: SimpleType{Kind: KindInteger}, ValueLabels: map[string]string{"1": "One"}}
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(def.Validate()))
+	def = FieldDefinition{Type: SimpleType{Kind: KindInteger}, StateTransitions: map[string][]string{"1": {"2"}}}
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(def.Validate()))
+
+	// Test enum with numeric bounds
+	def = FieldDefinition{Type: enumType, MinValue: &min}
+	err := def.Validate()
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(err))
+	assert.Contains(t, err.Error(), "min_value")
+
+	// Test contradicting bounds
+	def = FieldDefinition{Type: SimpleType{Kind: KindInteger}, MinValue: &max, MaxValue: &min}
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(def.Validate()))
+
+	// Test allowed schemes on a field that is not a URL
+	def = FieldDefinition{Type: SimpleType{Kind: KindString}, AllowedSchemes: []string{"ftp"}}
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(def.Validate()))
+
+	// Test the validation is applied when loading the definition
+	bytes := []byte(`{"Required":false,"Type":{"Kind":"enum","BaseType":{"Kind":"string"},"Values":["new","closed"]},"MinValue":1}`)
+	loaded := FieldDefinition{}
+	err = json.Unmarshal(bytes, &loaded)
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(err))
+}
+
+func TestFieldDefinitionNormalizeListValue(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	def := FieldDefinition{Type: ListType{SimpleType: SimpleType{Kind: KindList}, ComponentType: SimpleType{Kind: KindString}}}
+
+	// Test list with duplicates
+	res, err := def.NormalizeListValue([]interface{}{"b", "a", "b", "c", "a"})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"a", "b", "c"}, res)
+
+	// Test already canonical list
+	res, err = def.NormalizeListValue([]string{"a", "b"})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"a", "b"}, res)
+
+	// Test values of different types are kept apart
+	res, err = def.NormalizeListValue([]interface{}{1, "1", 1})
+	assert.Nil(t, err)
+	assert.Len(t, res, 2)
+
+	// Test non-list value
+	res, err = def.NormalizeListValue("a")
+	assert.IsType(t, errors.ConversionError{}, err)
+	assert.Nil(t, res)
+
+	// Test non-list field
+	def = FieldDefinition{Type: SimpleType{Kind: KindString}}
+	_, err = def.NormalizeListValue([]interface{}{"a"})
+	assert.IsType(t, errors.ConversionError{}, err)
+
+	// Test list fields are normalized on conversion
+	def = FieldDefinition{Type: ListType{SimpleType: SimpleType{Kind: KindList}, ComponentType: SimpleType{Kind: KindString}}}
+	res, err = def.ConvertFromModel("labels", []interface{}{"b", "a", "b"})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"a", "b"}, res)
+}
+
+func TestFieldDefinitionValidateEnum(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	newEnum := func(values ...interface{}) FieldDefinition {
+		return FieldDefinition{
+			Type: EnumType{
+				SimpleType: SimpleType{Kind: KindEnum},
+				BaseType:   SimpleType{Kind: KindString},
+				Values:     values,
+			},
+		}
+	}
+
+	// Test valid enum; values differing only in case are distinct
+	assert.Nil(t, newEnum("new", "New", "closed").ValidateEnum())
+
+	// Test empty enum
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(newEnum().ValidateEnum()))
+
+	// Test enum with a duplicate value
+	err := newEnum("new", "closed", "new").ValidateEnum()
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(err))
+	assert.Contains(t, err.Error(), "new")
+
+	// Test fields that are not enums are ignored
+	def := FieldDefinition{Type: SimpleType{Kind: KindString}}
+	assert.Nil(t, def.ValidateEnum())
+}
+
+func TestFieldDefinitionPrecision(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	precision := 5
+	scale := 2
+	def := FieldDefinition{Type: SimpleType{Kind: KindFloat}, Precision: &precision, Scale: &scale}
+	assert.Nil(t, def.ValidatePrecision())
+
+	// Test value within the scale
+	val, err := def.ConvertFromModel("cost", 123.45)
+	assert.Nil(t, err)
+	assert.Equal(t, 123.45, val)
+
+	// Test value with a representation error is rounded to the scale
+	val, err = def.ConvertFromModel("cost", 0.1+0.2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0.3, val)
+
+	// Test value with too many decimals
+	_, err = def.ConvertFromModel("cost", 1.234)
+	assert.IsType(t, errors.ConversionError{}, errs.Cause(err))
+
+	// Test value out of precision
+	_, err = def.ConvertFromModel("cost", 1234.5)
+	assert.IsType(t, errors.ConversionError{}, errs.Cause(err))
+
+	// Test values exceeding the scale or precision are rejected on write
+	val, err = def.ConvertToModel("cost", 0.1+0.2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0.3, val)
+	_, err = def.ConvertToModel("cost", 1.234)
+	assert.IsType(t, errors.ConversionError{}, errs.Cause(err))
+	_, err = def.ConvertToModel("cost", 1234.5)
+	assert.IsType(t, errors.ConversionError{}, errs.Cause(err))
+
+	// Test precision and scale survive a JSON round trip
+	bytes, err := json.Marshal(def)
+	assert.Nil(t, err)
+	loaded := FieldDefinition{}
+	assert.Nil(t, json.Unmarshal(bytes, &loaded))
+	assert.True(t, def.Equal(loaded))
+
+	// Test scale exceeding the precision in the definition
+	bytes = []byte(`{"Required":false,"Type":{"Kind":"float"},"Precision":2,"Scale":3}`)
+	loaded = FieldDefinition{}
+	err = json.Unmarshal(bytes, &loaded)
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(err))
+
+	// Test precision on a field that is not numeric
+	def = FieldDefinition{Type: SimpleType{Kind: KindString}, Precision: &precision}
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(def.ValidatePrecision()))
+}
+
+func TestFieldDefinitionsMarshalJSONIsDeterministic(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	names := []string{"system.title", "effort", "zeta", "alpha", "system.state"}
+	build := func(order []int) FieldDefinitions {
+		fd := FieldDefinitions{}
+		for _, i := range order {
+			fd[names[i]] = FieldDefinition{Label: names[i], Type: SimpleType{Kind: KindString}}
+		}
+		return fd
+	}
+	a := build([]int{0, 1, 2, 3, 4})
+	b := build([]int{4, 3, 2, 1, 0})
+
+	// Test repeated marshals of maps built in different insertion orders
+	expected, err := json.Marshal(a)
+	assert.Nil(t, err)
+	for i := 0; i < 10; i++ {
+		actual, err := json.Marshal(a)
+		assert.Nil(t, err)
+		assert.Equal(t, string(expected), string(actual))
+		actual, err = json.Marshal(b)
+		assert.Nil(t, err)
+		assert.Equal(t, string(expected), string(actual))
+	}
+
+	// Test the keys are emitted in sorted order
+	assert.True(t, strings.Index(string(expected), `"alpha"`) < strings.Index(string(expected), `"effort"`))
+	assert.True(t, strings.Index(string(expected), `"system.state"`) < strings.Index(string(expected), `"system.title"`))
+
+	// Test the round trip keeps the map semantics
+	loaded := FieldDefinitions{}
+	assert.Nil(t, json.Unmarshal(expected, &loaded))
+	assert.True(t, a.Equal(loaded))
+}
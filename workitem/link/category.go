@@ -23,6 +23,9 @@ type WorkItemLinkCategory struct {
 	Description *string
 	// Version for optimistic concurrency control
 	Version int
+	// SpaceID is the space the category belongs to. Categories of the system
+	// space are shared by all spaces (see CheckCategoryInSpace).
+	SpaceID satoriuuid.UUID `sql:"type:uuid"`
 }
 
 // Ensure Fields implements the Equaler interface
@@ -47,6 +50,9 @@ func (c WorkItemLinkCategory) Equal(u convert.Equaler) bool {
 	if c.Version != other.Version {
 		return false
 	}
+	if !satoriuuid.Equal(c.SpaceID, other.SpaceID) {
+		return false
+	}
 	if !strPtrIsNilOrContentIsEqual(c.Description, other.Description) {
 		return false
 	}
@@ -41,6 +41,11 @@ func TestWorkItemLinkCategory_Equal(t *testing.T) {
 	c.Version += 1
 	require.False(t, a.Equal(c))
 
+	// Test space
+	c = a
+	c.SpaceID = satoriuuid.FromStringOrNil("aa6ef831-36db-4e99-9e33-6f793472f769")
+	require.False(t, a.Equal(c))
+
 	// Test name
 	c = a
 	c.Name = "bar"
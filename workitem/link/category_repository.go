@@ -14,6 +14,7 @@ import (
 type WorkItemLinkCategoryRepository interface {
 	Create(ctx context.Context, name *string, description *string) (*app.WorkItemLinkCategorySingle, error)
 	Load(ctx context.Context, ID satoriuuid.UUID) (*app.WorkItemLinkCategorySingle, error)
+	LoadCategoryFromDBByID(ctx context.Context, ID satoriuuid.UUID) (*WorkItemLinkCategory, error)
 	List(ctx context.Context) (*app.WorkItemLinkCategoryList, error)
 	Delete(ctx context.Context, ID satoriuuid.UUID) error
 	Save(ctx context.Context, linkCat app.WorkItemLinkCategorySingle) (*app.WorkItemLinkCategorySingle, error)
@@ -29,7 +30,8 @@ type GormWorkItemLinkCategoryRepository struct {
 	db *gorm.DB
 }
 
-// Create creates a new work item link category in the repository.
+// Create creates a new work item link category in the repository. The
+// category is created in the system space and hence shared by all spaces.
 // Returns BadParameterError, ConversionError or InternalError
 func (r *GormWorkItemLinkCategoryRepository) Create(ctx context.Context, name *string, description *string) (*app.WorkItemLinkCategorySingle, error) {
 	if name == nil || *name == "" {
@@ -39,6 +41,7 @@ func (r *GormWorkItemLinkCategoryRepository) Create(ctx context.Context, name *s
 		// Omit "lifecycle" and "ID" fields as they will be filled by the DB
 		Name:        *name,
 		Description: description,
+		SpaceID:     ReservedSpaceID,
 	}
 	db := r.db.Create(&created)
 	if db.Error != nil {
@@ -73,6 +76,20 @@ func (r *GormWorkItemLinkCategoryRepository) Load(ctx context.Context, ID satori
 	return &result, nil
 }
 
+// LoadCategoryFromDBByID returns the work item link category for the given ID
+// Returns NotFoundError or InternalError
+func (r *GormWorkItemLinkCategoryRepository) LoadCategoryFromDBByID(ctx context.Context, ID satoriuuid.UUID) (*WorkItemLinkCategory, error) {
+	res := WorkItemLinkCategory{}
+	db := r.db.Model(&res).Where("id=?", ID).First(&res)
+	if db.RecordNotFound() {
+		return nil, errors.NewNotFoundError("work item link category", ID.String())
+	}
+	if db.Error != nil {
+		return nil, errors.NewInternalError(db.Error.Error())
+	}
+	return &res, nil
+}
+
 // LoadCategoryFromDB return work item link category for the name
 func (r *GormWorkItemLinkCategoryRepository) LoadCategoryFromDB(ctx context.Context, name string) (*WorkItemLinkCategory, error) {
 	log.Info(ctx, map[string]interface{}{
@@ -171,6 +188,7 @@ func (r *GormWorkItemLinkCategoryRepository) Save(ctx context.Context, linkCat a
 	newLinkCat := WorkItemLinkCategory{
 		ID:      ID,
 		Version: *linkCat.Data.Attributes.Version + 1,
+		SpaceID: res.SpaceID,
 	}
 
 	if linkCat.Data.Attributes.Name != nil {
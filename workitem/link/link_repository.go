@@ -1,6 +1,9 @@
 package link
 
 import (
+	"fmt"
+	"math"
+	"sort"
 	"strconv"
 
 	"golang.org/x/net/context"
@@ -32,6 +35,24 @@ type WorkItemLinkRepository interface {
 	DeleteRelatedLinks(ctx context.Context, wiIDStr string) error
 	Delete(ctx context.Context, ID satoriuuid.UUID) error
 	Save(ctx context.Context, linkCat app.WorkItemLinkSingle) (*app.WorkItemLinkSingle, error)
+	// ReachableTargets returns the IDs of all work items that can be reached
+	// from the given source by following links of the given type.
+	ReachableTargets(ctx context.Context, linkTypeID satoriuuid.UUID, sourceID uint64, maxDepth int) ([]uint64, error)
+	// ReachableSources returns the IDs of all work items from which the given
+	// target can be reached by following links of the given type.
+	ReachableSources(ctx context.Context, linkTypeID satoriuuid.UUID, targetID uint64, maxDepth int) ([]uint64, error)
+	// FindRoots returns the IDs of all work items that are the source but
+	// never the target of a link of the given tree or dependency link type.
+	FindRoots(ctx context.Context, linkTypeID satoriuuid.UUID, spaceID satoriuuid.UUID) ([]uint64, error)
+	// LinkDegree returns the number of incoming and outgoing links of the
+	// given work item across all link types of the given space.
+	LinkDegree(ctx context.Context, workItemID uint64, spaceID satoriuuid.UUID) (in int, out int, err error)
+	// LinkTypesForItem returns the distinct link types of the given space
+	// that have at least one link touching the given work item.
+	LinkTypesForItem(ctx context.Context, workItemID uint64, spaceID satoriuuid.UUID) ([]WorkItemLinkType, error)
+	// AuditTopologyViolations returns the IDs of all work items whose links
+	// of the given link type violate the link type's topology.
+	AuditTopologyViolations(ctx context.Context, linkTypeID satoriuuid.UUID) ([]uint64, error)
 }
 
 // NewWorkItemLinkRepository creates a work item link repository based on gorm
@@ -90,7 +111,7 @@ func (r *GormWorkItemLinkRepository) ValidateCorrectSourceAndTargetType(ctx cont
 }
 
 // Create creates a new work item link in the repository.
-// Returns BadParameterError, ConversionError or InternalError
+// Returns BadParameterError, ReferencedEntityNotFoundError, ConversionError or InternalError
 func (r *GormWorkItemLinkRepository) Create(ctx context.Context, sourceID, targetID uint64, linkTypeID satoriuuid.UUID) (*app.WorkItemLinkSingle, error) {
 	link := &WorkItemLink{
 		SourceID:   sourceID,
@@ -100,9 +121,25 @@ func (r *GormWorkItemLinkRepository) Create(ctx context.Context, sourceID, targe
 	if err := link.CheckValidForCreation(); err != nil {
 		return nil, errs.WithStack(err)
 	}
+	if err := CheckLinkEndpointsExist(ctx, sourceID, targetID, r.workItemRepo); err != nil {
+		return nil, errs.WithStack(err)
+	}
 	if err := r.ValidateCorrectSourceAndTargetType(ctx, sourceID, targetID, linkTypeID); err != nil {
 		return nil, errs.WithStack(err)
 	}
+	linkType, err := r.workItemLinkTypeRepo.LoadTypeFromDBByID(ctx, linkTypeID)
+	if err != nil {
+		return nil, errs.WithStack(err)
+	}
+	if err := CheckNoDuplicateEdge(ctx, linkTypeID, sourceID, targetID, r); err != nil {
+		return nil, errs.WithStack(err)
+	}
+	if err := CheckMultiplicity(ctx, *linkType, sourceID, targetID, r); err != nil {
+		return nil, errs.WithStack(err)
+	}
+	if err := ValidateLinkForTopology(ctx, *linkType, sourceID, targetID, r); err != nil {
+		return nil, errs.WithStack(err)
+	}
 	db := r.db.Create(link)
 	if db.Error != nil {
 		if gormsupport.IsUniqueViolation(db.Error, "work_item_links_unique_idx") {
@@ -279,3 +316,392 @@ func (r *GormWorkItemLinkRepository) Save(ctx context.Context, lt app.WorkItemLi
 	result := ConvertLinkFromModel(res)
 	return &result, nil
 }
+
+// ReachableTargets returns the sorted IDs of all work items that can be
+// reached from the work item with the given source ID by following links of
+// the given link type from source to target. At most maxDepth links are
+// followed. Links of the network topology are undirected and are therefore
+// followed in both directions.
+// returns NotFoundError, BadParameterError or InternalError
+func (r *GormWorkItemLinkRepository) ReachableTargets(ctx context.Context, linkTypeID satoriuuid.UUID, sourceID uint64, maxDepth int) ([]uint64, error) {
+	return r.reachable(ctx, linkTypeID, sourceID, maxDepth, true)
+}
+
+// ReachableSources returns the sorted IDs of all work items from which the
+// work item with the given target ID can be reached by following links of the
+// given link type from source to target (e.g. "what items block B?"). At most
+// maxDepth links are followed. For the network topology the result is the
+// same as for ReachableTargets.
+// returns NotFoundError, BadParameterError or InternalError
+func (r *GormWorkItemLinkRepository) ReachableSources(ctx context.Context, linkTypeID satoriuuid.UUID, targetID uint64, maxDepth int) ([]uint64, error) {
+	return r.reachable(ctx, linkTypeID, targetID, maxDepth, false)
+}
+
+// reachable walks the links of the given link type breadth-first starting at
+// the given work item. If forward is true, links are followed from source to
+// target; otherwise from target to source. Work items that were already
+// visited are not visited again which protects against cycles.
+func (r *GormWorkItemLinkRepository) reachable(ctx context.Context, linkTypeID satoriuuid.UUID, startID uint64, maxDepth int, forward bool) ([]uint64, error) {
+	if maxDepth < 1 {
+		return nil, errors.NewBadParameterError("maxDepth", maxDepth).Expected("at least 1")
+	}
+	linkType, err := r.workItemLinkTypeRepo.LoadTypeFromDBByID(ctx, linkTypeID)
+	if err != nil {
+		return nil, errs.WithStack(err)
+	}
+	undirected := linkType.Topology == TopologyNetwork
+
+	visited := map[uint64]bool{startID: true}
+	frontier := []uint64{startID}
+	result := []uint64{}
+	for depth := 0; depth < maxDepth && len(frontier) > 0; depth++ {
+		var links []WorkItemLink
+		db := r.db.Where("link_type_id = ?", linkTypeID)
+		switch {
+		case undirected:
+			db = db.Where("source_id IN (?) OR target_id IN (?)", frontier, frontier)
+		case forward:
+			db = db.Where("source_id IN (?)", frontier)
+		default:
+			db = db.Where("target_id IN (?)", frontier)
+		}
+		if err := db.Find(&links).Error; err != nil {
+			return nil, errors.NewInternalError(err.Error())
+		}
+		inFrontier := map[uint64]bool{}
+		for _, id := range frontier {
+			inFrontier[id] = true
+		}
+		var next []uint64
+		for _, l := range links {
+			var neighbours []uint64
+			if undirected || forward {
+				if inFrontier[l.SourceID] {
+					neighbours = append(neighbours, l.TargetID)
+				}
+			}
+			if undirected || !forward {
+				if inFrontier[l.TargetID] {
+					neighbours = append(neighbours, l.SourceID)
+				}
+			}
+			for _, id := range neighbours {
+				if !visited[id] {
+					visited[id] = true
+					next = append(next, id)
+					result = append(result, id)
+				}
+			}
+		}
+		frontier = next
+	}
+	sort.Sort(uint64Slice(result))
+	return result, nil
+}
+
+// FindRoots returns the sorted IDs of all work items that are the source of
+// at least one link of the given link type but never the target of one. The
+// link type must belong to the given space and have the tree or dependency
+// topology, as roots are meaningless for undirected links.
+// returns NotFoundError, BadParameterError or InternalError
+func (r *GormWorkItemLinkRepository) FindRoots(ctx context.Context, linkTypeID satoriuuid.UUID, spaceID satoriuuid.UUID) ([]uint64, error) {
+	linkType, err := r.workItemLinkTypeRepo.LoadTypeFromDBByID(ctx, linkTypeID)
+	if err != nil {
+		return nil, errs.WithStack(err)
+	}
+	if !satoriuuid.Equal(linkType.SpaceID, spaceID) {
+		return nil, errors.NewNotFoundError("work item link type", linkTypeID.String())
+	}
+	if _, acyclic, _, _ := TopologyConstraints(linkType.Topology); !acyclic {
+		return nil, errors.NewBadParameterError("topology", linkType.Topology).Expected("an acyclic topology")
+	}
+	var links []WorkItemLink
+	if err := r.db.Where("link_type_id = ?", linkTypeID).Find(&links).Error; err != nil {
+		return nil, errors.NewInternalError(err.Error())
+	}
+	targets := map[uint64]bool{}
+	for _, l := range links {
+		targets[l.TargetID] = true
+	}
+	seen := map[uint64]bool{}
+	roots := []uint64{}
+	for _, l := range links {
+		if !targets[l.SourceID] && !seen[l.SourceID] {
+			seen[l.SourceID] = true
+			roots = append(roots, l.SourceID)
+		}
+	}
+	sort.Sort(uint64Slice(roots))
+	return roots, nil
+}
+
+// LinkDegree returns the number of links that have the given work item as
+// their target (in) and as their source (out). Only links whose link type
+// belongs to the given space are counted; links of all topologies are
+// treated as directed from source to target.
+// returns InternalError
+func (r *GormWorkItemLinkRepository) LinkDegree(ctx context.Context, workItemID uint64, spaceID satoriuuid.UUID) (in int, out int, err error) {
+	var links []WorkItemLink
+	db := r.db.Where("(source_id = ? OR target_id = ?) AND link_type_id IN (SELECT id FROM work_item_link_types WHERE space_id = ? AND deleted_at IS NULL)", workItemID, workItemID, spaceID).Find(&links)
+	if db.Error != nil {
+		return 0, 0, errors.NewInternalError(db.Error.Error())
+	}
+	for _, l := range links {
+		if l.TargetID == workItemID {
+			in++
+		}
+		if l.SourceID == workItemID {
+			out++
+		}
+	}
+	return in, out, nil
+}
+
+// LinkTypesForItem returns the distinct link types of the given space that
+// have at least one link with the given work item as its source or target,
+// ordered by display order and name.
+// returns InternalError
+func (r *GormWorkItemLinkRepository) LinkTypesForItem(ctx context.Context, workItemID uint64, spaceID satoriuuid.UUID) ([]WorkItemLinkType, error) {
+	var res []WorkItemLinkType
+	db := r.db.Where("space_id = ? AND id IN (SELECT link_type_id FROM work_item_links WHERE (source_id = ? OR target_id = ?) AND deleted_at IS NULL)", spaceID, workItemID, workItemID).Order("display_order, name").Find(&res)
+	if db.Error != nil {
+		return nil, errors.NewInternalError(db.Error.Error())
+	}
+	return res, nil
+}
+
+// ValidateLinkForTopology checks whether a link of the given link type from
+// the given source to the given target work item obeys the rules of the link
+// type's topology. For the tree topology the target must not have a parent
+// yet and the link must not close a cycle. For the dependency topology the
+// link must not close a cycle. The directed_network and network topologies
+// impose no restrictions.
+// returns BadParameterError for a violation, TopologyError for an unknown
+// topology or the errors of the given repository
+func ValidateLinkForTopology(ctx context.Context, t WorkItemLinkType, sourceID, targetID uint64, repo WorkItemLinkRepository) error {
+	_, acyclic, singleParent, err := TopologyConstraints(t.Topology)
+	if err != nil {
+		return checkValidTopology(t.Topology, t.ID)
+	}
+	if singleParent {
+		parents, err := repo.ReachableSources(ctx, t.ID, targetID, 1)
+		if err != nil {
+			return errs.WithStack(err)
+		}
+		if len(parents) > 0 {
+			return errors.NewBadParameterError("target_id", targetID).Expected(fmt.Sprintf("a work item without a parent for the %s topology, but it already has parent %d", t.Topology, parents[0]))
+		}
+	}
+	if acyclic {
+		return errs.WithStack(checkNoCycle(ctx, t, sourceID, targetID, repo))
+	}
+	return nil
+}
+
+// CheckLinkEndpointsExist returns a ReferencedEntityNotFoundError if the
+// given source or target work item doesn't exist or has been deleted. The
+// kind of the error tells which of the two endpoints is missing.
+// returns ReferencedEntityNotFoundError or the errors of the given repository
+func CheckLinkEndpointsExist(ctx context.Context, sourceID, targetID uint64, repo workitem.WorkItemRepository) error {
+	endpoints := []struct {
+		id   uint64
+		kind string
+	}{
+		{sourceID, ReferencedKindSourceWorkItem},
+		{targetID, ReferencedKindTargetWorkItem},
+	}
+	for _, endpoint := range endpoints {
+		id := strconv.FormatUint(endpoint.id, 10)
+		if _, err := repo.Load(ctx, id); err != nil {
+			if _, ok := errs.Cause(err).(errors.NotFoundError); ok {
+				return errors.NewReferencedEntityNotFoundError(endpoint.kind, id)
+			}
+			return errs.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// CheckMultiplicity returns a BadParameterError if a new link of the given
+// link type from the given source to the given target work item would exceed
+// the link type's MaxTargetCount for the source or its MaxSourceCount for the
+// target. Links of the network topology are undirected, so for them all links
+// of a work item count toward both limits.
+// returns BadParameterError or the errors of the given repository
+func CheckMultiplicity(ctx context.Context, t WorkItemLinkType, sourceID, targetID uint64, repo WorkItemLinkRepository) error {
+	if t.MaxTargetCount != nil {
+		targets, err := repo.ReachableTargets(ctx, t.ID, sourceID, 1)
+		if err != nil {
+			return errs.WithStack(err)
+		}
+		if len(targets) >= *t.MaxTargetCount {
+			return errors.NewBadParameterError("source_id", sourceID).Expected(fmt.Sprintf("a work item with less than %d targets for link type %s", *t.MaxTargetCount, t.Name))
+		}
+	}
+	if t.MaxSourceCount != nil {
+		sources, err := repo.ReachableSources(ctx, t.ID, targetID, 1)
+		if err != nil {
+			return errs.WithStack(err)
+		}
+		if len(sources) >= *t.MaxSourceCount {
+			return errors.NewBadParameterError("target_id", targetID).Expected(fmt.Sprintf("a work item with less than %d sources for link type %s", *t.MaxSourceCount, t.Name))
+		}
+	}
+	return nil
+}
+
+// CheckNoDuplicateEdge returns a BadParameterError if a link of the given
+// link type from the given source to the given target work item already
+// exists. Links of the network topology are undirected, so for them a link
+// from the target to the source counts as the same edge.
+// returns BadParameterError or the errors of the given repository
+func CheckNoDuplicateEdge(ctx context.Context, linkTypeID satoriuuid.UUID, sourceID, targetID uint64, repo WorkItemLinkRepository) error {
+	// ReachableTargets already follows network links in both directions.
+	targets, err := repo.ReachableTargets(ctx, linkTypeID, sourceID, 1)
+	if err != nil {
+		return errs.WithStack(err)
+	}
+	for _, id := range targets {
+		if id == targetID {
+			return errors.NewBadParameterError("target_id", targetID).Expected(fmt.Sprintf("a work item not yet linked to source %d by link type %s", sourceID, linkTypeID))
+		}
+	}
+	return nil
+}
+
+// checkNoCycle returns a BadParameterError if a link of the given link type
+// from the given source to the given target would close a cycle, i.e. if the
+// source equals the target or can already be reached from the target.
+func checkNoCycle(ctx context.Context, t WorkItemLinkType, sourceID, targetID uint64, repo WorkItemLinkRepository) error {
+	if sourceID == targetID {
+		return errors.NewBadParameterError("target_id", targetID).Expected(fmt.Sprintf("a work item other than the source for the %s topology", t.Topology))
+	}
+	reachable, err := repo.ReachableTargets(ctx, t.ID, targetID, math.MaxInt32)
+	if err != nil {
+		return errs.WithStack(err)
+	}
+	for _, id := range reachable {
+		if id == sourceID {
+			return errors.NewBadParameterError("target_id", targetID).Expected(fmt.Sprintf("a work item that doesn't lead back to source %d for the %s topology", sourceID, t.Topology))
+		}
+	}
+	return nil
+}
+
+// AuditTopologyViolations returns the sorted IDs of all work items whose
+// existing links of the given link type violate the link type's current
+// topology. This can happen for links created before the topology was
+// changed. For the tree topology, targets with more than one parent and work
+// items on a cycle are returned. For the dependency topology, work items on a
+// cycle are returned. Links of the network and directed_network topologies
+// can't violate their topology.
+// returns NotFoundError or InternalError
+func (r *GormWorkItemLinkRepository) AuditTopologyViolations(ctx context.Context, linkTypeID satoriuuid.UUID) ([]uint64, error) {
+	linkType, err := r.workItemLinkTypeRepo.LoadTypeFromDBByID(ctx, linkTypeID)
+	if err != nil {
+		return nil, errs.WithStack(err)
+	}
+	_, acyclic, singleParent, _ := TopologyConstraints(linkType.Topology)
+	if !acyclic && !singleParent {
+		return []uint64{}, nil
+	}
+	var links []WorkItemLink
+	if err := r.db.Where("link_type_id = ?", linkTypeID).Find(&links).Error; err != nil {
+		return nil, errors.NewInternalError(err.Error())
+	}
+	violations := map[uint64]bool{}
+	if singleParent {
+		parents := map[uint64]int{}
+		for _, l := range links {
+			parents[l.TargetID]++
+			if parents[l.TargetID] > 1 {
+				violations[l.TargetID] = true
+			}
+		}
+	}
+	if acyclic {
+		for _, id := range nodesOnCycles(links) {
+			violations[id] = true
+		}
+	}
+	result := []uint64{}
+	for id := range violations {
+		result = append(result, id)
+	}
+	sort.Sort(uint64Slice(result))
+	return result, nil
+}
+
+// nodesOnCycles returns the IDs of all work items that are part of a cycle
+// of the directed graph formed by the given links. It uses Tarjan's algorithm
+// for strongly connected components: every component with more than one node
+// and every node linked to itself lies on a cycle.
+func nodesOnCycles(links []WorkItemLink) []uint64 {
+	edges := map[uint64][]uint64{}
+	var nodes []uint64
+	seen := map[uint64]bool{}
+	var result []uint64
+	for _, l := range links {
+		edges[l.SourceID] = append(edges[l.SourceID], l.TargetID)
+		for _, id := range []uint64{l.SourceID, l.TargetID} {
+			if !seen[id] {
+				seen[id] = true
+				nodes = append(nodes, id)
+			}
+		}
+		if l.SourceID == l.TargetID {
+			result = append(result, l.SourceID)
+		}
+	}
+
+	index := map[uint64]int{}
+	lowlink := map[uint64]int{}
+	onStack := map[uint64]bool{}
+	var stack []uint64
+	var strongConnect func(id uint64)
+	strongConnect = func(id uint64) {
+		index[id] = len(index)
+		lowlink[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+		for _, next := range edges[id] {
+			if _, visited := index[next]; !visited {
+				strongConnect(next)
+				if lowlink[next] < lowlink[id] {
+					lowlink[id] = lowlink[next]
+				}
+			} else if onStack[next] && index[next] < lowlink[id] {
+				lowlink[id] = index[next]
+			}
+		}
+		if lowlink[id] != index[id] {
+			return
+		}
+		var component []uint64
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == id {
+				break
+			}
+		}
+		if len(component) > 1 {
+			result = append(result, component...)
+		}
+	}
+	for _, id := range nodes {
+		if _, visited := index[id]; !visited {
+			strongConnect(id)
+		}
+	}
+	return result
+}
+
+// uint64Slice attaches the methods of sort.Interface to []uint64
+type uint64Slice []uint64
+
+func (s uint64Slice) Len() int           { return len(s) }
+func (s uint64Slice) Less(i, j int) bool { return s[i] < s[j] }
+func (s uint64Slice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,444 @@
+package link_test
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/almighty/almighty-core/errors"
+	"github.com/almighty/almighty-core/gormsupport"
+	"github.com/almighty/almighty-core/gormsupport/cleaner"
+	"github.com/almighty/almighty-core/migration"
+	"github.com/almighty/almighty-core/models"
+	"github.com/almighty/almighty-core/resource"
+	"github.com/almighty/almighty-core/space"
+	testsupport "github.com/almighty/almighty-core/test"
+	"github.com/almighty/almighty-core/workitem"
+	"github.com/almighty/almighty-core/workitem/link"
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+	satoriuuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type workItemLinkRepoBlackBoxTest struct {
+	gormsupport.DBTestSuite
+	clean      func()
+	repo       *link.GormWorkItemLinkRepository
+	categoryID satoriuuid.UUID
+	creatorID  satoriuuid.UUID
+	spaceID    satoriuuid.UUID
+}
+
+func TestRunWorkItemLinkRepoBlackBoxTest(t *testing.T) {
+	suite.Run(t, &workItemLinkRepoBlackBoxTest{DBTestSuite: gormsupport.NewDBTestSuite("../../config.yaml")})
+}
+
+// SetupSuite overrides the DBTestSuite's function but calls it before doing anything else
+// The SetupSuite method will run before the tests in the suite are run.
+// It sets up a database connection for all the tests in this suite without polluting global space.
+func (s *workItemLinkRepoBlackBoxTest) SetupSuite() {
+	s.DBTestSuite.SetupSuite()
+
+	// Make sure the database is populated with the correct types (e.g. bug etc.)
+	if _, c := os.LookupEnv(resource.Database); c != false {
+		if err := models.Transactional(s.DB, func(tx *gorm.DB) error {
+			return migration.PopulateCommonTypes(context.Background(), tx, workitem.NewWorkItemTypeRepository(tx))
+		}); err != nil {
+			panic(err.Error())
+		}
+	}
+}
+
+func (s *workItemLinkRepoBlackBoxTest) SetupTest() {
+	s.clean = cleaner.DeleteCreatedEntities(s.DB)
+	s.repo = link.NewWorkItemLinkRepository(s.DB)
+	testIdentity, err := testsupport.CreateTestIdentity(s.DB, "jdoe", "test")
+	require.Nil(s.T(), err)
+	s.creatorID = testIdentity.ID
+	categoryName := "test-reachable-category"
+	cat, err := link.NewWorkItemLinkCategoryRepository(s.DB).Create(context.Background(), &categoryName, nil)
+	require.Nil(s.T(), err)
+	s.categoryID = *cat.Data.ID
+	sp, err := space.NewRepository(s.DB).Create(context.Background(), &space.Space{Name: satoriuuid.NewV4().String()})
+	require.Nil(s.T(), err)
+	s.spaceID = sp.ID
+}
+
+func (s *workItemLinkRepoBlackBoxTest) TearDownTest() {
+	s.clean()
+}
+
+// createLinkType creates a bug to bug work item link type with the given topology
+// in the test space
+func (s *workItemLinkRepoBlackBoxTest) createLinkType(name, topology string) satoriuuid.UUID {
+	lt, err := link.NewWorkItemLinkTypeRepository(s.DB).Create(context.Background(), name, nil, workitem.SystemBug, workitem.SystemBug, "test-fwd", "test-rev", topology, s.categoryID, s.spaceID)
+	require.Nil(s.T(), err)
+	return *lt.Data.ID
+}
+
+// createWorkItem creates a bug work item and returns its ID
+func (s *workItemLinkRepoBlackBoxTest) createWorkItem(title string) uint64 {
+	wi, err := workitem.NewWorkItemRepository(s.DB).Create(
+		context.Background(), workitem.SystemBug,
+		map[string]interface{}{
+			workitem.SystemTitle: title,
+			workitem.SystemState: workitem.SystemStateNew,
+		}, s.creatorID)
+	require.Nil(s.T(), err)
+	id, err := strconv.ParseUint(wi.ID, 10, 64)
+	require.Nil(s.T(), err)
+	return id
+}
+
+// createLink links the given source and target work items
+func (s *workItemLinkRepoBlackBoxTest) createLink(sourceID, targetID uint64, linkTypeID satoriuuid.UUID) {
+	_, err := s.repo.Create(context.Background(), sourceID, targetID, linkTypeID)
+	require.Nil(s.T(), err)
+}
+
+// createLinkWithoutChecks stores a link directly in the database to simulate
+// links that violate the topology of their link type
+func (s *workItemLinkRepoBlackBoxTest) createLinkWithoutChecks(sourceID, targetID uint64, linkTypeID satoriuuid.UUID) {
+	l := link.WorkItemLink{SourceID: sourceID, TargetID: targetID, LinkTypeID: linkTypeID}
+	require.Nil(s.T(), s.DB.Create(&l).Error)
+}
+
+func (s *workItemLinkRepoBlackBoxTest) TestReachableOnDirectedGraph() {
+	ctx := context.Background()
+	linkTypeID := s.createLinkType("test-reachable-directed", link.TopologyDirectedNetwork)
+	a := s.createWorkItem("a")
+	b := s.createWorkItem("b")
+	c := s.createWorkItem("c")
+	d := s.createWorkItem("d")
+	// a -> b -> c -> a and d -> b
+	s.createLink(a, b, linkTypeID)
+	s.createLink(b, c, linkTypeID)
+	s.createLink(c, a, linkTypeID)
+	s.createLink(d, b, linkTypeID)
+
+	// Test forward traversal with cycle protection
+	targets, err := s.repo.ReachableTargets(ctx, linkTypeID, a, 10)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []uint64{b, c}, targets)
+
+	// Test reverse traversal differs from forward traversal
+	sources, err := s.repo.ReachableSources(ctx, linkTypeID, b, 10)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []uint64{a, c, d}, sources)
+	targets, err = s.repo.ReachableTargets(ctx, linkTypeID, b, 10)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []uint64{a, c}, targets)
+
+	// Test depth cap
+	sources, err = s.repo.ReachableSources(ctx, linkTypeID, b, 1)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []uint64{a, d}, sources)
+
+	// Test invalid depth cap
+	_, err = s.repo.ReachableSources(ctx, linkTypeID, b, 0)
+	require.NotNil(s.T(), err)
+}
+
+func (s *workItemLinkRepoBlackBoxTest) TestReachableOnNetwork() {
+	ctx := context.Background()
+	linkTypeID := s.createLinkType("test-reachable-network", link.TopologyNetwork)
+	x := s.createWorkItem("x")
+	y := s.createWorkItem("y")
+	z := s.createWorkItem("z")
+	// x - y - z
+	s.createLink(x, y, linkTypeID)
+	s.createLink(y, z, linkTypeID)
+
+	// Test that both directions yield the same result
+	for _, id := range []uint64{x, y, z} {
+		targets, err := s.repo.ReachableTargets(ctx, linkTypeID, id, 10)
+		require.Nil(s.T(), err)
+		sources, err := s.repo.ReachableSources(ctx, linkTypeID, id, 10)
+		require.Nil(s.T(), err)
+		require.Equal(s.T(), targets, sources)
+		require.Len(s.T(), sources, 2)
+	}
+}
+
+func (s *workItemLinkRepoBlackBoxTest) TestFindRoots() {
+	ctx := context.Background()
+	linkTypeID := s.createLinkType("test-roots-dependency", link.TopologyDependency)
+	// first tree: a -> b, a -> c, c -> d
+	a := s.createWorkItem("a")
+	b := s.createWorkItem("b")
+	c := s.createWorkItem("c")
+	d := s.createWorkItem("d")
+	s.createLink(a, b, linkTypeID)
+	s.createLink(a, c, linkTypeID)
+	s.createLink(c, d, linkTypeID)
+	// second tree: x -> y
+	x := s.createWorkItem("x")
+	y := s.createWorkItem("y")
+	s.createLink(x, y, linkTypeID)
+
+	// Test both roots of the forest are returned
+	roots, err := s.repo.FindRoots(ctx, linkTypeID, s.spaceID)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []uint64{a, x}, roots)
+
+	// Test link type of another space
+	_, err = s.repo.FindRoots(ctx, linkTypeID, satoriuuid.NewV4())
+	require.IsType(s.T(), errors.NotFoundError{}, errs.Cause(err))
+
+	// Test network topology
+	networkID := s.createLinkType("test-roots-network", link.TopologyNetwork)
+	_, err = s.repo.FindRoots(ctx, networkID, s.spaceID)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+}
+
+func (s *workItemLinkRepoBlackBoxTest) TestLinkDegree() {
+	ctx := context.Background()
+	dependencyID := s.createLinkType("test-degree-dependency", link.TopologyDependency)
+	networkID := s.createLinkType("test-degree-network", link.TopologyNetwork)
+	a := s.createWorkItem("a")
+	b := s.createWorkItem("b")
+	c := s.createWorkItem("c")
+	d := s.createWorkItem("d")
+	lonely := s.createWorkItem("lonely")
+	// a -> b, a -> c, d -> a (dependency) and b - a (network)
+	s.createLink(a, b, dependencyID)
+	s.createLink(a, c, dependencyID)
+	s.createLink(d, a, dependencyID)
+	s.createLink(b, a, networkID)
+
+	// Test an item with several incoming and outgoing links
+	in, out, err := s.repo.LinkDegree(ctx, a, s.spaceID)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), 2, in)
+	require.Equal(s.T(), 2, out)
+
+	// Test an item without links
+	in, out, err = s.repo.LinkDegree(ctx, lonely, s.spaceID)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), 0, in)
+	require.Equal(s.T(), 0, out)
+
+	// Test that links of another space's link types are not counted
+	in, out, err = s.repo.LinkDegree(ctx, a, satoriuuid.NewV4())
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), 0, in)
+	require.Equal(s.T(), 0, out)
+}
+
+func (s *workItemLinkRepoBlackBoxTest) TestLinkTypesForItem() {
+	ctx := context.Background()
+	dependencyID := s.createLinkType("test-types-for-item-dependency", link.TopologyDependency)
+	networkID := s.createLinkType("test-types-for-item-network", link.TopologyNetwork)
+	s.createLinkType("test-types-for-item-unused", link.TopologyNetwork)
+	a := s.createWorkItem("a")
+	b := s.createWorkItem("b")
+	c := s.createWorkItem("c")
+	lonely := s.createWorkItem("lonely")
+	// a -> b, c -> a (dependency) and b - a (network)
+	s.createLink(a, b, dependencyID)
+	s.createLink(c, a, dependencyID)
+	s.createLink(b, a, networkID)
+
+	// Test an item with links of two types
+	types, err := s.repo.LinkTypesForItem(ctx, a, s.spaceID)
+	require.Nil(s.T(), err)
+	require.Len(s.T(), types, 2)
+	require.Equal(s.T(), dependencyID, types[0].ID)
+	require.Equal(s.T(), networkID, types[1].ID)
+
+	// Test an item without links
+	types, err = s.repo.LinkTypesForItem(ctx, lonely, s.spaceID)
+	require.Nil(s.T(), err)
+	require.Empty(s.T(), types)
+
+	// Test that link types of another space are not returned
+	types, err = s.repo.LinkTypesForItem(ctx, a, satoriuuid.NewV4())
+	require.Nil(s.T(), err)
+	require.Empty(s.T(), types)
+}
+
+func (s *workItemLinkRepoBlackBoxTest) TestValidateLinkForTopology() {
+	ctx := context.Background()
+	linkTypeRepo := link.NewWorkItemLinkTypeRepository(s.DB)
+	loadLinkType := func(id satoriuuid.UUID) link.WorkItemLinkType {
+		lt, err := linkTypeRepo.LoadTypeFromDBByID(ctx, id)
+		require.Nil(s.T(), err)
+		return *lt
+	}
+	a := s.createWorkItem("a")
+	b := s.createWorkItem("b")
+	c := s.createWorkItem("c")
+
+	// Test tree topology: single parent and acyclicity
+	created, err := linkTypeRepo.Create(ctx, "test-validate-tree", nil, workitem.SystemBug, workitem.SystemPlannerItem, "test-parent of", "test-child of", link.TopologyTree, s.categoryID, s.spaceID)
+	require.Nil(s.T(), err)
+	tree := loadLinkType(*created.Data.ID)
+	s.createLink(a, b, tree.ID)
+	require.Nil(s.T(), link.ValidateLinkForTopology(ctx, tree, b, c, s.repo))
+	err = link.ValidateLinkForTopology(ctx, tree, c, b, s.repo)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+	require.Contains(s.T(), err.Error(), "parent")
+	err = link.ValidateLinkForTopology(ctx, tree, b, a, s.repo)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+	require.Contains(s.T(), err.Error(), "lead back")
+
+	// Test dependency topology: multiple parents but no cycles
+	dependency := loadLinkType(s.createLinkType("test-validate-dependency", link.TopologyDependency))
+	s.createLink(a, b, dependency.ID)
+	s.createLink(b, c, dependency.ID)
+	require.Nil(s.T(), link.ValidateLinkForTopology(ctx, dependency, a, c, s.repo))
+	err = link.ValidateLinkForTopology(ctx, dependency, c, a, s.repo)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+	err = link.ValidateLinkForTopology(ctx, dependency, a, a, s.repo)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+
+	// Test directed network topology: cycles and multiple edges are allowed
+	directed := loadLinkType(s.createLinkType("test-validate-directed", link.TopologyDirectedNetwork))
+	s.createLink(a, b, directed.ID)
+	require.Nil(s.T(), link.ValidateLinkForTopology(ctx, directed, b, a, s.repo))
+	require.Nil(s.T(), link.ValidateLinkForTopology(ctx, directed, c, b, s.repo))
+
+	// Test network topology: anything is allowed
+	network := loadLinkType(s.createLinkType("test-validate-network", link.TopologyNetwork))
+	s.createLink(a, b, network.ID)
+	require.Nil(s.T(), link.ValidateLinkForTopology(ctx, network, b, a, s.repo))
+	require.Nil(s.T(), link.ValidateLinkForTopology(ctx, network, a, a, s.repo))
+
+	// Test that a second parent in the tree topology is rejected on creation
+	_, err = s.repo.Create(ctx, c, b, tree.ID)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+	require.Contains(s.T(), err.Error(), "parent")
+}
+
+func (s *workItemLinkRepoBlackBoxTest) TestAuditTopologyViolations() {
+	ctx := context.Background()
+	a := s.createWorkItem("a")
+	b := s.createWorkItem("b")
+	c := s.createWorkItem("c")
+	d := s.createWorkItem("d")
+	e := s.createWorkItem("e")
+
+	// Test tree topology with a target of two parents and a cycle
+	created, err := link.NewWorkItemLinkTypeRepository(s.DB).Create(ctx, "test-audit-tree", nil, workitem.SystemBug, workitem.SystemPlannerItem, "test-parent of", "test-child of", link.TopologyTree, s.categoryID, s.spaceID)
+	require.Nil(s.T(), err)
+	treeID := *created.Data.ID
+	s.createLink(a, b, treeID)
+	s.createLinkWithoutChecks(c, b, treeID)
+	s.createLink(d, e, treeID)
+	s.createLinkWithoutChecks(e, d, treeID)
+	violations, err := s.repo.AuditTopologyViolations(ctx, treeID)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []uint64{b, d, e}, violations)
+
+	// Test dependency topology with a cycle and a target of two parents,
+	// which is allowed
+	dependencyID := s.createLinkType("test-audit-dependency", link.TopologyDependency)
+	s.createLink(a, c, dependencyID)
+	s.createLink(b, c, dependencyID)
+	s.createLink(c, d, dependencyID)
+	s.createLinkWithoutChecks(d, b, dependencyID)
+	violations, err = s.repo.AuditTopologyViolations(ctx, dependencyID)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []uint64{b, c, d}, violations)
+
+	// Test network topology without violations
+	networkID := s.createLinkType("test-audit-network", link.TopologyNetwork)
+	s.createLink(a, b, networkID)
+	s.createLink(b, a, networkID)
+	violations, err = s.repo.AuditTopologyViolations(ctx, networkID)
+	require.Nil(s.T(), err)
+	require.Empty(s.T(), violations)
+}
+
+func (s *workItemLinkRepoBlackBoxTest) TestCheckMultiplicity() {
+	ctx := context.Background()
+	linkTypeID := s.createLinkType("test-multiplicity-one-to-one", link.TopologyDependency)
+	db := s.DB.Model(&link.WorkItemLinkType{}).Where("id = ?", linkTypeID).Updates(map[string]interface{}{
+		"max_source_count": 1,
+		"max_target_count": 1,
+	})
+	require.Nil(s.T(), db.Error)
+	lt, err := link.NewWorkItemLinkTypeRepository(s.DB).LoadTypeFromDBByID(ctx, linkTypeID)
+	require.Nil(s.T(), err)
+	a := s.createWorkItem("a")
+	b := s.createWorkItem("b")
+	c := s.createWorkItem("c")
+	d := s.createWorkItem("d")
+
+	// Test links at the limit
+	require.Nil(s.T(), link.CheckMultiplicity(ctx, *lt, a, b, s.repo))
+	s.createLink(a, b, linkTypeID)
+	s.createLink(c, d, linkTypeID)
+
+	// Test a second target of the same source
+	err = link.CheckMultiplicity(ctx, *lt, a, d, s.repo)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+	require.Contains(s.T(), err.Error(), "source_id")
+
+	// Test a second source of the same target
+	err = link.CheckMultiplicity(ctx, *lt, c, b, s.repo)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+	require.Contains(s.T(), err.Error(), "target_id")
+
+	// Test the limit is enforced at link creation time
+	_, err = s.repo.Create(ctx, a, c, linkTypeID)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+}
+
+func (s *workItemLinkRepoBlackBoxTest) TestCheckLinkEndpointsExist() {
+	ctx := context.Background()
+	wiRepo := workitem.NewWorkItemRepository(s.DB)
+	a := s.createWorkItem("a")
+	b := s.createWorkItem("b")
+	deleted := s.createWorkItem("deleted")
+	require.Nil(s.T(), wiRepo.Delete(ctx, strconv.FormatUint(deleted, 10), s.creatorID))
+	checkMissing := func(err error, kind string, id uint64) {
+		require.IsType(s.T(), errors.ReferencedEntityNotFoundError{}, errs.Cause(err))
+		require.Equal(s.T(), kind, errs.Cause(err).(errors.ReferencedEntityNotFoundError).Kind)
+		require.Equal(s.T(), strconv.FormatUint(id, 10), errs.Cause(err).(errors.ReferencedEntityNotFoundError).ID)
+	}
+
+	// Test both endpoints present
+	require.Nil(s.T(), link.CheckLinkEndpointsExist(ctx, a, b, wiRepo))
+
+	// Test missing source
+	checkMissing(link.CheckLinkEndpointsExist(ctx, 0, b, wiRepo), link.ReferencedKindSourceWorkItem, 0)
+	checkMissing(link.CheckLinkEndpointsExist(ctx, deleted, b, wiRepo), link.ReferencedKindSourceWorkItem, deleted)
+
+	// Test missing target
+	checkMissing(link.CheckLinkEndpointsExist(ctx, a, 0, wiRepo), link.ReferencedKindTargetWorkItem, 0)
+	checkMissing(link.CheckLinkEndpointsExist(ctx, a, deleted, wiRepo), link.ReferencedKindTargetWorkItem, deleted)
+
+	// Test the check is performed at link creation time
+	linkTypeID := s.createLinkType("test-endpoints-network", link.TopologyNetwork)
+	_, err := s.repo.Create(ctx, a, deleted, linkTypeID)
+	checkMissing(err, link.ReferencedKindTargetWorkItem, deleted)
+}
+
+func (s *workItemLinkRepoBlackBoxTest) TestCheckNoDuplicateEdge() {
+	ctx := context.Background()
+	a := s.createWorkItem("a")
+	b := s.createWorkItem("b")
+
+	// Test duplicate directed edge
+	directedID := s.createLinkType("test-duplicate-directed", link.TopologyDirectedNetwork)
+	s.createLink(a, b, directedID)
+	err := link.CheckNoDuplicateEdge(ctx, directedID, a, b, s.repo)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+	_, err = s.repo.Create(ctx, a, b, directedID)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+	// the reversed directed edge is a different edge
+	require.Nil(s.T(), link.CheckNoDuplicateEdge(ctx, directedID, b, a, s.repo))
+
+	// Test network edge in reversed order
+	networkID := s.createLinkType("test-duplicate-network", link.TopologyNetwork)
+	s.createLink(a, b, networkID)
+	err = link.CheckNoDuplicateEdge(ctx, networkID, b, a, s.repo)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+	_, err = s.repo.Create(ctx, b, a, networkID)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+}
@@ -1,11 +1,18 @@
 package link
 
 import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
 	"github.com/almighty/almighty-core/app"
 	convert "github.com/almighty/almighty-core/convert"
 	"github.com/almighty/almighty-core/errors"
 	"github.com/almighty/almighty-core/gormsupport"
 	"github.com/almighty/almighty-core/rest"
+	"github.com/almighty/almighty-core/space"
+	"github.com/almighty/almighty-core/workitem"
 
 	"github.com/goadesign/goa"
 	errs "github.com/pkg/errors"
@@ -25,6 +32,30 @@ const (
 	SystemWorkItemLinkPlannerItemRelated = "Related planner item"
 )
 
+// reservedLinkTypeNames contains the names of the system link types. Other
+// code matches system link types by these names, so user-created link types
+// must not use them.
+var reservedLinkTypeNames = []string{
+	SystemWorkItemLinkTypeBugBlocker,
+	SystemWorkItemLinkPlannerItemRelated,
+}
+
+// ReservedSpaceID is the ID of the system space. Only system link types (see
+// WorkItemLinkType.System) may be created in it.
+var ReservedSpaceID = space.SystemSpace
+
+// IsReservedLinkTypeName returns true if the given name is reserved for a
+// system link type; the comparison ignores case and surrounding whitespace.
+func IsReservedLinkTypeName(name string) bool {
+	name = strings.TrimSpace(name)
+	for _, reserved := range reservedLinkTypeNames {
+		if strings.EqualFold(name, reserved) {
+			return true
+		}
+	}
+	return false
+}
+
 // returns true if the left hand and right hand side string
 // pointers either both point to nil or reference the same
 // content; otherwise false is returned.
@@ -41,6 +72,38 @@ func strPtrIsNilOrContentIsEqual(l, r *string) bool {
 	return *l == *r
 }
 
+// returns true if the left hand and right hand side int
+// pointers either both point to nil or reference the same
+// content; otherwise false is returned.
+func intPtrIsNilOrContentIsEqual(l, r *int) bool {
+	if l == nil && r != nil {
+		return false
+	}
+	if l != nil && r == nil {
+		return false
+	}
+	if l == nil && r == nil {
+		return true
+	}
+	return *l == *r
+}
+
+// returns true if the left hand and right hand side time
+// pointers either both point to nil or reference the same
+// point in time; otherwise false is returned.
+func timePtrIsNilOrContentIsEqual(l, r *time.Time) bool {
+	if l == nil && r != nil {
+		return false
+	}
+	if l != nil && r == nil {
+		return false
+	}
+	if l == nil && r == nil {
+		return true
+	}
+	return l.Equal(*r)
+}
+
 // WorkItemLinkType represents the type of a work item link as it is stored in the db
 type WorkItemLinkType struct {
 	gormsupport.Lifecycle
@@ -64,6 +127,36 @@ type WorkItemLinkType struct {
 
 	// Reference to one Space
 	SpaceID satoriuuid.UUID `sql:"type:uuid"`
+
+	// AllowSameType explicitly permits a link type of the tree topology to
+	// use the same work item type as source and target. It is only consulted
+	// by CheckValidForCreation and is not persisted.
+	AllowSameType bool `sql:"-"`
+
+	// System marks a link type that is created by the system itself and may
+	// therefore use a reserved name (see IsReservedLinkTypeName). It is only
+	// consulted by CheckValidForCreation and is not persisted.
+	System bool `sql:"-"`
+
+	// DeprecatedAt is the point in time at which this link type was
+	// deprecated. Deprecated link types are hidden from pickers but remain
+	// listable for administrators. A nil value means the link type is active.
+	DeprecatedAt *time.Time
+
+	// MaxSourceCount limits the number of sources a single work item can be
+	// linked to as target with this link type. Nil means unlimited.
+	MaxSourceCount *int
+	// MaxTargetCount limits the number of targets a single work item can be
+	// linked to as source with this link type. Nil means unlimited.
+	MaxTargetCount *int
+
+	// DisplayOrder determines the position of the link type in pickers;
+	// link types are listed by ascending display order (see Reorder).
+	DisplayOrder int
+
+	// IsDefault marks the link type that is preselected when creating a link
+	// in its space. A space has at most one default link type.
+	IsDefault bool
 }
 
 // Ensure Fields implements the Equaler interface
@@ -112,28 +205,139 @@ func (t WorkItemLinkType) Equal(u convert.Equaler) bool {
 	if !satoriuuid.Equal(t.SpaceID, other.SpaceID) {
 		return false
 	}
+	if !timePtrIsNilOrContentIsEqual(t.DeprecatedAt, other.DeprecatedAt) {
+		return false
+	}
+	if !intPtrIsNilOrContentIsEqual(t.MaxSourceCount, other.MaxSourceCount) {
+		return false
+	}
+	if !intPtrIsNilOrContentIsEqual(t.MaxTargetCount, other.MaxTargetCount) {
+		return false
+	}
+	if t.DisplayOrder != other.DisplayOrder {
+		return false
+	}
+	if t.IsDefault != other.IsDefault {
+		return false
+	}
 	return true
 }
 
+// Diff returns the attributes in which the given link type differs from this
+// one. The map is keyed by attribute name and holds the old value of this
+// link type and the new value of the other one. Pointer attributes are
+// dereferenced and unset ones are reported as nil. The ID, the version and
+// the lifecycle timestamps are ignored, so an empty map means that there is
+// no semantic change.
+func (t WorkItemLinkType) Diff(other WorkItemLinkType) map[string][2]interface{} {
+	diff := map[string][2]interface{}{}
+	if t.Name != other.Name {
+		diff["name"] = [2]interface{}{t.Name, other.Name}
+	}
+	if !strPtrIsNilOrContentIsEqual(t.Description, other.Description) {
+		diff["description"] = [2]interface{}{strPtrValue(t.Description), strPtrValue(other.Description)}
+	}
+	if t.Topology != other.Topology {
+		diff["topology"] = [2]interface{}{t.Topology, other.Topology}
+	}
+	if t.ForwardName != other.ForwardName {
+		diff["forward_name"] = [2]interface{}{t.ForwardName, other.ForwardName}
+	}
+	if t.ReverseName != other.ReverseName {
+		diff["reverse_name"] = [2]interface{}{t.ReverseName, other.ReverseName}
+	}
+	if !satoriuuid.Equal(t.SourceTypeID, other.SourceTypeID) {
+		diff["source_type_id"] = [2]interface{}{t.SourceTypeID, other.SourceTypeID}
+	}
+	if !satoriuuid.Equal(t.TargetTypeID, other.TargetTypeID) {
+		diff["target_type_id"] = [2]interface{}{t.TargetTypeID, other.TargetTypeID}
+	}
+	if !satoriuuid.Equal(t.LinkCategoryID, other.LinkCategoryID) {
+		diff["link_category_id"] = [2]interface{}{t.LinkCategoryID, other.LinkCategoryID}
+	}
+	if !satoriuuid.Equal(t.SpaceID, other.SpaceID) {
+		diff["space_id"] = [2]interface{}{t.SpaceID, other.SpaceID}
+	}
+	if !timePtrIsNilOrContentIsEqual(t.DeprecatedAt, other.DeprecatedAt) {
+		diff["deprecated_at"] = [2]interface{}{timePtrValue(t.DeprecatedAt), timePtrValue(other.DeprecatedAt)}
+	}
+	if !intPtrIsNilOrContentIsEqual(t.MaxSourceCount, other.MaxSourceCount) {
+		diff["max_source_count"] = [2]interface{}{intPtrValue(t.MaxSourceCount), intPtrValue(other.MaxSourceCount)}
+	}
+	if !intPtrIsNilOrContentIsEqual(t.MaxTargetCount, other.MaxTargetCount) {
+		diff["max_target_count"] = [2]interface{}{intPtrValue(t.MaxTargetCount), intPtrValue(other.MaxTargetCount)}
+	}
+	if t.DisplayOrder != other.DisplayOrder {
+		diff["display_order"] = [2]interface{}{t.DisplayOrder, other.DisplayOrder}
+	}
+	if t.IsDefault != other.IsDefault {
+		diff["is_default"] = [2]interface{}{t.IsDefault, other.IsDefault}
+	}
+	return diff
+}
+
+// strPtrValue returns the content of the given pointer or nil
+func strPtrValue(p *string) interface{} {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+// intPtrValue returns the content of the given pointer or nil
+func intPtrValue(p *int) interface{} {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+// timePtrValue returns the content of the given pointer or nil
+func timePtrValue(p *time.Time) interface{} {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
 // CheckValidForCreation returns an error if the work item link type
 // cannot be used for the creation of a new work item link type.
 func (t *WorkItemLinkType) CheckValidForCreation() error {
 	if t.Name == "" {
 		return errors.NewBadParameterError("name", t.Name)
 	}
+	if !t.System && IsReservedLinkTypeName(t.Name) {
+		return errors.NewBadParameterError("name", t.Name).Expected("a name not reserved for system link types")
+	}
 	if satoriuuid.Equal(t.SourceTypeID, satoriuuid.Nil) {
 		return errors.NewBadParameterError("source_type_name", t.SourceTypeID)
 	}
 	if satoriuuid.Equal(t.TargetTypeID, satoriuuid.Nil) {
 		return errors.NewBadParameterError("target_type_name", t.TargetTypeID)
 	}
+	// A tree link type between items of the same type can form type-level
+	// loops that can't be displayed as a tree, so this requires an opt-in.
+	if t.Topology == TopologyTree && satoriuuid.Equal(t.SourceTypeID, t.TargetTypeID) && !t.AllowSameType {
+		return errors.NewBadParameterError("target_type_id", t.TargetTypeID).Expected("a type other than the source type for the tree topology")
+	}
 	if t.ForwardName == "" {
 		return errors.NewBadParameterError("forward_name", t.ForwardName)
 	}
+	if err := checkNoNewline("forward_name", t.ForwardName); err != nil {
+		return errs.WithStack(err)
+	}
+	// Links of the network topology are undirected, so the reverse name may
+	// be omitted and defaults to the forward name.
+	if t.ReverseName == "" && t.Topology == TopologyNetwork {
+		t.ReverseName = t.ForwardName
+	}
 	if t.ReverseName == "" {
 		return errors.NewBadParameterError("reverse_name", t.ReverseName)
 	}
-	if err := CheckValidTopology(t.Topology); err != nil {
+	if err := checkNoNewline("reverse_name", t.ReverseName); err != nil {
+		return errs.WithStack(err)
+	}
+	if err := checkValidTopology(t.Topology, t.ID); err != nil {
 		return errs.WithStack(err)
 	}
 	if t.LinkCategoryID == satoriuuid.Nil {
@@ -142,39 +346,396 @@ func (t *WorkItemLinkType) CheckValidForCreation() error {
 	if t.SpaceID == satoriuuid.Nil {
 		return errors.NewBadParameterError("space_id", t.SpaceID)
 	}
+	if !t.System && satoriuuid.Equal(t.SpaceID, ReservedSpaceID) {
+		return errors.NewBadParameterError("space_id", t.SpaceID).Expected("a space other than the system space")
+	}
+	return t.checkValidMaxCounts()
+}
+
+// checkValidMaxCounts returns a BadParameterError if the maximum number of
+// sources or targets of the work item link type is set but less than 1.
+func (t *WorkItemLinkType) checkValidMaxCounts() error {
+	if t.MaxSourceCount != nil && *t.MaxSourceCount < 1 {
+		return errors.NewBadParameterError("max_source_count", *t.MaxSourceCount).Expected("at least 1")
+	}
+	if t.MaxTargetCount != nil && *t.MaxTargetCount < 1 {
+		return errors.NewBadParameterError("max_target_count", *t.MaxTargetCount).Expected("at least 1")
+	}
+	return nil
+}
+
+// RemapTypes returns a copy of the work item link type in which the source
+// type, target type and link category IDs are replaced by the IDs they map to
+// in the given mapping. This is used when cloning a link type from one space
+// into another space where the referenced types have different IDs. A
+// BadParameterError is returned if any of these IDs is missing from the
+// mapping.
+func (t WorkItemLinkType) RemapTypes(mapping map[satoriuuid.UUID]satoriuuid.UUID) (WorkItemLinkType, error) {
+	sourceTypeID, ok := mapping[t.SourceTypeID]
+	if !ok {
+		return t, errors.NewBadParameterError("source_type_id", t.SourceTypeID).Expected("mapped ID")
+	}
+	targetTypeID, ok := mapping[t.TargetTypeID]
+	if !ok {
+		return t, errors.NewBadParameterError("target_type_id", t.TargetTypeID).Expected("mapped ID")
+	}
+	linkCategoryID, ok := mapping[t.LinkCategoryID]
+	if !ok {
+		return t, errors.NewBadParameterError("link_category_id", t.LinkCategoryID).Expected("mapped ID")
+	}
+	t.SourceTypeID = sourceTypeID
+	t.TargetTypeID = targetTypeID
+	t.LinkCategoryID = linkCategoryID
+	return t, nil
+}
+
+// checkNoNewline returns a BadParameterError for the given parameter if the
+// value contains a newline or carriage-return character; otherwise nil is
+// returned. The forward and reverse names are used as UI labels and must fit
+// on a single line.
+func checkNoNewline(param, value string) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return errors.NewBadParameterError(param, value).Expected("no newline characters")
+	}
 	return nil
 }
 
+// symmetricPhrases contains wordings that describe an undirected relationship
+var symmetricPhrases = []string{
+	"related to",
+	"relates to",
+	"linked to",
+	"links to",
+	"associated with",
+	"connected to",
+	"similar to",
+}
+
+// WarnSymmetricDependencyNames returns non-fatal warnings if the given link
+// type uses the dependency topology but its forward and reverse names look
+// symmetric, i.e. they are identical or use a wording like "related to" that
+// doesn't express a direction. For other topologies nil is returned. The
+// warnings are meant to be shown to the user and don't prevent creation.
+func WarnSymmetricDependencyNames(t WorkItemLinkType) []string {
+	if t.Topology != TopologyDependency {
+		return nil
+	}
+	normalize := func(name string) string {
+		return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+	}
+	forwardName := normalize(t.ForwardName)
+	reverseName := normalize(t.ReverseName)
+	var warnings []string
+	if forwardName == reverseName {
+		warnings = append(warnings, fmt.Sprintf("forward name '%s' and reverse name '%s' are identical, but a dependency has a direction", t.ForwardName, t.ReverseName))
+	}
+	for _, phrase := range symmetricPhrases {
+		if strings.Contains(forwardName, phrase) {
+			warnings = append(warnings, fmt.Sprintf("forward name '%s' looks symmetric, but a dependency has a direction", t.ForwardName))
+		}
+		if strings.Contains(reverseName, phrase) {
+			warnings = append(warnings, fmt.Sprintf("reverse name '%s' looks symmetric, but a dependency has a direction", t.ReverseName))
+		}
+	}
+	return warnings
+}
+
+// NormalizeLinkDirection returns the given source and target work item IDs
+// of a link of this link type in a canonical order. Links of the network
+// topology are undirected, so their IDs are ordered with the lower ID first
+// and reversed reports whether they were swapped; this gives consistent keys
+// for deduplicating undirected links. For all other topologies the direction
+// is meaningful and the IDs are returned unchanged.
+func (t WorkItemLinkType) NormalizeLinkDirection(sourceID, targetID uint64) (normalizedSource, normalizedTarget uint64, reversed bool) {
+	if t.Topology == TopologyNetwork && sourceID > targetID {
+		return targetID, sourceID, true
+	}
+	return sourceID, targetID, false
+}
+
+// LabelFor returns the name under which a link of this link type between
+// the given source and target work items is shown from the perspective of
+// the given work item: the forward name for the source and the reverse name
+// for the target. Links of the network topology are undirected, so the
+// forward name is returned for both ends. A BadParameterError is returned if
+// the given work item is neither the source nor the target.
+func (t WorkItemLinkType) LabelFor(itemID, sourceID, targetID uint64) (string, error) {
+	if itemID != sourceID && itemID != targetID {
+		return "", errors.NewBadParameterError("item_id", itemID).Expected(fmt.Sprintf("source %d or target %d of the link", sourceID, targetID))
+	}
+	if itemID == sourceID || t.Topology == TopologyNetwork {
+		return t.ForwardName, nil
+	}
+	return t.ReverseName, nil
+}
+
+// Inverse returns a copy of the work item link type as seen from the target
+// side of its links: the source and target type IDs, the forward and reverse
+// names and the maximum source and target counts are swapped. Links of the
+// network topology are undirected, so for them the link type is returned
+// unchanged.
+func (t WorkItemLinkType) Inverse() WorkItemLinkType {
+	if t.Topology == TopologyNetwork {
+		return t
+	}
+	t.SourceTypeID, t.TargetTypeID = t.TargetTypeID, t.SourceTypeID
+	t.ForwardName, t.ReverseName = t.ReverseName, t.ForwardName
+	t.MaxSourceCount, t.MaxTargetCount = t.MaxTargetCount, t.MaxSourceCount
+	return t
+}
+
+// WarnAmbiguousDirectionalNames returns warnings for link types of a directed
+// topology whose forward name is a prefix of the reverse name or vice versa
+// (e.g. "blocks" and "blocks this"), ignoring case and surrounding
+// whitespace. Such names can't be told apart by prefix matching in the UI.
+// The warnings are meant to be shown before the link type is saved and don't
+// prevent its creation (see CheckValidForCreation).
+func WarnAmbiguousDirectionalNames(t WorkItemLinkType) []string {
+	if t.Topology == TopologyNetwork {
+		return nil
+	}
+	forwardName := strings.ToLower(strings.TrimSpace(t.ForwardName))
+	reverseName := strings.ToLower(strings.TrimSpace(t.ReverseName))
+	if forwardName == "" || reverseName == "" {
+		return nil
+	}
+	var warnings []string
+	if strings.HasPrefix(reverseName, forwardName) {
+		warnings = append(warnings, fmt.Sprintf("forward name %q is a prefix of reverse name %q", t.ForwardName, t.ReverseName))
+	} else if strings.HasPrefix(forwardName, reverseName) {
+		warnings = append(warnings, fmt.Sprintf("reverse name %q is a prefix of forward name %q", t.ReverseName, t.ForwardName))
+	}
+	return warnings
+}
+
+// WarnRedundantDescription returns true if the link type has a description
+// that equals its name, ignoring case and surrounding whitespace. Such a
+// description adds nothing in the UI. Like WarnAmbiguousDirectionalNames this
+// is only a warning for the admin preview and doesn't prevent the creation of
+// the link type.
+func WarnRedundantDescription(t WorkItemLinkType) bool {
+	if t.Description == nil {
+		return false
+	}
+	return strings.EqualFold(strings.TrimSpace(*t.Description), strings.TrimSpace(t.Name))
+}
+
+// DetectAmbiguousPairs returns the ID pairs of the given link types that
+// connect the same source and target types and where the forward name of one
+// link type equals the reverse name of the other or vice versa (e.g.
+// "blocks"/"blocked by" and "is blocked by"/"blocks"), ignoring case and
+// surrounding whitespace. Users can't tell in which direction such link types
+// point. The pairs are meant for an admin warning and are returned in the
+// order of the given link types.
+func DetectAmbiguousPairs(types []WorkItemLinkType) [][2]satoriuuid.UUID {
+	normalize := func(name string) string {
+		return strings.ToLower(strings.TrimSpace(name))
+	}
+	res := [][2]satoriuuid.UUID{}
+	for i, a := range types {
+		for _, b := range types[i+1:] {
+			if !satoriuuid.Equal(a.SourceTypeID, b.SourceTypeID) || !satoriuuid.Equal(a.TargetTypeID, b.TargetTypeID) {
+				continue
+			}
+			forwardMatches := normalize(a.ForwardName) != "" && normalize(a.ForwardName) == normalize(b.ReverseName)
+			reverseMatches := normalize(a.ReverseName) != "" && normalize(a.ReverseName) == normalize(b.ForwardName)
+			if forwardMatches || reverseMatches {
+				res = append(res, [2]satoriuuid.UUID{a.ID, b.ID})
+			}
+		}
+	}
+	return res
+}
+
+// SubtypeMatcher answers whether work item types can be used as the source
+// or target of a link type in constant time. It gives the same answers as
+// calling IsTypeOrSubtypeOf with the source or target type ID of the link
+// type, but walks the type paths only once on construction, which pays off
+// when many links are validated against the same link type.
+type SubtypeMatcher struct {
+	sources map[satoriuuid.UUID]bool
+	targets map[satoriuuid.UUID]bool
+}
+
+// NewSubtypeMatcher returns a SubtypeMatcher for the given link type that
+// knows about the given work item types. A type matches the source (target)
+// if the ltree node of the source (target) type of the link type is one of
+// the nodes of its path, i.e. one of its path prefixes ends with that node.
+func NewSubtypeMatcher(t WorkItemLinkType, types []workitem.WorkItemType) *SubtypeMatcher {
+	m := &SubtypeMatcher{
+		sources: map[satoriuuid.UUID]bool{},
+		targets: map[satoriuuid.UUID]bool{},
+	}
+	sourceNode := workitem.LtreeSafeID(t.SourceTypeID)
+	targetNode := workitem.LtreeSafeID(t.TargetTypeID)
+	for _, wit := range types {
+		for _, node := range strings.Split(wit.Path, workitem.GetTypePathSeparator()) {
+			if node == sourceNode {
+				m.sources[wit.ID] = true
+			}
+			if node == targetNode {
+				m.targets[wit.ID] = true
+			}
+		}
+	}
+	return m
+}
+
+// MatchesSource returns true if the work item type with the given ID is the
+// source type of the link type or a subtype of it. Types the matcher was not
+// built with never match.
+func (m *SubtypeMatcher) MatchesSource(witID satoriuuid.UUID) bool {
+	return m.sources[witID]
+}
+
+// MatchesTarget returns true if the work item type with the given ID is the
+// target type of the link type or a subtype of it. Types the matcher was not
+// built with never match.
+func (m *SubtypeMatcher) MatchesTarget(witID satoriuuid.UUID) bool {
+	return m.targets[witID]
+}
+
 // TableName implements gorm.tabler
 func (t WorkItemLinkType) TableName() string {
 	return "work_item_link_types"
 }
 
+// TopologyInfo describes a topology a work item link type can have
+type TopologyInfo struct {
+	Topology string `json:"topology"`
+	// Label is a human-readable name of the topology
+	Label string `json:"label"`
+	// Directed is true if the source and target of a link play different
+	// roles (e.g. parent and child)
+	Directed bool `json:"directed"`
+	// Acyclic is true if links of this topology must not form a cycle
+	Acyclic bool `json:"acyclic"`
+	// SingleParent is true if a work item can be the target of at most one
+	// link of this topology
+	SingleParent bool `json:"single_parent"`
+	// Description is a sentence explaining what the topology means for the
+	// links of a link type (e.g. for tooltips)
+	Description string `json:"description"`
+}
+
+// topologies contains all topologies a work item link type can have
+var topologies = []TopologyInfo{
+	{Topology: TopologyNetwork, Label: "Network", Directed: false, Acyclic: false,
+		Description: "Network: items are linked without a direction and any item can be linked to any number of other items."},
+	{Topology: TopologyDirectedNetwork, Label: "Directed network", Directed: true, Acyclic: false,
+		Description: "Directed network: each link points from a source to a target item and links may form cycles."},
+	{Topology: TopologyDependency, Label: "Dependency", Directed: true, Acyclic: true,
+		Description: "Dependency: each link points from a source to a target item and an item can never depend on itself, not even indirectly."},
+	{Topology: TopologyTree, Label: "Tree", Directed: true, Acyclic: true, SingleParent: true,
+		Description: "Tree: each item has at most one parent and an item can never be its own ancestor."},
+}
+
+// validTopologies contains the names of all topologies a work item link type
+// can have
+var validTopologies = topologyNames(topologies)
+
+// topologyNames returns the names of the given topologies
+func topologyNames(infos []TopologyInfo) []string {
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Topology
+	}
+	return names
+}
+
+// AllTopologies returns all topologies a work item link type can have
+// together with a human-readable label and their properties.
+func AllTopologies() []TopologyInfo {
+	res := make([]TopologyInfo, len(topologies))
+	copy(res, topologies)
+	return res
+}
+
+// TopologyDescription returns a sentence describing what the given topology
+// means for the links of a link type. A BadParameterError is returned for an
+// unknown topology.
+func TopologyDescription(topology string) (string, error) {
+	for _, info := range topologies {
+		if info.Topology == topology {
+			return info.Description, nil
+		}
+	}
+	return "", errors.NewBadParameterError("topology", topology).Expected(fmt.Sprintf("one of %s", strings.Join(validTopologies, ", ")))
+}
+
+// TopologyConstraints returns the rules links of the given topology have to
+// obey: whether source and target play different roles, whether the links
+// must not form a cycle and whether a work item can have at most one
+// parent. A BadParameterError is returned for an unknown topology.
+func TopologyConstraints(topology string) (directed bool, acyclic bool, singleParent bool, err error) {
+	for _, info := range topologies {
+		if info.Topology == topology {
+			return info.Directed, info.Acyclic, info.SingleParent, nil
+		}
+	}
+	return false, false, false, errors.NewBadParameterError("topology", topology).Expected(fmt.Sprintf("one of %s", strings.Join(validTopologies, ", ")))
+}
+
 // CheckValidTopology returns nil if the given topology is valid;
-// otherwise a BadParameterError is returned.
+// otherwise a TopologyError is returned.
 func CheckValidTopology(t string) error {
-	if t != TopologyNetwork && t != TopologyDirectedNetwork && t != TopologyDependency && t != TopologyTree {
-		return errors.NewBadParameterError("topolgy", t).Expected(TopologyNetwork + "|" + TopologyDirectedNetwork + "|" + TopologyDependency + "|" + TopologyTree)
+	return checkValidTopology(t, satoriuuid.Nil)
+}
+
+// CanonicalizeTopology lowercases and trims the given topology and replaces
+// inner whitespace with underscores, so that e.g. "Directed Network" becomes
+// TopologyDirectedNetwork. If the result is not a valid topology, a
+// BadParameterError is returned.
+func CanonicalizeTopology(raw string) (string, error) {
+	canonical := strings.Join(strings.Fields(strings.ToLower(raw)), "_")
+	for _, valid := range validTopologies {
+		if canonical == valid {
+			return canonical, nil
+		}
 	}
-	return nil
+	return "", errors.NewBadParameterError("topology", raw).Expected(fmt.Sprintf("one of %s", strings.Join(validTopologies, ", ")))
+}
+
+// checkValidTopology returns nil if the given topology is valid; otherwise a
+// TopologyError is returned that references the given link type ID (if any).
+func checkValidTopology(t string, linkTypeID satoriuuid.UUID) error {
+	for _, valid := range validTopologies {
+		if t == valid {
+			return nil
+		}
+	}
+	err := errors.NewTopologyError(t, validTopologies)
+	if linkTypeID != satoriuuid.Nil {
+		err = err.WithLinkTypeID(linkTypeID.String())
+	}
+	return err
 }
 
 // ConvertLinkTypeFromModel converts a work item link type from model to REST representation
 func ConvertLinkTypeFromModel(request *goa.RequestData, t WorkItemLinkType) app.WorkItemLinkTypeSingle {
 	spaceType := "spaces"
 	spaceSelfURL := rest.AbsoluteURL(request, app.SpaceHref(t.SpaceID.String()))
+	selfURL := rest.AbsoluteURL(request, app.WorkItemLinkTypeHref(t.ID.String()))
 
 	var converted = app.WorkItemLinkTypeSingle{
 		Data: &app.WorkItemLinkTypeData{
 			Type: EndpointWorkItemLinkTypes,
 			ID:   &t.ID,
+			Links: &app.GenericLinks{
+				Self: &selfURL,
+			},
 			Attributes: &app.WorkItemLinkTypeAttributes{
-				Name:        &t.Name,
-				Description: t.Description,
-				Version:     &t.Version,
-				ForwardName: &t.ForwardName,
-				ReverseName: &t.ReverseName,
-				Topology:    &t.Topology,
+				Name:           &t.Name,
+				Description:    t.Description,
+				Version:        &t.Version,
+				ForwardName:    &t.ForwardName,
+				ReverseName:    &t.ReverseName,
+				Topology:       &t.Topology,
+				DeprecatedAt:   t.DeprecatedAt,
+				MaxSourceCount: t.MaxSourceCount,
+				MaxTargetCount: t.MaxTargetCount,
+				DisplayOrder:   &t.DisplayOrder,
+				IsDefault:      &t.IsDefault,
 			},
 			Relationships: &app.WorkItemLinkTypeRelationships{
 				LinkCategory: &app.RelationWorkItemLinkCategory{
@@ -210,6 +771,22 @@ func ConvertLinkTypeFromModel(request *goa.RequestData, t WorkItemLinkType) app.
 	return converted
 }
 
+// ConvertLinkTypeFromModelIncluding converts a work item link type from model
+// to REST representation like ConvertLinkTypeFromModel and additionally
+// embeds the given source and target work item types in the "included" array
+// of the result. Nil types are not included.
+func ConvertLinkTypeFromModelIncluding(request *goa.RequestData, t WorkItemLinkType, sourceWIT, targetWIT *workitem.WorkItemType) app.WorkItemLinkTypeSingle {
+	converted := ConvertLinkTypeFromModel(request, t)
+	for _, wit := range []*workitem.WorkItemType{sourceWIT, targetWIT} {
+		if wit == nil {
+			continue
+		}
+		data := workitem.ConvertTypeFromModel(*wit)
+		converted.Included = append(converted.Included, &data)
+	}
+	return converted
+}
+
 // ConvertLinkTypeToModel converts the incoming app representation of a work item link type to the model layout.
 // Values are only overwrriten if they are set in "in", otherwise the values in "out" remain.
 func ConvertLinkTypeToModel(in app.WorkItemLinkTypeSingle, out *WorkItemLinkType) error {
@@ -247,27 +824,58 @@ func ConvertLinkTypeToModel(in app.WorkItemLinkTypeSingle, out *WorkItemLinkType
 			out.Version = *attrs.Version
 		}
 
-		// If the forwardName is not nil, it MUST NOT be empty
+		if attrs.Topology != nil {
+			topology, err := CanonicalizeTopology(*attrs.Topology)
+			if err != nil {
+				return errs.WithStack(err)
+			}
+			out.Topology = topology
+		}
+
+		// If the forwardName is not nil, it MUST NOT be empty and MUST NOT
+		// contain newlines. Surrounding whitespace is trimmed.
 		if attrs.ForwardName != nil {
-			if *attrs.ForwardName == "" {
+			forwardName := strings.TrimSpace(*attrs.ForwardName)
+			if forwardName == "" {
 				return errors.NewBadParameterError("data.attributes.forward_name", *attrs.ForwardName)
 			}
-			out.ForwardName = *attrs.ForwardName
+			if err := checkNoNewline("data.attributes.forward_name", forwardName); err != nil {
+				return errs.WithStack(err)
+			}
+			out.ForwardName = forwardName
 		}
 
-		// If the ReverseName is not nil, it MUST NOT be empty
+		// If the ReverseName is not nil, it MUST NOT contain newlines and
+		// MUST NOT be empty unless the topology is network, in which case it
+		// defaults to the forward name. Surrounding whitespace is trimmed.
 		if attrs.ReverseName != nil {
-			if *attrs.ReverseName == "" {
+			reverseName := strings.TrimSpace(*attrs.ReverseName)
+			if reverseName == "" && out.Topology == TopologyNetwork {
+				reverseName = out.ForwardName
+			}
+			if reverseName == "" {
 				return errors.NewBadParameterError("data.attributes.reverse_name", *attrs.ReverseName)
 			}
-			out.ReverseName = *attrs.ReverseName
-		}
-
-		if attrs.Topology != nil {
-			if err := CheckValidTopology(*attrs.Topology); err != nil {
+			if err := checkNoNewline("data.attributes.reverse_name", reverseName); err != nil {
 				return errs.WithStack(err)
 			}
-			out.Topology = *attrs.Topology
+			out.ReverseName = reverseName
+		}
+
+		if attrs.DeprecatedAt != nil {
+			out.DeprecatedAt = attrs.DeprecatedAt
+		}
+		if attrs.MaxSourceCount != nil {
+			out.MaxSourceCount = attrs.MaxSourceCount
+		}
+		if attrs.MaxTargetCount != nil {
+			out.MaxTargetCount = attrs.MaxTargetCount
+		}
+		if attrs.DisplayOrder != nil {
+			out.DisplayOrder = *attrs.DisplayOrder
+		}
+		if attrs.IsDefault != nil {
+			out.IsDefault = *attrs.IsDefault
 		}
 	}
 
@@ -286,3 +894,122 @@ func ConvertLinkTypeToModel(in app.WorkItemLinkTypeSingle, out *WorkItemLinkType
 
 	return nil
 }
+
+// ConvertLinkTypeToModelStrict is like ConvertLinkTypeToModel but instead of
+// returning on the first invalid attribute, it validates all attributes and
+// returns every error found. Only valid values are written to "out"; values
+// that are not set in "in" or are invalid leave "out" untouched.
+func ConvertLinkTypeToModelStrict(in app.WorkItemLinkTypeSingle, out *WorkItemLinkType) []error {
+	if in.Data == nil {
+		return []error{errors.NewBadParameterError("data", nil).Expected("not <nil>")}
+	}
+	if in.Data.Attributes == nil {
+		return []error{errors.NewBadParameterError("data.attributes", nil).Expected("not <nil>")}
+	}
+	if in.Data.Relationships == nil {
+		return []error{errors.NewBadParameterError("data.relationships", nil).Expected("not <nil>")}
+	}
+
+	var problems []error
+	attrs := in.Data.Attributes
+	rel := in.Data.Relationships
+
+	if in.Data.ID != nil {
+		out.ID = *in.Data.ID
+	}
+
+	if attrs.Name != nil {
+		if *attrs.Name == "" {
+			problems = append(problems, errors.NewBadParameterError("data.attributes.name", *attrs.Name))
+		} else {
+			out.Name = *attrs.Name
+		}
+	}
+
+	if attrs.Description != nil {
+		if !utf8.ValidString(*attrs.Description) {
+			problems = append(problems, errors.NewBadParameterError("data.attributes.description", *attrs.Description).Expected("valid UTF-8"))
+		} else {
+			out.Description = attrs.Description
+		}
+	}
+
+	if attrs.Version != nil {
+		out.Version = *attrs.Version
+	}
+
+	if attrs.ForwardName != nil {
+		forwardName := strings.TrimSpace(*attrs.ForwardName)
+		if forwardName == "" {
+			problems = append(problems, errors.NewBadParameterError("data.attributes.forward_name", *attrs.ForwardName))
+		} else if err := checkNoNewline("data.attributes.forward_name", forwardName); err != nil {
+			problems = append(problems, err)
+		} else {
+			out.ForwardName = forwardName
+		}
+	}
+
+	// The topology is canonicalized like in ConvertLinkTypeToModel. It is
+	// needed before the reverse name, which defaults to the forward name for
+	// the network topology.
+	topology := out.Topology
+	var topologyErr error
+	if attrs.Topology != nil {
+		if topology, topologyErr = CanonicalizeTopology(*attrs.Topology); topologyErr != nil {
+			topologyErr = CheckValidTopology(*attrs.Topology)
+		}
+	}
+
+	if attrs.ReverseName != nil {
+		reverseName := strings.TrimSpace(*attrs.ReverseName)
+		if reverseName == "" && topology == TopologyNetwork {
+			reverseName = out.ForwardName
+		}
+		if reverseName == "" {
+			problems = append(problems, errors.NewBadParameterError("data.attributes.reverse_name", *attrs.ReverseName))
+		} else if err := checkNoNewline("data.attributes.reverse_name", reverseName); err != nil {
+			problems = append(problems, err)
+		} else {
+			out.ReverseName = reverseName
+		}
+	}
+
+	if attrs.Topology != nil {
+		if topologyErr != nil {
+			problems = append(problems, topologyErr)
+		} else {
+			out.Topology = topology
+		}
+	}
+
+	if attrs.DeprecatedAt != nil {
+		out.DeprecatedAt = attrs.DeprecatedAt
+	}
+	if attrs.MaxSourceCount != nil {
+		out.MaxSourceCount = attrs.MaxSourceCount
+	}
+	if attrs.MaxTargetCount != nil {
+		out.MaxTargetCount = attrs.MaxTargetCount
+	}
+	if attrs.DisplayOrder != nil {
+		out.DisplayOrder = *attrs.DisplayOrder
+	}
+	if attrs.IsDefault != nil {
+		out.IsDefault = *attrs.IsDefault
+	}
+
+	if rel.LinkCategory != nil && rel.LinkCategory.Data != nil {
+		out.LinkCategoryID = rel.LinkCategory.Data.ID
+	}
+	if rel.SourceType != nil && rel.SourceType.Data != nil {
+		out.SourceTypeID = rel.SourceType.Data.ID
+	}
+	if rel.TargetType != nil && rel.TargetType.Data != nil {
+		out.TargetTypeID = rel.TargetType.Data.ID
+	}
+	if rel.Space != nil && rel.Space.Data != nil {
+		out.SpaceID = *rel.Space.Data.ID
+	}
+
+	return problems
+}
@@ -1,15 +1,23 @@
 package link_test
 
 import (
+	"net/http"
 	"testing"
 
 	"time"
 
+	"golang.org/x/net/context"
+
+	"github.com/almighty/almighty-core/app"
 	"github.com/almighty/almighty-core/convert"
+	"github.com/almighty/almighty-core/errors"
 	"github.com/almighty/almighty-core/gormsupport"
 	"github.com/almighty/almighty-core/resource"
+	"github.com/almighty/almighty-core/space"
 	"github.com/almighty/almighty-core/workitem"
 	"github.com/almighty/almighty-core/workitem/link"
+	"github.com/goadesign/goa"
+	errs "github.com/pkg/errors"
 	satoriuuid "github.com/satori/go.uuid"
 	"github.com/stretchr/testify/require"
 )
@@ -102,6 +110,71 @@ func TestWorkItemLinkType_Equal(t *testing.T) {
 	b = a
 	b.SpaceID = satoriuuid.FromStringOrNil("aaa71e36-871b-43a6-9166-0v5ce684dBBB")
 	require.False(t, a.Equal(b))
+
+	// Test DeprecatedAt
+	deprecatedAt := time.Now()
+	b = a
+	b.DeprecatedAt = &deprecatedAt
+	require.False(t, a.Equal(b))
+	a.DeprecatedAt = &deprecatedAt
+	require.True(t, a.Equal(b))
+
+	// Test MaxSourceCount and MaxTargetCount
+	one, two := 1, 2
+	b = a
+	b.MaxSourceCount = &one
+	require.False(t, a.Equal(b))
+	b = a
+	b.MaxTargetCount = &one
+	require.False(t, a.Equal(b))
+	a.MaxTargetCount = &two
+	require.False(t, a.Equal(b))
+}
+
+func TestWorkItemLinkTypeDiff(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	description := "An example description"
+	a := link.WorkItemLinkType{
+		ID:             satoriuuid.FromStringOrNil("0e671e36-871b-43a6-9166-0c4bd573e231"),
+		Name:           "Example work item link type",
+		Description:    &description,
+		Topology:       link.TopologyNetwork,
+		SourceTypeID:   workitem.SystemBug,
+		TargetTypeID:   workitem.SystemUserStory,
+		ForwardName:    "blocks",
+		ReverseName:    "blocked by",
+		LinkCategoryID: satoriuuid.FromStringOrNil("0e671e36-871b-43a6-9166-0c4bd573eAAA"),
+		SpaceID:        satoriuuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+	}
+
+	// Test no semantic change, ignoring version and lifecycle
+	b := a
+	b.Version = 3
+	b.Lifecycle = gormsupport.Lifecycle{UpdatedAt: time.Now()}
+	require.Empty(t, a.Diff(b))
+
+	// Test name-only change
+	b = a
+	b.Name = "Renamed work item link type"
+	require.Equal(t, map[string][2]interface{}{
+		"name": {"Example work item link type", "Renamed work item link type"},
+	}, a.Diff(b))
+
+	// Test multi-field change
+	b = a
+	b.Description = nil
+	b.Topology = link.TopologyDependency
+	b.TargetTypeID = workitem.SystemFeature
+	maxTargets := 1
+	b.MaxTargetCount = &maxTargets
+	require.Equal(t, map[string][2]interface{}{
+		"description":      {description, nil},
+		"topology":         {link.TopologyNetwork, link.TopologyDependency},
+		"target_type_id":   {workitem.SystemUserStory, workitem.SystemFeature},
+		"max_target_count": {nil, 1},
+	}, a.Diff(b))
 }
 
 func TestWorkItemLinkTypeCheckValidForCreation(t *testing.T) {
@@ -147,10 +220,19 @@ func TestWorkItemLinkTypeCheckValidForCreation(t *testing.T) {
 	b.ForwardName = ""
 	require.NotNil(t, b.CheckValidForCreation())
 
-	// Check empty ReverseName
+	// Check empty ReverseName defaults to the ForwardName for the network topology
 	b = a
 	b.ReverseName = ""
-	require.NotNil(t, b.CheckValidForCreation())
+	require.Nil(t, b.CheckValidForCreation())
+	require.Equal(t, "blocks", b.ReverseName)
+
+	// Check empty ReverseName is rejected for directed topologies
+	for _, topology := range []string{link.TopologyDirectedNetwork, link.TopologyDependency, link.TopologyTree} {
+		b = a
+		b.Topology = topology
+		b.ReverseName = ""
+		require.NotNil(t, b.CheckValidForCreation())
+	}
 
 	// Check empty Topology
 	b = a
@@ -166,4 +248,898 @@ func TestWorkItemLinkTypeCheckValidForCreation(t *testing.T) {
 	b = a
 	b.SpaceID = satoriuuid.Nil
 	require.NotNil(t, b.CheckValidForCreation())
+
+	// Check tree topology with identical source and target type
+	b = a
+	b.Topology = link.TopologyTree
+	b.TargetTypeID = b.SourceTypeID
+	err := b.CheckValidForCreation()
+	require.IsType(t, errors.BadParameterError{}, err)
+	require.Contains(t, err.Error(), "target_type_id")
+
+	// Check tree topology with identical source and target type and opt-in
+	b.AllowSameType = true
+	require.Nil(t, b.CheckValidForCreation())
+
+	// Check other topologies with identical source and target type
+	b = a
+	b.TargetTypeID = b.SourceTypeID
+	require.Nil(t, b.CheckValidForCreation())
+
+	// Check multiplicity limits below one
+	zero := 0
+	b = a
+	b.MaxSourceCount = &zero
+	require.IsType(t, errors.BadParameterError{}, b.CheckValidForCreation())
+	b = a
+	b.MaxTargetCount = &zero
+	require.IsType(t, errors.BadParameterError{}, b.CheckValidForCreation())
+
+	// Check reserved name for a user-created link type
+	b = a
+	b.Name = " bug BLOCKER "
+	err = b.CheckValidForCreation()
+	require.IsType(t, errors.BadParameterError{}, err)
+	require.Contains(t, err.Error(), "name")
+
+	// Check reserved name for a system link type
+	b.Name = link.SystemWorkItemLinkTypeBugBlocker
+	b.System = true
+	require.Nil(t, b.CheckValidForCreation())
+
+	// Check user-created link type in the system space
+	b = a
+	b.SpaceID = link.ReservedSpaceID
+	err = b.CheckValidForCreation()
+	require.IsType(t, errors.BadParameterError{}, err)
+	require.Contains(t, err.Error(), "space_id")
+
+	// Check system link type in the system space
+	b.System = true
+	require.Nil(t, b.CheckValidForCreation())
+}
+
+func TestWorkItemLinkTypeRemapTypes(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	newSourceTypeID := satoriuuid.FromStringOrNil("1a2b9e36-871b-43a6-9166-0c4bd573e001")
+	newTargetTypeID := satoriuuid.FromStringOrNil("1a2b9e36-871b-43a6-9166-0c4bd573e002")
+	newLinkCategoryID := satoriuuid.FromStringOrNil("1a2b9e36-871b-43a6-9166-0c4bd573e003")
+	a := link.WorkItemLinkType{
+		ID:             satoriuuid.FromStringOrNil("0e671e36-871b-43a6-9166-0c4bd573e231"),
+		Name:           "Example work item link type",
+		Topology:       link.TopologyNetwork,
+		SourceTypeID:   workitem.SystemBug,
+		TargetTypeID:   workitem.SystemUserStory,
+		ForwardName:    "blocks",
+		ReverseName:    "blocked by",
+		LinkCategoryID: satoriuuid.FromStringOrNil("0e671e36-871b-43a6-9166-0c4bd573eAAA"),
+		SpaceID:        satoriuuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+	}
+
+	// Check complete mapping
+	mapping := map[satoriuuid.UUID]satoriuuid.UUID{
+		a.SourceTypeID:   newSourceTypeID,
+		a.TargetTypeID:   newTargetTypeID,
+		a.LinkCategoryID: newLinkCategoryID,
+	}
+	b, err := a.RemapTypes(mapping)
+	require.Nil(t, err)
+	require.Equal(t, newSourceTypeID, b.SourceTypeID)
+	require.Equal(t, newTargetTypeID, b.TargetTypeID)
+	require.Equal(t, newLinkCategoryID, b.LinkCategoryID)
+	require.Equal(t, a.ID, b.ID)
+	require.Equal(t, a.SpaceID, b.SpaceID)
+	// the original must not be touched
+	require.Equal(t, workitem.SystemBug, a.SourceTypeID)
+
+	// Check mapping with missing target type
+	delete(mapping, a.TargetTypeID)
+	_, err = a.RemapTypes(mapping)
+	require.NotNil(t, err)
+	require.IsType(t, errors.BadParameterError{}, err)
+}
+
+func TestAllTopologies(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	infos := link.AllTopologies()
+
+	// Check that every listed topology is accepted and has a label
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		require.Nil(t, link.CheckValidTopology(info.Topology))
+		require.NotEmpty(t, info.Label)
+		names[i] = info.Topology
+	}
+
+	// Check that the list covers exactly the accepted topologies
+	topologyErr, ok := link.CheckValidTopology("foo").(errors.TopologyError)
+	require.True(t, ok)
+	require.Equal(t, topologyErr.ValidTopologies, names)
+	require.Equal(t, []string{link.TopologyNetwork, link.TopologyDirectedNetwork, link.TopologyDependency, link.TopologyTree}, names)
+
+	// Check the properties of the topologies
+	require.False(t, infos[0].Directed)
+	require.False(t, infos[0].Acyclic)
+	require.True(t, infos[1].Directed)
+	require.False(t, infos[1].Acyclic)
+	require.True(t, infos[2].Directed)
+	require.True(t, infos[2].Acyclic)
+	require.True(t, infos[3].Directed)
+	require.True(t, infos[3].Acyclic)
+
+	// Check that modifying the result doesn't affect the topologies
+	infos[0].Label = "changed"
+	require.NotEqual(t, "changed", link.AllTopologies()[0].Label)
+}
+
+func TestTopologyConstraints(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	checkConstraints := func(topology string, expectedDirected, expectedAcyclic, expectedSingleParent bool) {
+		directed, acyclic, singleParent, err := link.TopologyConstraints(topology)
+		require.Nil(t, err)
+		require.Equal(t, expectedDirected, directed, topology)
+		require.Equal(t, expectedAcyclic, acyclic, topology)
+		require.Equal(t, expectedSingleParent, singleParent, topology)
+	}
+
+	// Check the constraints of each topology
+	checkConstraints(link.TopologyNetwork, false, false, false)
+	checkConstraints(link.TopologyDirectedNetwork, true, false, false)
+	checkConstraints(link.TopologyDependency, true, true, false)
+	checkConstraints(link.TopologyTree, true, true, true)
+
+	// Check unknown topology
+	_, _, _, err := link.TopologyConstraints("foo")
+	require.IsType(t, errors.BadParameterError{}, err)
+}
+
+func TestTopologyDescription(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	// Check that every valid topology has a description
+	for _, info := range link.AllTopologies() {
+		description, err := link.TopologyDescription(info.Topology)
+		require.Nil(t, err)
+		require.NotEmpty(t, description)
+		require.Equal(t, info.Description, description)
+	}
+
+	// Check unknown topology
+	_, err := link.TopologyDescription("foo")
+	require.IsType(t, errors.BadParameterError{}, err)
+}
+
+func TestCheckValidTopology(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	// Check valid topologies
+	require.Nil(t, link.CheckValidTopology(link.TopologyNetwork))
+	require.Nil(t, link.CheckValidTopology(link.TopologyDirectedNetwork))
+	require.Nil(t, link.CheckValidTopology(link.TopologyDependency))
+	require.Nil(t, link.CheckValidTopology(link.TopologyTree))
+
+	// Check invalid topology
+	err := link.CheckValidTopology("foo")
+	require.NotNil(t, err)
+	topologyErr, ok := err.(errors.TopologyError)
+	require.True(t, ok)
+	require.Equal(t, "foo", topologyErr.Topology)
+	require.Equal(t, []string{link.TopologyNetwork, link.TopologyDirectedNetwork, link.TopologyDependency, link.TopologyTree}, topologyErr.ValidTopologies)
+	require.Equal(t, "", topologyErr.LinkTypeID)
+
+	// Check invalid topology of a link type carries the link type ID
+	lt := link.WorkItemLinkType{
+		ID:             satoriuuid.FromStringOrNil("0e671e36-871b-43a6-9166-0c4bd573e231"),
+		Name:           "Example work item link type",
+		Topology:       "foo",
+		SourceTypeID:   workitem.SystemBug,
+		TargetTypeID:   workitem.SystemUserStory,
+		ForwardName:    "blocks",
+		ReverseName:    "blocked by",
+		LinkCategoryID: satoriuuid.FromStringOrNil("0e671e36-871b-43a6-9166-0c4bd573eAAA"),
+		SpaceID:        satoriuuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+	}
+	err = lt.CheckValidForCreation()
+	require.NotNil(t, err)
+	topologyErr, ok = errs.Cause(err).(errors.TopologyError)
+	require.True(t, ok)
+	require.Equal(t, lt.ID.String(), topologyErr.LinkTypeID)
+}
+
+func TestWorkItemLinkTypeDirectionalNamesWithNewlines(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	a := link.WorkItemLinkType{
+		ID:             satoriuuid.FromStringOrNil("0e671e36-871b-43a6-9166-0c4bd573e231"),
+		Name:           "Example work item link type",
+		Topology:       link.TopologyNetwork,
+		SourceTypeID:   workitem.SystemBug,
+		TargetTypeID:   workitem.SystemUserStory,
+		ForwardName:    "blocks",
+		ReverseName:    "blocked by",
+		LinkCategoryID: satoriuuid.FromStringOrNil("0e671e36-871b-43a6-9166-0c4bd573eAAA"),
+		SpaceID:        satoriuuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+	}
+
+	// Check clean names
+	b := a
+	require.Nil(t, b.CheckValidForCreation())
+
+	// Check embedded newline in ForwardName
+	b = a
+	b.ForwardName = "blo\ncks"
+	err := b.CheckValidForCreation()
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "forward_name")
+
+	// Check embedded carriage return in ReverseName
+	b = a
+	b.ReverseName = "blocked\rby"
+	err = b.CheckValidForCreation()
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "reverse_name")
+
+	// Check surrounding whitespace is trimmed on conversion
+	forwardName := "  blocks  "
+	reverseName := "\tblocked by "
+	in := app.WorkItemLinkTypeSingle{
+		Data: &app.WorkItemLinkTypeData{
+			Attributes: &app.WorkItemLinkTypeAttributes{
+				ForwardName: &forwardName,
+				ReverseName: &reverseName,
+			},
+			Relationships: &app.WorkItemLinkTypeRelationships{},
+		},
+	}
+	out := link.WorkItemLinkType{}
+	require.Nil(t, link.ConvertLinkTypeToModel(in, &out))
+	require.Equal(t, "blocks", out.ForwardName)
+	require.Equal(t, "blocked by", out.ReverseName)
+
+	// Check embedded newline is rejected on conversion
+	forwardName = "blo\ncks"
+	err = link.ConvertLinkTypeToModel(in, &out)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "data.attributes.forward_name")
+}
+
+func TestConvertLinkTypeToModelDefaultReverseName(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	forwardName := "relates to"
+	reverseName := ""
+	topology := link.TopologyNetwork
+	in := app.WorkItemLinkTypeSingle{
+		Data: &app.WorkItemLinkTypeData{
+			Attributes: &app.WorkItemLinkTypeAttributes{
+				ForwardName: &forwardName,
+				ReverseName: &reverseName,
+				Topology:    &topology,
+			},
+			Relationships: &app.WorkItemLinkTypeRelationships{},
+		},
+	}
+
+	// Check network type with only a forward name
+	out := link.WorkItemLinkType{}
+	require.Nil(t, link.ConvertLinkTypeToModel(in, &out))
+	require.Equal(t, "relates to", out.ReverseName)
+	out = link.WorkItemLinkType{}
+	require.Empty(t, link.ConvertLinkTypeToModelStrict(in, &out))
+	require.Equal(t, "relates to", out.ReverseName)
+
+	// Check directed type still requires both names
+	topology = link.TopologyDependency
+	out = link.WorkItemLinkType{}
+	err := link.ConvertLinkTypeToModel(in, &out)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "data.attributes.reverse_name")
+	out = link.WorkItemLinkType{}
+	problems := link.ConvertLinkTypeToModelStrict(in, &out)
+	require.Len(t, problems, 1)
+	require.Contains(t, problems[0].Error(), "data.attributes.reverse_name")
+}
+
+// fakeWorkItemTypeLoader knows the work item types with the given IDs
+type fakeWorkItemTypeLoader map[satoriuuid.UUID]bool
+
+func (l fakeWorkItemTypeLoader) LoadTypeFromDB(ctx context.Context, id satoriuuid.UUID) (*workitem.WorkItemType, error) {
+	if !l[id] {
+		return nil, errors.NewNotFoundError("work item type", id.String())
+	}
+	return &workitem.WorkItemType{ID: id, SpaceID: link.ReservedSpaceID}, nil
+}
+
+// fakeLinkCategoryRepository knows the link categories with the given IDs
+type fakeLinkCategoryRepository struct {
+	link.WorkItemLinkCategoryRepository
+	ids map[satoriuuid.UUID]bool
+}
+
+func (r fakeLinkCategoryRepository) Load(ctx context.Context, id satoriuuid.UUID) (*app.WorkItemLinkCategorySingle, error) {
+	if !r.ids[id] {
+		return nil, errors.NewNotFoundError("work item link category", id.String())
+	}
+	return &app.WorkItemLinkCategorySingle{Data: &app.WorkItemLinkCategoryData{ID: &id}}, nil
+}
+
+// fakeSpaceRepository knows the spaces with the given IDs
+type fakeSpaceRepository struct {
+	space.Repository
+	ids map[satoriuuid.UUID]bool
+}
+
+func (r fakeSpaceRepository) Load(ctx context.Context, id satoriuuid.UUID) (*space.Space, error) {
+	if !r.ids[id] {
+		return nil, errors.NewNotFoundError("space", id.String())
+	}
+	return &space.Space{ID: id}, nil
+}
+
+func TestValidateLinkTypeDraft(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	a := link.WorkItemLinkType{
+		Name:           "Example work item link type",
+		Topology:       link.TopologyNetwork,
+		SourceTypeID:   workitem.SystemBug,
+		TargetTypeID:   workitem.SystemUserStory,
+		ForwardName:    "blocks",
+		ReverseName:    "blocked by",
+		LinkCategoryID: satoriuuid.FromStringOrNil("0e671e36-871b-43a6-9166-0c4bd573eAAA"),
+		SpaceID:        satoriuuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+	}
+	witRepo := fakeWorkItemTypeLoader{workitem.SystemBug: true, workitem.SystemUserStory: true}
+	categoryRepo := fakeLinkCategoryRepository{ids: map[satoriuuid.UUID]bool{a.LinkCategoryID: true}}
+	spaceRepo := fakeSpaceRepository{ids: map[satoriuuid.UUID]bool{a.SpaceID: true}}
+
+	// Check fully valid draft
+	require.Empty(t, link.ValidateLinkTypeDraft(context.Background(), a, witRepo, categoryRepo, spaceRepo))
+
+	// Check draft with an invalid attribute and several missing references
+	b := a
+	b.Name = ""
+	b.TargetTypeID = satoriuuid.FromStringOrNil("aaa71e36-871b-43a6-9166-0c4bd573eCCC")
+	b.SpaceID = satoriuuid.FromStringOrNil("bbb71e36-871b-43a6-9166-0c4bd573eCCC")
+	problems := link.ValidateLinkTypeDraft(context.Background(), b, witRepo, categoryRepo, spaceRepo)
+	require.Len(t, problems, 3)
+	require.Contains(t, problems[0].Error(), "name")
+	require.Contains(t, problems[1].Error(), "target_type_id")
+	require.Contains(t, problems[2].Error(), "space_id")
+
+	// Check invalid topology
+	b = a
+	b.Topology = "foo"
+	problems = link.ValidateLinkTypeDraft(context.Background(), b, witRepo, categoryRepo, spaceRepo)
+	require.Len(t, problems, 1)
+	require.IsType(t, errors.TopologyError{}, problems[0])
+
+	// Check the rules of CheckValidForCreation, e.g. a reserved name, a
+	// multiplicity limit below one and the system space
+	b = a
+	b.Name = link.SystemWorkItemLinkTypeBugBlocker
+	problems = link.ValidateLinkTypeDraft(context.Background(), b, witRepo, categoryRepo, spaceRepo)
+	require.Len(t, problems, 1)
+	require.Contains(t, problems[0].Error(), "reserved")
+	zero := 0
+	b = a
+	b.MaxSourceCount = &zero
+	problems = link.ValidateLinkTypeDraft(context.Background(), b, witRepo, categoryRepo, spaceRepo)
+	require.Len(t, problems, 1)
+	require.Contains(t, problems[0].Error(), "max_source_count")
+	b = a
+	b.SpaceID = link.ReservedSpaceID
+	spaceRepo.ids[b.SpaceID] = true
+	problems = link.ValidateLinkTypeDraft(context.Background(), b, witRepo, categoryRepo, spaceRepo)
+	require.Len(t, problems, 1)
+	require.Contains(t, problems[0].Error(), "system space")
+
+	// Check the draft was not modified
+	b = a
+	b.ReverseName = ""
+	require.Empty(t, link.ValidateLinkTypeDraft(context.Background(), b, witRepo, categoryRepo, spaceRepo))
+	require.Equal(t, "", b.ReverseName)
+}
+
+func TestConvertLinkTypeFromModelSelfLink(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	a := link.WorkItemLinkType{
+		ID:             satoriuuid.FromStringOrNil("0e671e36-871b-43a6-9166-0c4bd573e231"),
+		Name:           "Example work item link type",
+		Topology:       link.TopologyNetwork,
+		SourceTypeID:   workitem.SystemBug,
+		TargetTypeID:   workitem.SystemUserStory,
+		ForwardName:    "blocks",
+		ReverseName:    "blocked by",
+		LinkCategoryID: satoriuuid.FromStringOrNil("0e671e36-871b-43a6-9166-0c4bd573eAAA"),
+		SpaceID:        satoriuuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+	}
+	req := &goa.RequestData{
+		Request: &http.Request{Host: "api.service.domain.org"},
+	}
+	converted := link.ConvertLinkTypeFromModel(req, a)
+	require.NotNil(t, converted.Data.Links)
+	require.NotNil(t, converted.Data.Links.Self)
+	require.Equal(t, "http://api.service.domain.org/api/workitemlinktypes/0e671e36-871b-43a6-9166-0c4bd573e231", *converted.Data.Links.Self)
+}
+
+func TestConvertLinkTypeFromModelIncluding(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	a := link.WorkItemLinkType{
+		ID:             satoriuuid.FromStringOrNil("0e671e36-871b-43a6-9166-0c4bd573e231"),
+		Name:           "Example work item link type",
+		Topology:       link.TopologyNetwork,
+		SourceTypeID:   workitem.SystemBug,
+		TargetTypeID:   workitem.SystemUserStory,
+		ForwardName:    "blocks",
+		ReverseName:    "blocked by",
+		LinkCategoryID: satoriuuid.FromStringOrNil("0e671e36-871b-43a6-9166-0c4bd573eAAA"),
+		SpaceID:        satoriuuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+	}
+	sourceWIT := workitem.WorkItemType{
+		ID:     workitem.SystemBug,
+		Name:   "Bug",
+		Icon:   "fa-bug",
+		Fields: workitem.FieldDefinitions{},
+	}
+	targetWIT := workitem.WorkItemType{
+		ID:     workitem.SystemUserStory,
+		Name:   "User Story",
+		Icon:   "fa-map-marker",
+		Fields: workitem.FieldDefinitions{},
+	}
+	req := &goa.RequestData{
+		Request: &http.Request{Host: "api.service.domain.org"},
+	}
+
+	// Check without types the result equals the plain conversion
+	require.Equal(t, link.ConvertLinkTypeFromModel(req, a), link.ConvertLinkTypeFromModelIncluding(req, a, nil, nil))
+
+	// Check source and target types are included
+	converted := link.ConvertLinkTypeFromModelIncluding(req, a, &sourceWIT, &targetWIT)
+	require.Len(t, converted.Included, 2)
+	for i, wit := range []workitem.WorkItemType{sourceWIT, targetWIT} {
+		included, ok := converted.Included[i].(*app.WorkItemTypeData)
+		require.True(t, ok)
+		require.Equal(t, wit.ID, *included.ID)
+		require.Equal(t, wit.Name, included.Attributes.Name)
+		require.Equal(t, wit.Icon, included.Attributes.Icon)
+	}
+
+	// Check only the given type is included
+	converted = link.ConvertLinkTypeFromModelIncluding(req, a, nil, &targetWIT)
+	require.Len(t, converted.Included, 1)
+	require.Equal(t, targetWIT.ID, *converted.Included[0].(*app.WorkItemTypeData).ID)
+}
+
+func TestConvertLinkTypeDeprecatedAtRoundTrip(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	deprecatedAt := time.Date(2017, time.March, 1, 12, 0, 0, 0, time.UTC)
+	a := link.WorkItemLinkType{
+		ID:             satoriuuid.FromStringOrNil("0e671e36-871b-43a6-9166-0c4bd573e231"),
+		Name:           "Example work item link type",
+		Topology:       link.TopologyNetwork,
+		SourceTypeID:   workitem.SystemBug,
+		TargetTypeID:   workitem.SystemUserStory,
+		ForwardName:    "blocks",
+		ReverseName:    "blocked by",
+		LinkCategoryID: satoriuuid.FromStringOrNil("0e671e36-871b-43a6-9166-0c4bd573eAAA"),
+		SpaceID:        satoriuuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+		DeprecatedAt:   &deprecatedAt,
+	}
+	req := &goa.RequestData{
+		Request: &http.Request{Host: "api.service.domain.org"},
+	}
+
+	// Check deprecated link type
+	converted := link.ConvertLinkTypeFromModel(req, a)
+	require.NotNil(t, converted.Data.Attributes.DeprecatedAt)
+	b := link.WorkItemLinkType{}
+	require.Nil(t, link.ConvertLinkTypeToModel(converted, &b))
+	require.True(t, a.Equal(b))
+
+	// Check active link type
+	a.DeprecatedAt = nil
+	converted = link.ConvertLinkTypeFromModel(req, a)
+	require.Nil(t, converted.Data.Attributes.DeprecatedAt)
+	b = link.WorkItemLinkType{}
+	require.Nil(t, link.ConvertLinkTypeToModel(converted, &b))
+	require.Nil(t, b.DeprecatedAt)
+	require.True(t, a.Equal(b))
+}
+
+func TestConvertLinkTypeToModelStrict(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	name := ""
+	description := "valid description"
+	forwardName := "  "
+	reverseName := "blocked\nby"
+	topology := "foo"
+	in := app.WorkItemLinkTypeSingle{
+		Data: &app.WorkItemLinkTypeData{
+			Attributes: &app.WorkItemLinkTypeAttributes{
+				Name:        &name,
+				Description: &description,
+				ForwardName: &forwardName,
+				ReverseName: &reverseName,
+				Topology:    &topology,
+			},
+			Relationships: &app.WorkItemLinkTypeRelationships{},
+		},
+	}
+
+	// Check all invalid attributes are reported and only valid values are written
+	out := link.WorkItemLinkType{Name: "old name", Topology: link.TopologyNetwork}
+	problems := link.ConvertLinkTypeToModelStrict(in, &out)
+	require.Len(t, problems, 4)
+	require.Contains(t, problems[0].Error(), "data.attributes.name")
+	require.Contains(t, problems[1].Error(), "data.attributes.forward_name")
+	require.Contains(t, problems[2].Error(), "data.attributes.reverse_name")
+	require.IsType(t, errors.TopologyError{}, problems[3])
+	require.Equal(t, "old name", out.Name)
+	require.Equal(t, link.TopologyNetwork, out.Topology)
+	require.Equal(t, &description, out.Description)
+
+	// Check invalid description
+	description = "\xff"
+	name = "foo"
+	problems = link.ConvertLinkTypeToModelStrict(in, &out)
+	require.Len(t, problems, 4)
+	require.Contains(t, problems[0].Error(), "data.attributes.description")
+	require.Equal(t, "foo", out.Name)
+
+	// Check valid attributes
+	description = "valid description"
+	forwardName = " blocks "
+	reverseName = "blocked by"
+	topology = link.TopologyTree
+	problems = link.ConvertLinkTypeToModelStrict(in, &out)
+	require.Empty(t, problems)
+	require.Equal(t, "blocks", out.ForwardName)
+	require.Equal(t, "blocked by", out.ReverseName)
+	require.Equal(t, link.TopologyTree, out.Topology)
+
+	// Check the topology is canonicalized before the reverse name defaults
+	// to the forward name
+	topology = " Network "
+	reverseName = ""
+	problems = link.ConvertLinkTypeToModelStrict(in, &out)
+	require.Empty(t, problems)
+	require.Equal(t, link.TopologyNetwork, out.Topology)
+	require.Equal(t, "blocks", out.ReverseName)
+	topology = "Directed Network"
+	reverseName = "blocked by"
+	problems = link.ConvertLinkTypeToModelStrict(in, &out)
+	require.Empty(t, problems)
+	require.Equal(t, link.TopologyDirectedNetwork, out.Topology)
+
+	// Check missing attributes
+	problems = link.ConvertLinkTypeToModelStrict(app.WorkItemLinkTypeSingle{Data: &app.WorkItemLinkTypeData{}}, &out)
+	require.Len(t, problems, 1)
+}
+
+func TestWarnSymmetricDependencyNames(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	lt := link.WorkItemLinkType{
+		Topology:    link.TopologyDependency,
+		ForwardName: "depends on",
+		ReverseName: "is depended on by",
+	}
+
+	// Test directional names
+	require.Empty(t, link.WarnSymmetricDependencyNames(lt))
+
+	// Test identical names
+	lt.ForwardName = "Blocks"
+	lt.ReverseName = " blocks"
+	require.Len(t, link.WarnSymmetricDependencyNames(lt), 1)
+
+	// Test symmetric wording
+	lt.ForwardName = "relates to"
+	lt.ReverseName = "is depended on by"
+	warnings := link.WarnSymmetricDependencyNames(lt)
+	require.Len(t, warnings, 1)
+	require.Contains(t, warnings[0], "relates to")
+
+	// Test symmetric names of another topology
+	lt.Topology = link.TopologyNetwork
+	lt.ReverseName = "relates to"
+	require.Nil(t, link.WarnSymmetricDependencyNames(lt))
+}
+
+func TestCanonicalizeTopology(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	// Check canonical topologies
+	topology, err := link.CanonicalizeTopology("Directed Network")
+	require.Nil(t, err)
+	require.Equal(t, link.TopologyDirectedNetwork, topology)
+	topology, err = link.CanonicalizeTopology(" tree ")
+	require.Nil(t, err)
+	require.Equal(t, link.TopologyTree, topology)
+
+	// Check unknown topology
+	_, err = link.CanonicalizeTopology("Star")
+	require.IsType(t, errors.BadParameterError{}, err)
+
+	// Check conversion of a non-canonical topology
+	topologyAttr := "Dependency"
+	in := app.WorkItemLinkTypeSingle{
+		Data: &app.WorkItemLinkTypeData{
+			Attributes: &app.WorkItemLinkTypeAttributes{
+				Topology: &topologyAttr,
+			},
+			Relationships: &app.WorkItemLinkTypeRelationships{},
+		},
+	}
+	out := link.WorkItemLinkType{}
+	require.Nil(t, link.ConvertLinkTypeToModel(in, &out))
+	require.Equal(t, link.TopologyDependency, out.Topology)
+}
+
+func TestWorkItemLinkTypeNormalizeLinkDirection(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	lt := link.WorkItemLinkType{Topology: link.TopologyNetwork}
+
+	// Check network link with swapped IDs is canonicalized
+	source, target, reversed := lt.NormalizeLinkDirection(42, 7)
+	require.Equal(t, uint64(7), source)
+	require.Equal(t, uint64(42), target)
+	require.True(t, reversed)
+
+	// Check network link in canonical order
+	source, target, reversed = lt.NormalizeLinkDirection(7, 42)
+	require.Equal(t, uint64(7), source)
+	require.Equal(t, uint64(42), target)
+	require.False(t, reversed)
+
+	// Check directed topologies are unchanged
+	for _, topology := range []string{link.TopologyDirectedNetwork, link.TopologyDependency, link.TopologyTree} {
+		lt.Topology = topology
+		source, target, reversed = lt.NormalizeLinkDirection(42, 7)
+		require.Equal(t, uint64(42), source)
+		require.Equal(t, uint64(7), target)
+		require.False(t, reversed)
+	}
+}
+
+func TestWorkItemLinkTypeLabelFor(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	lt := link.WorkItemLinkType{
+		Topology:    link.TopologyTree,
+		ForwardName: "parent of",
+		ReverseName: "child of",
+	}
+
+	// Check source perspective
+	label, err := lt.LabelFor(7, 7, 42)
+	require.Nil(t, err)
+	require.Equal(t, "parent of", label)
+
+	// Check target perspective
+	label, err = lt.LabelFor(42, 7, 42)
+	require.Nil(t, err)
+	require.Equal(t, "child of", label)
+
+	// Check unrelated item
+	_, err = lt.LabelFor(1, 7, 42)
+	require.IsType(t, errors.BadParameterError{}, err)
+
+	// Check network topology uses the forward name from both ends
+	lt.Topology = link.TopologyNetwork
+	label, err = lt.LabelFor(42, 7, 42)
+	require.Nil(t, err)
+	require.Equal(t, "parent of", label)
+}
+
+func TestWorkItemLinkTypeInverse(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	maxSourceCount := 1
+	lt := link.WorkItemLinkType{
+		ID:             satoriuuid.NewV4(),
+		Name:           "parenting",
+		Topology:       link.TopologyTree,
+		ForwardName:    "parent of",
+		ReverseName:    "child of",
+		SourceTypeID:   satoriuuid.NewV4(),
+		TargetTypeID:   satoriuuid.NewV4(),
+		MaxSourceCount: &maxSourceCount,
+	}
+	original := lt
+
+	// Check directed link type
+	inverse := lt.Inverse()
+	require.Equal(t, lt.ID, inverse.ID)
+	require.Equal(t, "child of", inverse.ForwardName)
+	require.Equal(t, "parent of", inverse.ReverseName)
+	require.Equal(t, lt.TargetTypeID, inverse.SourceTypeID)
+	require.Equal(t, lt.SourceTypeID, inverse.TargetTypeID)
+	require.Nil(t, inverse.MaxSourceCount)
+	require.Equal(t, &maxSourceCount, inverse.MaxTargetCount)
+	require.True(t, original.Equal(lt))
+	require.True(t, lt.Equal(inverse.Inverse()))
+
+	// Check network link type is its own inverse
+	lt.Topology = link.TopologyNetwork
+	require.True(t, lt.Equal(lt.Inverse()))
+}
+
+func TestWarnRedundantDescription(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	lt := link.WorkItemLinkType{Name: "Bug blocker"}
+
+	// Check nil description
+	require.False(t, link.WarnRedundantDescription(lt))
+
+	// Check identical description
+	description := " bug BLOCKER "
+	lt.Description = &description
+	require.True(t, link.WarnRedundantDescription(lt))
+
+	// Check distinct description
+	description = "A bug that blocks another work item"
+	require.False(t, link.WarnRedundantDescription(lt))
+}
+
+func TestDetectAmbiguousPairs(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	newLinkType := func(forwardName, reverseName string, targetTypeID satoriuuid.UUID) link.WorkItemLinkType {
+		return link.WorkItemLinkType{
+			ID:           satoriuuid.NewV4(),
+			Topology:     link.TopologyDependency,
+			SourceTypeID: workitem.SystemBug,
+			TargetTypeID: targetTypeID,
+			ForwardName:  forwardName,
+			ReverseName:  reverseName,
+		}
+	}
+	blocks := newLinkType("blocks", "blocked by", workitem.SystemBug)
+	parentOf := newLinkType("parent of", "child of", workitem.SystemBug)
+	otherTarget := newLinkType("is blocked by", "blocks", workitem.SystemUserStory)
+
+	// Check clean set
+	require.Empty(t, link.DetectAmbiguousPairs([]link.WorkItemLinkType{blocks, parentOf, otherTarget}))
+
+	// Check ambiguous pair with swapped names
+	swapped := newLinkType("Is blocked by", " Blocks", workitem.SystemBug)
+	pairs := link.DetectAmbiguousPairs([]link.WorkItemLinkType{blocks, parentOf, otherTarget, swapped})
+	require.Len(t, pairs, 1)
+	require.Equal(t, [2]satoriuuid.UUID{blocks.ID, swapped.ID}, pairs[0])
+}
+
+func TestWarnAmbiguousDirectionalNames(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	lt := link.WorkItemLinkType{
+		Topology:    link.TopologyDependency,
+		ForwardName: "blocks",
+		ReverseName: "blocked by",
+	}
+
+	// Check clearly distinct names
+	require.Empty(t, link.WarnAmbiguousDirectionalNames(lt))
+
+	// Check forward name being a prefix of the reverse name
+	lt.ReverseName = "Blocks this"
+	warnings := link.WarnAmbiguousDirectionalNames(lt)
+	require.Len(t, warnings, 1)
+	require.Contains(t, warnings[0], "blocks")
+
+	// Check reverse name being a prefix of the forward name
+	lt.ForwardName = "blocks this item"
+	lt.ReverseName = "blocks"
+	require.Len(t, link.WarnAmbiguousDirectionalNames(lt), 1)
+
+	// Check the network topology is undirected and never ambiguous
+	lt.Topology = link.TopologyNetwork
+	require.Empty(t, link.WarnAmbiguousDirectionalNames(lt))
+
+	// Check the warning doesn't block creation
+	lt = link.WorkItemLinkType{
+		Name:           "Example work item link type",
+		Topology:       link.TopologyDependency,
+		SourceTypeID:   workitem.SystemBug,
+		TargetTypeID:   workitem.SystemUserStory,
+		ForwardName:    "blocks",
+		ReverseName:    "blocks this",
+		LinkCategoryID: satoriuuid.FromStringOrNil("0e671e36-871b-43a6-9166-0c4bd573eAAA"),
+		SpaceID:        satoriuuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+	}
+	require.NotEmpty(t, link.WarnAmbiguousDirectionalNames(lt))
+	require.Nil(t, lt.CheckValidForCreation())
+}
+
+// newTypeHierarchy returns n work item types that form a tree in which the
+// i-th type extends the ((i-1)/2)-th type.
+func newTypeHierarchy(n int) []workitem.WorkItemType {
+	types := make([]workitem.WorkItemType, n)
+	for i := range types {
+		id := satoriuuid.NewV4()
+		path := workitem.LtreeSafeID(id)
+		if i > 0 {
+			path = types[(i-1)/2].Path + workitem.GetTypePathSeparator() + path
+		}
+		types[i] = workitem.WorkItemType{ID: id, Path: path}
+	}
+	return types
+}
+
+func TestSubtypeMatcher(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	types := newTypeHierarchy(15)
+	unknown := workitem.WorkItemType{ID: satoriuuid.NewV4()}
+	unknown.Path = workitem.LtreeSafeID(unknown.ID)
+
+	// Check every combination of source and target type agrees with IsTypeOrSubtypeOf
+	for _, source := range types {
+		for _, target := range types {
+			lt := link.WorkItemLinkType{SourceTypeID: source.ID, TargetTypeID: target.ID}
+			m := link.NewSubtypeMatcher(lt, types)
+			for _, wit := range types {
+				require.Equal(t, wit.IsTypeOrSubtypeOf(source.ID), m.MatchesSource(wit.ID), "source %s, type %s", source.Path, wit.Path)
+				require.Equal(t, wit.IsTypeOrSubtypeOf(target.ID), m.MatchesTarget(wit.ID), "target %s, type %s", target.Path, wit.Path)
+			}
+			// Check types the matcher doesn't know never match
+			require.False(t, m.MatchesSource(unknown.ID))
+			require.False(t, m.MatchesTarget(unknown.ID))
+		}
+	}
+}
+
+func BenchmarkSubtypeMatcher(b *testing.B) {
+	types := newTypeHierarchy(100)
+	lt := link.WorkItemLinkType{SourceTypeID: types[1].ID, TargetTypeID: types[2].ID}
+	m := link.NewSubtypeMatcher(lt, types)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		wit := types[i%len(types)]
+		m.MatchesSource(wit.ID)
+		m.MatchesTarget(wit.ID)
+	}
+}
+
+func BenchmarkIsTypeOrSubtypeOf(b *testing.B) {
+	types := newTypeHierarchy(100)
+	lt := link.WorkItemLinkType{SourceTypeID: types[1].ID, TargetTypeID: types[2].ID}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		wit := types[i%len(types)]
+		wit.IsTypeOrSubtypeOf(lt.SourceTypeID)
+		wit.IsTypeOrSubtypeOf(lt.TargetTypeID)
+	}
 }
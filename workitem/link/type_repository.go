@@ -2,12 +2,14 @@ package link
 
 import (
 	"fmt"
+	"strings"
 
 	"golang.org/x/net/context"
 
 	"github.com/almighty/almighty-core/app"
 	"github.com/almighty/almighty-core/errors"
 	"github.com/almighty/almighty-core/log"
+	"github.com/almighty/almighty-core/models"
 	"github.com/almighty/almighty-core/space"
 	"github.com/almighty/almighty-core/workitem"
 
@@ -20,10 +22,53 @@ import (
 // WorkItemLinkTypeRepository encapsulates storage & retrieval of work item link types
 type WorkItemLinkTypeRepository interface {
 	Create(ctx context.Context, name string, description *string, sourceTypeID, targetTypeID satoriuuid.UUID, forwardName, reverseName, topology string, linkCategory, spaceID satoriuuid.UUID) (*app.WorkItemLinkTypeSingle, error)
+	// CreateMany creates all of the given link types or none of them.
+	CreateMany(ctx context.Context, types []*WorkItemLinkType) ([]*WorkItemLinkType, error)
 	Load(ctx context.Context, ID satoriuuid.UUID) (*app.WorkItemLinkTypeSingle, error)
+	// LoadSystemLinkType loads the system link type with the given well-known
+	// name (e.g. SystemWorkItemLinkTypeBugBlocker) of the given space.
+	LoadSystemLinkType(ctx context.Context, name string, spaceID satoriuuid.UUID) (*WorkItemLinkType, error)
+	// LoadDefault loads the link type of the given space that is
+	// preselected when creating a link.
+	LoadDefault(ctx context.Context, spaceID satoriuuid.UUID) (*WorkItemLinkType, error)
+	// LoadIncludingDeleted loads the link type with the given ID even if it
+	// has been soft-deleted.
+	LoadIncludingDeleted(ctx context.Context, ID satoriuuid.UUID) (*WorkItemLinkType, error)
 	List(ctx context.Context) (*app.WorkItemLinkTypeList, error)
+	// ListIncludingDeleted returns all link types of the given space
+	// including soft-deleted ones.
+	ListIncludingDeleted(ctx context.Context, spaceID satoriuuid.UUID) ([]WorkItemLinkType, error)
+	// ListActive returns the link types of the given space that are not
+	// deprecated.
+	ListActive(ctx context.Context, spaceID satoriuuid.UUID) (*app.WorkItemLinkTypeList, error)
+	// ListApplicable returns the active link types of the given space that
+	// can link a work item of the given source type to a work item of the
+	// given target type.
+	ListApplicable(ctx context.Context, spaceID, sourceTypeID, targetTypeID satoriuuid.UUID, witRepo workitem.WorkItemTypeLoader) ([]WorkItemLinkType, error)
+	// ListBySpace returns all link types of the given space including
+	// deprecated ones.
+	ListBySpace(ctx context.Context, spaceID satoriuuid.UUID) ([]WorkItemLinkType, error)
+	// ListByCategory returns the link types of the given space that belong
+	// to the given link category.
+	ListByCategory(ctx context.Context, spaceID, categoryID satoriuuid.UUID) ([]WorkItemLinkType, error)
+	// Reorder assigns sequential display orders to the link types of the
+	// given space in the order of the given IDs.
+	Reorder(ctx context.Context, spaceID satoriuuid.UUID, orderedIDs []satoriuuid.UUID) error
+	// FindSemanticDuplicates returns groups of link types of the given space
+	// that only differ in name.
+	FindSemanticDuplicates(ctx context.Context, spaceID satoriuuid.UUID) ([][]satoriuuid.UUID, error)
 	Delete(ctx context.Context, ID satoriuuid.UUID) error
+	// Restore undoes the soft-deletion of the link type with the given ID.
+	Restore(ctx context.Context, ID satoriuuid.UUID) (*WorkItemLinkType, error)
+	// LoadVersion returns the work item link type with the given ID as it
+	// was at the given version.
+	LoadVersion(ctx context.Context, ID satoriuuid.UUID, version int) (*WorkItemLinkType, error)
 	Save(ctx context.Context, linkCat app.WorkItemLinkTypeSingle) (*app.WorkItemLinkTypeSingle, error)
+	// CountLinks returns the number of work item links that use the given
+	// link type.
+	CountLinks(ctx context.Context, linkTypeID satoriuuid.UUID) (int, error)
+	// CanDelete returns true if no work item link uses the given link type.
+	CanDelete(ctx context.Context, linkTypeID satoriuuid.UUID) (bool, error)
 	// ListSourceLinkTypes returns the possible link types for where the given
 	// WIT can be used in the source.
 	ListSourceLinkTypes(ctx context.Context, witID satoriuuid.UUID) (*app.WorkItemLinkTypeList, error)
@@ -42,50 +87,305 @@ type GormWorkItemLinkTypeRepository struct {
 	db *gorm.DB
 }
 
-// Create creates a new work item link type in the repository.
-// Returns BadParameterError, ConversionError or InternalError
+// The kinds of referenced entities reported by a
+// ReferencedEntityNotFoundError when a work item link type or a work item
+// link references an entity that doesn't exist.
+const (
+	ReferencedKindWorkItemType   = "work item type"
+	ReferencedKindLinkCategory   = "work item link category"
+	ReferencedKindSpace          = "space"
+	ReferencedKindSourceWorkItem = "source work item"
+	ReferencedKindTargetWorkItem = "target work item"
+)
+
+// Create creates a new work item link type in the repository. The link type
+// is never a system link type, so it can't be created in the system space (see
+// CreateSystemType).
+// Returns BadParameterError, ReferencedEntityNotFoundError, ConversionError or InternalError
 func (r *GormWorkItemLinkTypeRepository) Create(ctx context.Context, name string, description *string, sourceTypeID, targetTypeID satoriuuid.UUID, forwardName, reverseName, topology string, linkCategoryID, spaceID satoriuuid.UUID) (*app.WorkItemLinkTypeSingle, error) {
 	linkType := &WorkItemLinkType{
 		Name:           name,
 		Description:    description,
 		SourceTypeID:   sourceTypeID,
 		TargetTypeID:   targetTypeID,
-		ForwardName:    forwardName,
-		ReverseName:    reverseName,
+		ForwardName:    strings.TrimSpace(forwardName),
+		ReverseName:    strings.TrimSpace(reverseName),
 		Topology:       topology,
 		LinkCategoryID: linkCategoryID,
 		SpaceID:        spaceID,
 	}
-	if err := linkType.CheckValidForCreation(); err != nil {
+	if err := createLinkType(ctx, r.db, linkType); err != nil {
 		return nil, errs.WithStack(err)
 	}
+	// Convert the created link type entry into a JSONAPI response
+	result := ConvertLinkTypeFromModel(goa.ContextRequest(ctx), *linkType)
+	return &result, nil
+}
 
-	// Check link category exists
-	linkCategory := WorkItemLinkCategory{}
-	db := r.db.Where("id=?", linkType.LinkCategoryID).Find(&linkCategory)
-	if db.RecordNotFound() {
-		return nil, errors.NewBadParameterError("work item link category", linkType.LinkCategoryID)
+// CreateFromModel creates the given work item link type like Create does, but
+// takes all of its attributes (e.g. the multiplicity limits or the display
+// order) from the given model. The link type is never a system link type.
+// Returns BadParameterError, ReferencedEntityNotFoundError or InternalError
+func (r *GormWorkItemLinkTypeRepository) CreateFromModel(ctx context.Context, linkType *WorkItemLinkType) error {
+	linkType.ForwardName = strings.TrimSpace(linkType.ForwardName)
+	linkType.ReverseName = strings.TrimSpace(linkType.ReverseName)
+	linkType.System = false
+	return createLinkType(ctx, r.db, linkType)
+}
+
+// CreateSystemType creates the given work item link type as a system link
+// type, which may use a reserved name and live in the system space. It is
+// meant for the system's own link types (e.g. during the migration) and must
+// not be exposed to clients.
+// Returns BadParameterError, ReferencedEntityNotFoundError or InternalError
+func (r *GormWorkItemLinkTypeRepository) CreateSystemType(ctx context.Context, linkType *WorkItemLinkType) error {
+	linkType.ForwardName = strings.TrimSpace(linkType.ForwardName)
+	linkType.ReverseName = strings.TrimSpace(linkType.ReverseName)
+	linkType.System = true
+	return createLinkType(ctx, r.db, linkType)
+}
+
+// createLinkType validates the given link type, checks that the entities it
+// references exist and that it doesn't become a second default link type of
+// its space and inserts it using the given database handle.
+// Returns BadParameterError, ReferencedEntityNotFoundError or InternalError
+func createLinkType(ctx context.Context, db *gorm.DB, linkType *WorkItemLinkType) error {
+	if err := linkType.CheckValidForCreation(); err != nil {
+		return errs.WithStack(err)
 	}
-	if db.Error != nil {
-		return nil, errors.NewInternalError(fmt.Sprintf("Failed to find work item link category: %s", db.Error.Error()))
+	typeIDs := []satoriuuid.UUID{linkType.SourceTypeID, linkType.TargetTypeID}
+	if err := CheckTypeIDsExist(ctx, typeIDs, linkType.SpaceID, workitem.NewWorkItemTypeRepository(db)); err != nil {
+		return errs.WithStack(err)
+	}
+
+	if err := CheckCategoryInSpace(ctx, linkType.LinkCategoryID, linkType.SpaceID, NewWorkItemLinkCategoryRepository(db)); err != nil {
+		return errs.WithStack(err)
 	}
 	// Check space exists
 	space := space.Space{}
-	db = r.db.Where("id=?", linkType.SpaceID).Find(&space)
-	if db.RecordNotFound() {
-		return nil, errors.NewBadParameterError("work item link space", linkType.SpaceID)
+	res := db.Where("id=?", linkType.SpaceID).Find(&space)
+	if res.RecordNotFound() {
+		return errors.NewReferencedEntityNotFoundError(ReferencedKindSpace, linkType.SpaceID.String())
 	}
-	if db.Error != nil {
-		return nil, errors.NewInternalError(fmt.Sprintf("Failed to find work item link space: %s", db.Error.Error()))
+	if res.Error != nil {
+		return errors.NewInternalError(fmt.Sprintf("Failed to find work item link space: %s", res.Error.Error()))
+	}
+	if err := checkSingleDefault(db, *linkType); err != nil {
+		return errs.WithStack(err)
 	}
 
-	db = r.db.Create(linkType)
-	if db.Error != nil {
-		return nil, errors.NewInternalError(db.Error.Error())
+	if err := db.Create(linkType).Error; err != nil {
+		return errors.NewInternalError(err.Error())
 	}
-	// Convert the created link type entry into a JSONAPI response
-	result := ConvertLinkTypeFromModel(goa.ContextRequest(ctx), *linkType)
-	return &result, nil
+	return nil
+}
+
+// CreateManyError is returned by CreateMany when one of the given link types
+// could not be created. Index is the position of the failing link type in
+// the batch; Cause returns the underlying error.
+type CreateManyError struct {
+	Index int
+	Err   error
+}
+
+// Error implements the error interface
+func (err CreateManyError) Error() string {
+	return fmt.Sprintf("failed to create work item link type at index %d: %s", err.Index, err.Err.Error())
+}
+
+// Cause returns the error that made the creation of the link type fail
+func (err CreateManyError) Cause() error {
+	return err.Err
+}
+
+// CreateMany creates all of the given work item link types in a single
+// transaction. If any of the link types is invalid or can't be inserted,
+// none of them is created and a CreateManyError carrying the index of the
+// failing link type is returned. Like Create, none of the link types is a
+// system link type.
+// Returns CreateManyError (whose cause is a BadParameterError,
+// ReferencedEntityNotFoundError or InternalError) or InternalError
+func (r *GormWorkItemLinkTypeRepository) CreateMany(ctx context.Context, types []*WorkItemLinkType) ([]*WorkItemLinkType, error) {
+	var failed *CreateManyError
+	err := models.Transactional(r.db, func(tx *gorm.DB) error {
+		for i, linkType := range types {
+			if linkType == nil {
+				failed = &CreateManyError{Index: i, Err: errors.NewBadParameterError("types", nil).Expected("not <nil>")}
+				return failed.Err
+			}
+			if err := NewWorkItemLinkTypeRepository(tx).CreateFromModel(ctx, linkType); err != nil {
+				failed = &CreateManyError{Index: i, Err: errs.Cause(err)}
+				return failed.Err
+			}
+		}
+		return nil
+	})
+	if failed != nil {
+		return nil, *failed
+	}
+	if err != nil {
+		return nil, errors.NewInternalError(err.Error())
+	}
+	return types, nil
+}
+
+// CheckCategoryInSpace returns a BadParameterError if the work item link
+// category with the given ID belongs to a space other than the given one.
+// Categories of the system space are shared by all spaces and can be used
+// in every space.
+// returns ReferencedEntityNotFoundError, BadParameterError or InternalError
+func CheckCategoryInSpace(ctx context.Context, categoryID, spaceID satoriuuid.UUID, repo WorkItemLinkCategoryRepository) error {
+	category, err := repo.LoadCategoryFromDBByID(ctx, categoryID)
+	if err != nil {
+		if _, ok := errs.Cause(err).(errors.NotFoundError); ok {
+			return errors.NewReferencedEntityNotFoundError(ReferencedKindLinkCategory, categoryID.String())
+		}
+		return errs.WithStack(err)
+	}
+	if !satoriuuid.Equal(category.SpaceID, ReservedSpaceID) && !satoriuuid.Equal(category.SpaceID, spaceID) {
+		return errors.NewBadParameterError("link_category_id", categoryID).Expected(fmt.Sprintf("link category of space %s or of the system space", spaceID))
+	}
+	return nil
+}
+
+// CheckTypeIDsExist returns a ReferencedEntityNotFoundError listing all of
+// the given work item type IDs that don't exist. Duplicate IDs are only
+// checked once. If all types exist, a BadParameterError is returned for the
+// first one that belongs to a space other than the given one (see
+// CheckTypeInSpace).
+// returns ReferencedEntityNotFoundError, BadParameterError or InternalError
+func CheckTypeIDsExist(ctx context.Context, ids []satoriuuid.UUID, spaceID satoriuuid.UUID, repo workitem.WorkItemTypeLoader) error {
+	checked := map[satoriuuid.UUID]bool{}
+	var missing []string
+	var foreign error
+	for _, id := range ids {
+		if checked[id] {
+			continue
+		}
+		checked[id] = true
+		wit, err := repo.LoadTypeFromDB(ctx, id)
+		if err != nil {
+			if _, notFound := errs.Cause(err).(errors.NotFoundError); !notFound {
+				return errs.WithStack(err)
+			}
+			missing = append(missing, id.String())
+			continue
+		}
+		if foreign == nil {
+			foreign = CheckTypeInSpace(*wit, spaceID)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.NewReferencedEntityNotFoundError(ReferencedKindWorkItemType, strings.Join(missing, ", "))
+	}
+	return foreign
+}
+
+// CheckTypeInSpace returns a BadParameterError if the given work item type
+// belongs to a space other than the given one. Work item types of the system
+// space are shared by all spaces and can be used in every space.
+func CheckTypeInSpace(wit workitem.WorkItemType, spaceID satoriuuid.UUID) error {
+	if !satoriuuid.Equal(wit.SpaceID, ReservedSpaceID) && !satoriuuid.Equal(wit.SpaceID, spaceID) {
+		return errors.NewBadParameterError("work item type", wit.ID).Expected(fmt.Sprintf("work item type of space %s or of the system space", spaceID))
+	}
+	return nil
+}
+
+// ValidateLinkTypeDraft checks whether the given work item link type could be
+// created. It applies the same rules as CheckValidForCreation and
+// additionally checks that the source and target work item types, the link
+// category and the space exist and that the work item types and the link
+// category may be used in the space (see CheckTypeInSpace and
+// CheckCategoryInSpace). Instead of stopping at the first missing
+// reference, all problems found are returned. Nothing is written and the
+// given link type is not modified.
+func ValidateLinkTypeDraft(ctx context.Context, t WorkItemLinkType, witRepo workitem.WorkItemTypeLoader, categoryRepo WorkItemLinkCategoryRepository, spaceRepo space.Repository) []error {
+	var result []error
+	// t is a copy, so CheckValidForCreation can't modify the caller's draft
+	if err := t.CheckValidForCreation(); err != nil {
+		result = append(result, errs.Cause(err))
+	}
+	if !satoriuuid.Equal(t.SourceTypeID, satoriuuid.Nil) {
+		if wit, err := witRepo.LoadTypeFromDB(ctx, t.SourceTypeID); err != nil {
+			result = append(result, errors.NewBadParameterError("source_type_id", t.SourceTypeID).Expected("existing work item type"))
+		} else if err := CheckTypeInSpace(*wit, t.SpaceID); err != nil {
+			result = append(result, err)
+		}
+	}
+	if !satoriuuid.Equal(t.TargetTypeID, satoriuuid.Nil) {
+		if wit, err := witRepo.LoadTypeFromDB(ctx, t.TargetTypeID); err != nil {
+			result = append(result, errors.NewBadParameterError("target_type_id", t.TargetTypeID).Expected("existing work item type"))
+		} else if err := CheckTypeInSpace(*wit, t.SpaceID); err != nil {
+			result = append(result, err)
+		}
+	}
+	if !satoriuuid.Equal(t.LinkCategoryID, satoriuuid.Nil) {
+		err := CheckCategoryInSpace(ctx, t.LinkCategoryID, t.SpaceID, categoryRepo)
+		switch errs.Cause(err).(type) {
+		case nil:
+		case errors.BadParameterError:
+			result = append(result, errs.Cause(err))
+		default:
+			result = append(result, errors.NewBadParameterError("link_category_id", t.LinkCategoryID).Expected("existing work item link category"))
+		}
+	}
+	if !satoriuuid.Equal(t.SpaceID, satoriuuid.Nil) {
+		if _, err := spaceRepo.Load(ctx, t.SpaceID); err != nil {
+			result = append(result, errors.NewBadParameterError("space_id", t.SpaceID).Expected("existing space"))
+		}
+	}
+	return result
+}
+
+// ValidateSpaceLinkTypes checks the whole link type configuration of the
+// given space and returns all problems found instead of stopping at the
+// first one. It reports link types whose source or target work item type or
+// whose link category doesn't exist (anymore) or belongs to another space
+// (see CheckTypeInSpace and CheckCategoryInSpace), link types outside the system space that use a
+// name reserved for system link types and groups of semantically duplicate
+// link types (see FindSemanticDuplicates). Errors that prevent the checks from
+// running at all are returned as the only element.
+func ValidateSpaceLinkTypes(ctx context.Context, spaceID satoriuuid.UUID, linkTypeRepo WorkItemLinkTypeRepository, witRepo workitem.WorkItemTypeLoader, categoryRepo WorkItemLinkCategoryRepository) []error {
+	linkTypes, err := linkTypeRepo.ListBySpace(ctx, spaceID)
+	if err != nil {
+		return []error{errs.WithStack(err)}
+	}
+	var result []error
+	for _, lt := range linkTypes {
+		if wit, err := witRepo.LoadTypeFromDB(ctx, lt.SourceTypeID); err != nil {
+			result = append(result, errs.Wrapf(errors.NewBadParameterError("source_type_id", lt.SourceTypeID).Expected("existing work item type"), "work item link type %s", lt.ID))
+		} else if err := CheckTypeInSpace(*wit, lt.SpaceID); err != nil {
+			result = append(result, errs.Wrapf(err, "work item link type %s", lt.ID))
+		}
+		if wit, err := witRepo.LoadTypeFromDB(ctx, lt.TargetTypeID); err != nil {
+			result = append(result, errs.Wrapf(errors.NewBadParameterError("target_type_id", lt.TargetTypeID).Expected("existing work item type"), "work item link type %s", lt.ID))
+		} else if err := CheckTypeInSpace(*wit, lt.SpaceID); err != nil {
+			result = append(result, errs.Wrapf(err, "work item link type %s", lt.ID))
+		}
+		err := CheckCategoryInSpace(ctx, lt.LinkCategoryID, lt.SpaceID, categoryRepo)
+		switch errs.Cause(err).(type) {
+		case nil:
+		case errors.BadParameterError:
+			result = append(result, errs.Wrapf(errs.Cause(err), "work item link type %s", lt.ID))
+		default:
+			result = append(result, errs.Wrapf(errors.NewBadParameterError("link_category_id", lt.LinkCategoryID).Expected("existing work item link category"), "work item link type %s", lt.ID))
+		}
+		if !satoriuuid.Equal(spaceID, space.SystemSpace) && IsReservedLinkTypeName(lt.Name) {
+			result = append(result, errs.Wrapf(errors.NewBadParameterError("name", lt.Name).Expected("a name not reserved for system link types"), "work item link type %s", lt.ID))
+		}
+	}
+	duplicates, err := linkTypeRepo.FindSemanticDuplicates(ctx, spaceID)
+	if err != nil {
+		return append(result, errs.WithStack(err))
+	}
+	for _, group := range duplicates {
+		ids := make([]string, len(group))
+		for i, id := range group {
+			ids[i] = id.String()
+		}
+		result = append(result, errors.NewBadParameterError("work item link types", strings.Join(ids, ", ")).Expected("link types that differ in more than their name"))
+	}
+	return result
 }
 
 // Load returns the work item link type for the given ID.
@@ -134,6 +434,65 @@ func (r *GormWorkItemLinkTypeRepository) LoadTypeFromDBByNameAndCategory(ctx con
 	return &res, nil
 }
 
+// LoadSystemLinkType returns the link type of the given space whose name is
+// the given well-known system link type name, i.e. one of the
+// SystemWorkItemLinkType* constants. This saves callers from knowing the
+// UUIDs of the system link types.
+// returns BadParameterError (for an unknown name), NotFoundError or InternalError
+func (r *GormWorkItemLinkTypeRepository) LoadSystemLinkType(ctx context.Context, name string, spaceID satoriuuid.UUID) (*WorkItemLinkType, error) {
+	known := false
+	for _, systemName := range reservedLinkTypeNames {
+		if name == systemName {
+			known = true
+		}
+	}
+	if !known {
+		return nil, errors.NewBadParameterError("name", name).Expected(fmt.Sprintf("one of %s", strings.Join(reservedLinkTypeNames, ", ")))
+	}
+	res := WorkItemLinkType{}
+	db := r.db.Model(&res).Where("name=? AND space_id=?", name, spaceID).First(&res)
+	if db.RecordNotFound() {
+		return nil, errors.NewNotFoundError("work item link type", name)
+	}
+	if db.Error != nil {
+		return nil, errors.NewInternalError(db.Error.Error())
+	}
+	return &res, nil
+}
+
+// LoadDefault returns the default link type of the given space, i.e. the one
+// with IsDefault set.
+// returns NotFoundError or InternalError
+func (r *GormWorkItemLinkTypeRepository) LoadDefault(ctx context.Context, spaceID satoriuuid.UUID) (*WorkItemLinkType, error) {
+	res := WorkItemLinkType{}
+	db := r.db.Model(&res).Where("space_id = ? AND is_default", spaceID).First(&res)
+	if db.RecordNotFound() {
+		return nil, errors.NewNotFoundError("default work item link type of space", spaceID.String())
+	}
+	if db.Error != nil {
+		return nil, errors.NewInternalError(db.Error.Error())
+	}
+	return &res, nil
+}
+
+// checkSingleDefault returns a BadParameterError if the given link type is
+// marked as default but another link type of its space already is.
+// returns BadParameterError or InternalError
+func checkSingleDefault(db *gorm.DB, linkType WorkItemLinkType) error {
+	if !linkType.IsDefault {
+		return nil
+	}
+	var count int
+	res := db.Model(&WorkItemLinkType{}).Where("space_id = ? AND is_default AND id <> ?", linkType.SpaceID, linkType.ID).Count(&count)
+	if res.Error != nil {
+		return errors.NewInternalError(res.Error.Error())
+	}
+	if count > 0 {
+		return errors.NewBadParameterError("is_default", linkType.IsDefault).Expected(fmt.Sprintf("at most one default link type in space %s", linkType.SpaceID))
+	}
+	return nil
+}
+
 // LoadTypeFromDB return work item link type for the given ID
 func (r *GormWorkItemLinkTypeRepository) LoadTypeFromDBByID(ctx context.Context, ID satoriuuid.UUID) (*WorkItemLinkType, error) {
 	log.Info(ctx, map[string]interface{}{
@@ -154,12 +513,68 @@ func (r *GormWorkItemLinkTypeRepository) LoadTypeFromDBByID(ctx context.Context,
 	return &res, nil
 }
 
+// LoadIncludingDeleted returns the work item link type for the given ID
+// without filtering soft-deleted link types. The DeletedAt of the Lifecycle
+// tells whether the link type has been deleted. Use this only for
+// administrative purposes such as restoring link types.
+// returns NotFoundError or InternalError
+func (r *GormWorkItemLinkTypeRepository) LoadIncludingDeleted(ctx context.Context, ID satoriuuid.UUID) (*WorkItemLinkType, error) {
+	res := WorkItemLinkType{}
+	db := r.db.Unscoped().Model(&res).Where("id=?", ID).First(&res)
+	if db.RecordNotFound() {
+		return nil, errors.NewNotFoundError("work item link type", ID.String())
+	}
+	if db.Error != nil {
+		return nil, errors.NewInternalError(db.Error.Error())
+	}
+	return &res, nil
+}
+
+// ListIncludingDeleted returns all work item link types of the given space,
+// including soft-deleted ones, ordered by name.
+// returns InternalError
+func (r *GormWorkItemLinkTypeRepository) ListIncludingDeleted(ctx context.Context, spaceID satoriuuid.UUID) ([]WorkItemLinkType, error) {
+	res := []WorkItemLinkType{}
+	db := r.db.Unscoped().Where("space_id = ?", spaceID).Order("name").Find(&res)
+	if db.Error != nil {
+		return nil, errors.NewInternalError(db.Error.Error())
+	}
+	return res, nil
+}
+
+// LoadByDirectionalName returns the work item link type in the given space
+// whose forward or reverse name matches the given name (case-insensitive).
+// The returned boolean is true if the match was on the reverse name, which
+// tells the caller that the link is meant to be used in reverse direction.
+// Returns NotFoundError or InternalError
+func (r *GormWorkItemLinkTypeRepository) LoadByDirectionalName(ctx context.Context, spaceID satoriuuid.UUID, name string) (*WorkItemLinkType, bool, error) {
+	log.Info(ctx, map[string]interface{}{
+		"spaceID":  spaceID,
+		"wiltName": name,
+	}, "Loading work item link type by directional name %s", name)
+
+	res := WorkItemLinkType{}
+	db := r.db.Model(&res).Where("space_id=? AND (LOWER(forward_name)=LOWER(?) OR LOWER(reverse_name)=LOWER(?))", spaceID, name, name).First(&res)
+	if db.RecordNotFound() {
+		log.Error(ctx, map[string]interface{}{
+			"spaceID":  spaceID,
+			"wiltName": name,
+		}, "work item link type not found")
+		return nil, false, errors.NewNotFoundError("work item link type", name)
+	}
+	if db.Error != nil {
+		return nil, false, errors.NewInternalError(db.Error.Error())
+	}
+	isReverse := !strings.EqualFold(res.ForwardName, name)
+	return &res, isReverse, nil
+}
+
 // List returns all work item link types
 // TODO: Handle pagination
 func (r *GormWorkItemLinkTypeRepository) List(ctx context.Context) (*app.WorkItemLinkTypeList, error) {
 	// We don't have any where clause or paging at the moment.
 	var rows []WorkItemLinkType
-	db := r.db.Find(&rows)
+	db := r.db.Order("display_order, name").Find(&rows)
 	if db.Error != nil {
 		return nil, db.Error
 	}
@@ -177,8 +592,188 @@ func (r *GormWorkItemLinkTypeRepository) List(ctx context.Context) (*app.WorkIte
 	return &res, nil
 }
 
-// Delete deletes the work item link type with the given id
+// ListActive returns all work item link types of the given space that have
+// not been deprecated. Use List to also retrieve deprecated link types.
+func (r *GormWorkItemLinkTypeRepository) ListActive(ctx context.Context, spaceID satoriuuid.UUID) (*app.WorkItemLinkTypeList, error) {
+	return r.listLinkTypes(ctx, func() ([]WorkItemLinkType, error) {
+		var rows []WorkItemLinkType
+		db := r.db.Where("space_id = ? AND deprecated_at IS NULL", spaceID).Order("display_order, name").Find(&rows)
+		if db.Error != nil {
+			return nil, errs.WithStack(db.Error)
+		}
+		return rows, nil
+	})
+}
+
+// CountLinks returns the number of work item links that use the link type
+// with the given id.
+// returns InternalError
+func (r *GormWorkItemLinkTypeRepository) CountLinks(ctx context.Context, linkTypeID satoriuuid.UUID) (int, error) {
+	var count int
+	db := r.db.Model(&WorkItemLink{}).Where("link_type_id = ?", linkTypeID).Count(&count)
+	if db.Error != nil {
+		return 0, errors.NewInternalError(db.Error.Error())
+	}
+	return count, nil
+}
+
+// CanDelete returns true if the link type with the given id is not used by
+// any work item link and can therefore be deleted safely.
+// returns InternalError
+func (r *GormWorkItemLinkTypeRepository) CanDelete(ctx context.Context, linkTypeID satoriuuid.UUID) (bool, error) {
+	count, err := r.CountLinks(ctx, linkTypeID)
+	if err != nil {
+		return false, errs.WithStack(err)
+	}
+	return count == 0, nil
+}
+
+// ListApplicable returns the non-deprecated link types of the given space
+// that permit linking a work item of the given source type to a work item of
+// the given target type. A link type is applicable if the source type is its
+// source type or a subtype of it and the same holds for the target type. As
+// links of the network topology are undirected, these link types are also
+// applicable if source and target type match the other way around.
 // returns NotFoundError or InternalError
+func (r *GormWorkItemLinkTypeRepository) ListApplicable(ctx context.Context, spaceID, sourceTypeID, targetTypeID satoriuuid.UUID, witRepo workitem.WorkItemTypeLoader) ([]WorkItemLinkType, error) {
+	sourceType, err := witRepo.LoadTypeFromDB(ctx, sourceTypeID)
+	if err != nil {
+		return nil, errs.WithStack(err)
+	}
+	targetType, err := witRepo.LoadTypeFromDB(ctx, targetTypeID)
+	if err != nil {
+		return nil, errs.WithStack(err)
+	}
+	var rows []WorkItemLinkType
+	db := r.db.Where("space_id = ? AND deprecated_at IS NULL", spaceID).Order("display_order, name").Find(&rows)
+	if db.Error != nil {
+		return nil, errors.NewInternalError(db.Error.Error())
+	}
+	res := []WorkItemLinkType{}
+	for _, lt := range rows {
+		forward := sourceType.IsTypeOrSubtypeOf(lt.SourceTypeID) && targetType.IsTypeOrSubtypeOf(lt.TargetTypeID)
+		reverse := lt.Topology == TopologyNetwork && sourceType.IsTypeOrSubtypeOf(lt.TargetTypeID) && targetType.IsTypeOrSubtypeOf(lt.SourceTypeID)
+		if forward || reverse {
+			res = append(res, lt)
+		}
+	}
+	return res, nil
+}
+
+// ListBySpace returns all link types of the given space, including
+// deprecated ones, ordered by display order and name.
+// returns InternalError
+func (r *GormWorkItemLinkTypeRepository) ListBySpace(ctx context.Context, spaceID satoriuuid.UUID) ([]WorkItemLinkType, error) {
+	res := []WorkItemLinkType{}
+	db := r.db.Where("space_id = ?", spaceID).Order("display_order, name").Find(&res)
+	if db.Error != nil {
+		return nil, errors.NewInternalError(db.Error.Error())
+	}
+	return res, nil
+}
+
+// ListByCategory returns the link types of the given space whose link
+// category is the given one, ordered by display order and name.
+// returns BadParameterError or InternalError
+func (r *GormWorkItemLinkTypeRepository) ListByCategory(ctx context.Context, spaceID, categoryID satoriuuid.UUID) ([]WorkItemLinkType, error) {
+	if satoriuuid.Equal(categoryID, satoriuuid.Nil) {
+		return nil, errors.NewBadParameterError("categoryID", categoryID).Expected("not nil")
+	}
+	res := []WorkItemLinkType{}
+	db := r.db.Where("space_id = ? AND link_category_id = ?", spaceID, categoryID).Order("display_order, name").Find(&res)
+	if db.Error != nil {
+		return nil, errors.NewInternalError(db.Error.Error())
+	}
+	return res, nil
+}
+
+// Reorder assigns the display orders 1, 2, 3, ... to the link types of the
+// given space in the order of the given IDs. The IDs must contain every link
+// type of the space exactly once. All display orders are updated in one
+// transaction and the version of every link type is incremented.
+// returns BadParameterError or InternalError
+func (r *GormWorkItemLinkTypeRepository) Reorder(ctx context.Context, spaceID satoriuuid.UUID, orderedIDs []satoriuuid.UUID) error {
+	return models.Transactional(r.db, func(tx *gorm.DB) error {
+		var rows []WorkItemLinkType
+		if err := tx.Where("space_id = ?", spaceID).Find(&rows).Error; err != nil {
+			return errors.NewInternalError(err.Error())
+		}
+		existing := map[satoriuuid.UUID]bool{}
+		for _, lt := range rows {
+			existing[lt.ID] = true
+		}
+		seen := map[satoriuuid.UUID]bool{}
+		for _, id := range orderedIDs {
+			if !existing[id] || seen[id] {
+				return errors.NewBadParameterError("ordered_ids", id).Expected("each link type of the space exactly once")
+			}
+			seen[id] = true
+		}
+		if len(seen) != len(existing) {
+			return errors.NewBadParameterError("ordered_ids", len(orderedIDs)).Expected(fmt.Sprintf("all %d link types of the space", len(existing)))
+		}
+		for _, lt := range rows {
+			if err := createVersion(tx, lt); err != nil {
+				return errs.WithStack(err)
+			}
+		}
+		for i, id := range orderedIDs {
+			db := tx.Model(&WorkItemLinkType{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+				"display_order": i + 1,
+				"version":       gorm.Expr("version + 1"),
+			})
+			if db.Error != nil {
+				return errors.NewInternalError(db.Error.Error())
+			}
+		}
+		log.Info(ctx, map[string]interface{}{
+			"spaceID": spaceID,
+		}, "Work item link types reordered")
+		return nil
+	})
+}
+
+// semanticKey identifies the semantics of a work item link type regardless
+// of its name
+type semanticKey struct {
+	sourceTypeID   satoriuuid.UUID
+	targetTypeID   satoriuuid.UUID
+	topology       string
+	linkCategoryID satoriuuid.UUID
+}
+
+// FindSemanticDuplicates groups the link types of the given space that share
+// source type, target type, topology and link category. Only groups with more
+// than one member are returned. Groups are ordered by the creation time of
+// their oldest member and the IDs inside a group by creation time.
+// returns InternalError
+func (r *GormWorkItemLinkTypeRepository) FindSemanticDuplicates(ctx context.Context, spaceID satoriuuid.UUID) ([][]satoriuuid.UUID, error) {
+	var rows []WorkItemLinkType
+	db := r.db.Where("space_id = ?", spaceID).Order("created_at, id").Find(&rows)
+	if db.Error != nil {
+		return nil, errors.NewInternalError(db.Error.Error())
+	}
+	var keys []semanticKey
+	groups := map[semanticKey][]satoriuuid.UUID{}
+	for _, lt := range rows {
+		key := semanticKey{lt.SourceTypeID, lt.TargetTypeID, lt.Topology, lt.LinkCategoryID}
+		if _, exists := groups[key]; !exists {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], lt.ID)
+	}
+	res := [][]satoriuuid.UUID{}
+	for _, key := range keys {
+		if len(groups[key]) > 1 {
+			res = append(res, groups[key])
+		}
+	}
+	return res, nil
+}
+
+// Delete deletes the work item link type with the given id
+// returns NotFoundError, InUseError (if the link type is still in use by
+// work item links) or InternalError
 func (r *GormWorkItemLinkTypeRepository) Delete(ctx context.Context, ID satoriuuid.UUID) error {
 	var cat = WorkItemLinkType{
 		ID: ID,
@@ -187,6 +782,14 @@ func (r *GormWorkItemLinkTypeRepository) Delete(ctx context.Context, ID satoriuu
 		"wiltID": ID,
 	}, "Work item link type to delete %v", cat)
 
+	count, err := r.CountLinks(ctx, ID)
+	if err != nil {
+		return errs.WithStack(err)
+	}
+	if count > 0 {
+		return errors.NewInUseError("work item link type", ID.String(), count, "work item links")
+	}
+
 	db := r.db.Delete(&cat)
 	if db.Error != nil {
 		return errors.NewInternalError(db.Error.Error())
@@ -197,8 +800,86 @@ func (r *GormWorkItemLinkTypeRepository) Delete(ctx context.Context, ID satoriuu
 	return nil
 }
 
+// Restore undoes the soft-deletion of the work item link type with the given
+// ID. As the link type may have become invalid while it was deleted, it is
+// checked like a newly created link type, including the existence of its
+// source and target work item types. The version is incremented and the
+// restored link type is returned.
+// returns NotFoundError, BadParameterError (if the link type is not deleted
+// or no longer valid), ReferencedEntityNotFoundError or InternalError
+func (r *GormWorkItemLinkTypeRepository) Restore(ctx context.Context, ID satoriuuid.UUID) (*WorkItemLinkType, error) {
+	res, err := r.LoadIncludingDeleted(ctx, ID)
+	if err != nil {
+		return nil, errs.WithStack(err)
+	}
+	if res.DeletedAt == nil {
+		return nil, errors.NewBadParameterError("work item link type", ID.String()).Expected("deleted work item link type")
+	}
+	// Clients can't create link types in the system space, so a stored link
+	// type of the system space has been created by the system itself.
+	res.System = satoriuuid.Equal(res.SpaceID, ReservedSpaceID)
+	if err := res.CheckValidForCreation(); err != nil {
+		return nil, errs.WithStack(err)
+	}
+	typeIDs := []satoriuuid.UUID{res.SourceTypeID, res.TargetTypeID}
+	if err := CheckTypeIDsExist(ctx, typeIDs, res.SpaceID, workitem.NewWorkItemTypeRepository(r.db)); err != nil {
+		return nil, errs.WithStack(err)
+	}
+	if err := CheckCategoryInSpace(ctx, res.LinkCategoryID, res.SpaceID, NewWorkItemLinkCategoryRepository(r.db)); err != nil {
+		return nil, errs.WithStack(err)
+	}
+	// another link type may have become the default of the space meanwhile
+	if err := checkSingleDefault(r.db, *res); err != nil {
+		return nil, errs.WithStack(err)
+	}
+	previous := *res
+	res.DeletedAt = nil
+	res.Version = res.Version + 1
+	err = models.Transactional(r.db, func(tx *gorm.DB) error {
+		if err := createVersion(tx, previous); err != nil {
+			return errs.WithStack(err)
+		}
+		if err := tx.Unscoped().Save(res).Error; err != nil {
+			return errors.NewInternalError(err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errs.WithStack(err)
+	}
+	log.Info(ctx, map[string]interface{}{
+		"wiltID": ID,
+	}, "Work item link type restored")
+	return res, nil
+}
+
+// LoadVersion returns the work item link type with the given ID as it was
+// at the given version. The current version is loaded from the link type
+// itself (even if it has been deleted), all prior versions from the versions
+// that were stored whenever the version was incremented.
+// returns NotFoundError or InternalError
+func (r *GormWorkItemLinkTypeRepository) LoadVersion(ctx context.Context, ID satoriuuid.UUID, version int) (*WorkItemLinkType, error) {
+	current, err := r.LoadIncludingDeleted(ctx, ID)
+	if err != nil {
+		return nil, errs.WithStack(err)
+	}
+	if current.Version == version {
+		return current, nil
+	}
+	v := WorkItemLinkTypeVersion{}
+	db := r.db.Where("link_type_id = ? AND version = ?", ID, version).First(&v)
+	if db.RecordNotFound() {
+		return nil, errors.NewNotFoundError("work item link type version", fmt.Sprintf("%s@%d", ID, version))
+	}
+	if db.Error != nil {
+		return nil, errors.NewInternalError(db.Error.Error())
+	}
+	res := WorkItemLinkType(v.LinkType)
+	return &res, nil
+}
+
 // Save updates the given work item link type in storage. Version must be the same as the one int the stored version.
-// returns NotFoundError, VersionConflictError, ConversionError or InternalError
+// returns NotFoundError, VersionConflictError, ConversionError, BadParameterError (if the link type would become a second default of its space) or InternalError
 func (r *GormWorkItemLinkTypeRepository) Save(ctx context.Context, lt app.WorkItemLinkTypeSingle) (*app.WorkItemLinkTypeSingle, error) {
 	res := WorkItemLinkType{}
 	if lt.Data.ID == nil {
@@ -221,18 +902,36 @@ func (r *GormWorkItemLinkTypeRepository) Save(ctx context.Context, lt app.WorkIt
 	if lt.Data.Attributes.Version == nil || res.Version != *lt.Data.Attributes.Version {
 		return nil, errors.NewVersionConflictError("version conflict")
 	}
+	previous := res
 	if err := ConvertLinkTypeToModel(lt, &res); err != nil {
 		return nil, errs.WithStack(err)
 	}
+	if err := res.checkValidMaxCounts(); err != nil {
+		return nil, errs.WithStack(err)
+	}
+	if err := CheckCategoryInSpace(ctx, res.LinkCategoryID, res.SpaceID, NewWorkItemLinkCategoryRepository(r.db)); err != nil {
+		return nil, errs.WithStack(err)
+	}
+	if err := checkSingleDefault(r.db, res); err != nil {
+		return nil, errs.WithStack(err)
+	}
 	res.Version = res.Version + 1
-	db = db.Save(&res)
-	if db.Error != nil {
-		log.Error(ctx, map[string]interface{}{
-			"wiltID": res.ID,
-			"wilt":   res,
-			"err":    db.Error,
-		}, "unable to save work item link type repository")
-		return nil, errors.NewInternalError(db.Error.Error())
+	err := models.Transactional(r.db, func(tx *gorm.DB) error {
+		if err := createVersion(tx, previous); err != nil {
+			return errs.WithStack(err)
+		}
+		if err := tx.Save(&res).Error; err != nil {
+			log.Error(ctx, map[string]interface{}{
+				"wiltID": res.ID,
+				"wilt":   res,
+				"err":    err,
+			}, "unable to save work item link type repository")
+			return errors.NewInternalError(err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errs.WithStack(err)
 	}
 	log.Info(ctx, map[string]interface{}{
 		"wiltID": res.ID,
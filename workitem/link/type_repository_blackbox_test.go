@@ -0,0 +1,797 @@
+package link_test
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/almighty/almighty-core/app"
+	"github.com/almighty/almighty-core/errors"
+	"github.com/almighty/almighty-core/gormsupport"
+	"github.com/almighty/almighty-core/gormsupport/cleaner"
+	"github.com/almighty/almighty-core/migration"
+	"github.com/almighty/almighty-core/models"
+	"github.com/almighty/almighty-core/resource"
+	"github.com/almighty/almighty-core/space"
+	testsupport "github.com/almighty/almighty-core/test"
+	"github.com/almighty/almighty-core/workitem"
+	"github.com/almighty/almighty-core/workitem/link"
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+	satoriuuid "github.com/satori/go.uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type workItemLinkTypeRepoBlackBoxTest struct {
+	gormsupport.DBTestSuite
+	clean        func()
+	repo         *link.GormWorkItemLinkTypeRepository
+	categoryRepo *link.GormWorkItemLinkCategoryRepository
+	creatorID    satoriuuid.UUID
+	spaceID      satoriuuid.UUID
+}
+
+func TestRunWorkItemLinkTypeRepoBlackBoxTest(t *testing.T) {
+	suite.Run(t, &workItemLinkTypeRepoBlackBoxTest{DBTestSuite: gormsupport.NewDBTestSuite("../../config.yaml")})
+}
+
+// SetupSuite overrides the DBTestSuite's function but calls it before doing anything else
+// The SetupSuite method will run before the tests in the suite are run.
+// It sets up a database connection for all the tests in this suite without polluting global space.
+func (s *workItemLinkTypeRepoBlackBoxTest) SetupSuite() {
+	s.DBTestSuite.SetupSuite()
+
+	// Make sure the database is populated with the correct types (e.g. bug etc.)
+	if _, c := os.LookupEnv(resource.Database); c != false {
+		if err := models.Transactional(s.DB, func(tx *gorm.DB) error {
+			return migration.PopulateCommonTypes(context.Background(), tx, workitem.NewWorkItemTypeRepository(tx))
+		}); err != nil {
+			panic(err.Error())
+		}
+	}
+}
+
+func (s *workItemLinkTypeRepoBlackBoxTest) SetupTest() {
+	s.clean = cleaner.DeleteCreatedEntities(s.DB)
+	s.repo = link.NewWorkItemLinkTypeRepository(s.DB)
+	s.categoryRepo = link.NewWorkItemLinkCategoryRepository(s.DB)
+	testIdentity, err := testsupport.CreateTestIdentity(s.DB, "jdoe", "test")
+	require.Nil(s.T(), err)
+	s.creatorID = testIdentity.ID
+	sp, err := space.NewRepository(s.DB).Create(context.Background(), &space.Space{Name: satoriuuid.NewV4().String()})
+	require.Nil(s.T(), err)
+	s.spaceID = sp.ID
+}
+
+func (s *workItemLinkTypeRepoBlackBoxTest) TearDownTest() {
+	s.clean()
+}
+
+// createLinkCategory creates a work item link category with the given name
+func (s *workItemLinkTypeRepoBlackBoxTest) createLinkCategory(name string) satoriuuid.UUID {
+	cat, err := s.categoryRepo.Create(context.Background(), &name, nil)
+	require.Nil(s.T(), err)
+	require.NotNil(s.T(), cat.Data.ID)
+	return *cat.Data.ID
+}
+
+// createLinkType creates a work item link type in the test space
+func (s *workItemLinkTypeRepoBlackBoxTest) createLinkType(name, forwardName, reverseName, topology string, sourceTypeID, targetTypeID, categoryID satoriuuid.UUID) satoriuuid.UUID {
+	lt, err := s.repo.Create(context.Background(), name, nil, sourceTypeID, targetTypeID, forwardName, reverseName, topology, categoryID, s.spaceID)
+	require.Nil(s.T(), err)
+	require.NotNil(s.T(), lt.Data.ID)
+	return *lt.Data.ID
+}
+
+func (s *workItemLinkTypeRepoBlackBoxTest) TestLoadByDirectionalName() {
+	categoryID := s.createLinkCategory("test-directional-name-category")
+	linkTypeID := s.createLinkType("test-directional-name", "test-blocks", "test-blocked by", link.TopologyDependency, workitem.SystemBug, workitem.SystemBug, categoryID)
+
+	// Test match on forward name
+	lt, isReverse, err := s.repo.LoadByDirectionalName(context.Background(), s.spaceID, "TEST-Blocks")
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), linkTypeID, lt.ID)
+	require.False(s.T(), isReverse)
+
+	// Test match on reverse name
+	lt, isReverse, err = s.repo.LoadByDirectionalName(context.Background(), s.spaceID, "test-blocked by")
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), linkTypeID, lt.ID)
+	require.True(s.T(), isReverse)
+
+	// Test no match
+	_, _, err = s.repo.LoadByDirectionalName(context.Background(), s.spaceID, "test-unknown")
+	require.NotNil(s.T(), err)
+	require.IsType(s.T(), errors.NotFoundError{}, errs.Cause(err))
+}
+
+// linkTypeIDs returns the IDs of all link types in the given list
+func linkTypeIDs(list *app.WorkItemLinkTypeList) []satoriuuid.UUID {
+	ids := make([]satoriuuid.UUID, len(list.Data))
+	for i, lt := range list.Data {
+		ids[i] = *lt.ID
+	}
+	return ids
+}
+
+func (s *workItemLinkTypeRepoBlackBoxTest) TestListActive() {
+	categoryID := s.createLinkCategory("test-list-active-category")
+	activeID := s.createLinkType("test-list-active", "test-active-fwd", "test-active-rev", link.TopologyNetwork, workitem.SystemBug, workitem.SystemBug, categoryID)
+
+	// Test without deprecated link types
+	active, err := s.repo.ListActive(context.Background(), s.spaceID)
+	require.Nil(s.T(), err)
+	require.Contains(s.T(), linkTypeIDs(active), activeID)
+
+	// Test with a deprecated link type
+	deprecatedID := s.createLinkType("test-list-deprecated", "test-deprecated-fwd", "test-deprecated-rev", link.TopologyNetwork, workitem.SystemBug, workitem.SystemBug, categoryID)
+	db := s.DB.Model(&link.WorkItemLinkType{}).Where("id = ?", deprecatedID).Update("deprecated_at", time.Now())
+	require.Nil(s.T(), db.Error)
+	active, err = s.repo.ListActive(context.Background(), s.spaceID)
+	require.Nil(s.T(), err)
+	require.Contains(s.T(), linkTypeIDs(active), activeID)
+	require.NotContains(s.T(), linkTypeIDs(active), deprecatedID)
+
+	// Test that List still returns deprecated link types
+	all, err := s.repo.List(context.Background())
+	require.Nil(s.T(), err)
+	require.Contains(s.T(), linkTypeIDs(all), activeID)
+	require.Contains(s.T(), linkTypeIDs(all), deprecatedID)
+}
+
+// createWorkItem creates a bug work item and returns its ID
+func (s *workItemLinkTypeRepoBlackBoxTest) createWorkItem(title string) uint64 {
+	wi, err := workitem.NewWorkItemRepository(s.DB).Create(
+		context.Background(), workitem.SystemBug,
+		map[string]interface{}{
+			workitem.SystemTitle: title,
+			workitem.SystemState: workitem.SystemStateNew,
+		}, s.creatorID)
+	require.Nil(s.T(), err)
+	id, err := strconv.ParseUint(wi.ID, 10, 64)
+	require.Nil(s.T(), err)
+	return id
+}
+
+func (s *workItemLinkTypeRepoBlackBoxTest) TestCanDelete() {
+	categoryID := s.createLinkCategory("test-can-delete-category")
+
+	// Test link type without links
+	unusedID := s.createLinkType("test-can-delete-unused", "test-unused-fwd", "test-unused-rev", link.TopologyNetwork, workitem.SystemBug, workitem.SystemBug, categoryID)
+	count, err := s.repo.CountLinks(context.Background(), unusedID)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), 0, count)
+	canDelete, err := s.repo.CanDelete(context.Background(), unusedID)
+	require.Nil(s.T(), err)
+	require.True(s.T(), canDelete)
+	require.Nil(s.T(), s.repo.Delete(context.Background(), unusedID))
+
+	// Test link type with links
+	usedID := s.createLinkType("test-can-delete-used", "test-used-fwd", "test-used-rev", link.TopologyNetwork, workitem.SystemBug, workitem.SystemBug, categoryID)
+	sourceID := s.createWorkItem("test-can-delete-source")
+	targetID := s.createWorkItem("test-can-delete-target")
+	_, err = link.NewWorkItemLinkRepository(s.DB).Create(context.Background(), sourceID, targetID, usedID)
+	require.Nil(s.T(), err)
+	count, err = s.repo.CountLinks(context.Background(), usedID)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), 1, count)
+	canDelete, err = s.repo.CanDelete(context.Background(), usedID)
+	require.Nil(s.T(), err)
+	require.False(s.T(), canDelete)
+	err = s.repo.Delete(context.Background(), usedID)
+	require.IsType(s.T(), errors.InUseError{}, errs.Cause(err))
+	require.Equal(s.T(), 1, errs.Cause(err).(errors.InUseError).Count)
+}
+
+// linkTypeNames returns the names of the given link types
+func linkTypeNames(linkTypes []link.WorkItemLinkType) []string {
+	names := make([]string, len(linkTypes))
+	for i, lt := range linkTypes {
+		names[i] = lt.Name
+	}
+	return names
+}
+
+func (s *workItemLinkTypeRepoBlackBoxTest) TestListApplicable() {
+	ctx := context.Background()
+	witRepo := workitem.NewWorkItemTypeRepository(s.DB)
+	stringType := app.FieldType{Kind: "string"}
+	fields := map[string]app.FieldDefinition{
+		workitem.SystemTitle: {Required: true, Type: &stringType},
+		workitem.SystemState: {Required: true, Type: &stringType},
+	}
+	createType := func(name string, extendedTypeID *satoriuuid.UUID) satoriuuid.UUID {
+		id := satoriuuid.NewV4()
+		_, err := witRepo.Create(ctx, &id, extendedTypeID, name, nil, "fa-question", fields)
+		require.Nil(s.T(), err)
+		return id
+	}
+	parentID := createType("test-applicable-parent", nil)
+	childID := createType("test-applicable-child", &parentID)
+	otherID := createType("test-applicable-other", nil)
+	categoryID := s.createLinkCategory("test-applicable-category")
+	s.createLinkType("test-applicable-dependency", "test-dep-fwd", "test-dep-rev", link.TopologyDependency, parentID, otherID, categoryID)
+	s.createLinkType("test-applicable-network", "test-net-fwd", "test-net-rev", link.TopologyNetwork, otherID, parentID, categoryID)
+
+	// Test subtype acceptance and symmetric matching of the network type
+	applicable, err := s.repo.ListApplicable(ctx, s.spaceID, childID, otherID, witRepo)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []string{"test-applicable-dependency", "test-applicable-network"}, linkTypeNames(applicable))
+
+	// Test that the dependency type is not applicable in reverse
+	applicable, err = s.repo.ListApplicable(ctx, s.spaceID, otherID, childID, witRepo)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []string{"test-applicable-network"}, linkTypeNames(applicable))
+
+	// Test types that no link type connects
+	applicable, err = s.repo.ListApplicable(ctx, s.spaceID, otherID, otherID, witRepo)
+	require.Nil(s.T(), err)
+	require.Empty(s.T(), applicable)
+}
+
+func (s *workItemLinkTypeRepoBlackBoxTest) TestListByCategory() {
+	ctx := context.Background()
+	sp, err := space.NewRepository(s.DB).Create(ctx, &space.Space{
+		Name: satoriuuid.NewV4().String(),
+	})
+	require.Nil(s.T(), err)
+	categoryID := s.createLinkCategory("test-by-category")
+	otherCategoryID := s.createLinkCategory("test-by-category-other")
+	createLinkType := func(name string, categoryID, spaceID satoriuuid.UUID) {
+		_, err := s.repo.Create(ctx, name, nil, workitem.SystemBug, workitem.SystemBug, name+"-fwd", name+"-rev", link.TopologyNetwork, categoryID, spaceID)
+		require.Nil(s.T(), err)
+	}
+	createLinkType("test-by-category-b", categoryID, sp.ID)
+	createLinkType("test-by-category-a", categoryID, sp.ID)
+	createLinkType("test-by-category-other", otherCategoryID, sp.ID)
+	createLinkType("test-by-category-other-space", categoryID, s.spaceID)
+
+	// Test filtering by category within the space, ordered by name
+	linkTypes, err := s.repo.ListByCategory(ctx, sp.ID, categoryID)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []string{"test-by-category-a", "test-by-category-b"}, linkTypeNames(linkTypes))
+	linkTypes, err = s.repo.ListByCategory(ctx, sp.ID, otherCategoryID)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []string{"test-by-category-other"}, linkTypeNames(linkTypes))
+
+	// Test category without link types in the space
+	linkTypes, err = s.repo.ListByCategory(ctx, sp.ID, s.createLinkCategory("test-by-category-empty"))
+	require.Nil(s.T(), err)
+	require.Empty(s.T(), linkTypes)
+
+	// Test nil category
+	_, err = s.repo.ListByCategory(ctx, sp.ID, satoriuuid.Nil)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+}
+
+func (s *workItemLinkTypeRepoBlackBoxTest) TestReorder() {
+	ctx := context.Background()
+	sp, err := space.NewRepository(s.DB).Create(ctx, &space.Space{
+		Name: satoriuuid.NewV4().String(),
+	})
+	require.Nil(s.T(), err)
+	categoryID := s.createLinkCategory("test-reorder")
+	createLinkType := func(name string) satoriuuid.UUID {
+		lt, err := s.repo.Create(ctx, name, nil, workitem.SystemBug, workitem.SystemPlannerItem, name+"-fwd", name+"-rev", link.TopologyNetwork, categoryID, sp.ID)
+		require.Nil(s.T(), err)
+		return *lt.Data.ID
+	}
+	a := createLinkType("test-reorder-a")
+	b := createLinkType("test-reorder-b")
+	c := createLinkType("test-reorder-c")
+
+	// Test valid reorder
+	require.Nil(s.T(), s.repo.Reorder(ctx, sp.ID, []satoriuuid.UUID{c, a, b}))
+	linkTypes, err := s.repo.ListBySpace(ctx, sp.ID)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []string{"test-reorder-c", "test-reorder-a", "test-reorder-b"}, linkTypeNames(linkTypes))
+	require.Equal(s.T(), 1, linkTypes[0].DisplayOrder)
+	require.Equal(s.T(), 1, linkTypes[0].Version)
+
+	// Test missing ID
+	err = s.repo.Reorder(ctx, sp.ID, []satoriuuid.UUID{a, b})
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+
+	// Test unknown and duplicate IDs
+	err = s.repo.Reorder(ctx, sp.ID, []satoriuuid.UUID{a, b, satoriuuid.NewV4()})
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+	err = s.repo.Reorder(ctx, sp.ID, []satoriuuid.UUID{a, b, b})
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+
+	// Test the order is unchanged after the failed reorders
+	linkTypes, err = s.repo.ListBySpace(ctx, sp.ID)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []string{"test-reorder-c", "test-reorder-a", "test-reorder-b"}, linkTypeNames(linkTypes))
+}
+
+func (s *workItemLinkTypeRepoBlackBoxTest) TestValidateSpaceLinkTypes() {
+	ctx := context.Background()
+	sp, err := space.NewRepository(s.DB).Create(ctx, &space.Space{
+		Name: satoriuuid.NewV4().String(),
+	})
+	require.Nil(s.T(), err)
+	witRepo := workitem.NewWorkItemTypeRepository(s.DB)
+	categoryID := s.createLinkCategory("test-validate-space")
+	createLinkType := func(name, topology string, sourceTypeID, targetTypeID, categoryID satoriuuid.UUID) {
+		_, err := s.repo.Create(ctx, name, nil, sourceTypeID, targetTypeID, name+"-fwd", name+"-rev", topology, categoryID, sp.ID)
+		require.Nil(s.T(), err)
+	}
+	createLinkType("test-validate-clean", link.TopologyNetwork, workitem.SystemBug, workitem.SystemPlannerItem, categoryID)
+
+	// Test clean space
+	require.Empty(s.T(), link.ValidateSpaceLinkTypes(ctx, sp.ID, s.repo, witRepo, s.categoryRepo))
+
+	// Seed a link type whose target type gets deleted
+	orphanTypeID := satoriuuid.NewV4()
+	_, err = witRepo.Create(ctx, &orphanTypeID, &workitem.SystemBug, "test-validate-orphan", nil, "fa-bug", nil)
+	require.Nil(s.T(), err)
+	createLinkType("test-validate-orphan-type", link.TopologyDependency, workitem.SystemBug, orphanTypeID, categoryID)
+	require.Nil(s.T(), s.DB.Delete(&workitem.WorkItemType{ID: orphanTypeID}).Error)
+	workitem.ClearGlobalWorkItemTypeCache()
+
+	// Seed a link type whose category gets deleted
+	orphanCategoryID := s.createLinkCategory("test-validate-space-orphan")
+	createLinkType("test-validate-orphan-category", link.TopologyDirectedNetwork, workitem.SystemBug, workitem.SystemBug, orphanCategoryID)
+	require.Nil(s.T(), s.DB.Delete(&link.WorkItemLinkCategory{ID: orphanCategoryID}).Error)
+
+	// Seed a link type with a reserved name, bypassing the creation checks
+	reserved := link.WorkItemLinkType{
+		ID:             satoriuuid.NewV4(),
+		Name:           link.SystemWorkItemLinkTypeBugBlocker,
+		Topology:       link.TopologyNetwork,
+		SourceTypeID:   workitem.SystemPlannerItem,
+		TargetTypeID:   workitem.SystemBug,
+		ForwardName:    "test-reserved-fwd",
+		ReverseName:    "test-reserved-rev",
+		LinkCategoryID: categoryID,
+		SpaceID:        sp.ID,
+	}
+	require.Nil(s.T(), s.DB.Create(&reserved).Error)
+
+	// Seed a semantic duplicate of the clean link type
+	createLinkType("test-validate-duplicate", link.TopologyNetwork, workitem.SystemBug, workitem.SystemPlannerItem, categoryID)
+
+	// Test that every violation is reported
+	problems := link.ValidateSpaceLinkTypes(ctx, sp.ID, s.repo, witRepo, s.categoryRepo)
+	require.Len(s.T(), problems, 4)
+	for _, problem := range problems {
+		require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(problem))
+	}
+	require.Contains(s.T(), problems[0].Error(), "name")
+	require.Contains(s.T(), problems[1].Error(), "link_category_id")
+	require.Contains(s.T(), problems[2].Error(), "target_type_id")
+	require.Contains(s.T(), problems[3].Error(), "work item link types")
+}
+
+func (s *workItemLinkTypeRepoBlackBoxTest) TestLoadIncludingDeleted() {
+	ctx := context.Background()
+	sp, err := space.NewRepository(s.DB).Create(ctx, &space.Space{
+		Name: satoriuuid.NewV4().String(),
+	})
+	require.Nil(s.T(), err)
+	categoryID := s.createLinkCategory("test-including-deleted")
+	createLinkType := func(name string) satoriuuid.UUID {
+		lt, err := s.repo.Create(ctx, name, nil, workitem.SystemBug, workitem.SystemBug, name+"-fwd", name+"-rev", link.TopologyNetwork, categoryID, sp.ID)
+		require.Nil(s.T(), err)
+		return *lt.Data.ID
+	}
+	activeID := createLinkType("test-including-deleted-active")
+	deletedID := createLinkType("test-including-deleted-deleted")
+	require.Nil(s.T(), s.repo.Delete(ctx, deletedID))
+
+	// Test the deleted link type is hidden by the normal lookups
+	_, err = s.repo.Load(ctx, deletedID)
+	require.IsType(s.T(), errors.NotFoundError{}, errs.Cause(err))
+	linkTypes, err := s.repo.ListBySpace(ctx, sp.ID)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []string{"test-including-deleted-active"}, linkTypeNames(linkTypes))
+
+	// Test the deleted link type is returned including its deletion time
+	lt, err := s.repo.LoadIncludingDeleted(ctx, deletedID)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), deletedID, lt.ID)
+	require.NotNil(s.T(), lt.DeletedAt)
+	lt, err = s.repo.LoadIncludingDeleted(ctx, activeID)
+	require.Nil(s.T(), err)
+	require.Nil(s.T(), lt.DeletedAt)
+	linkTypes, err = s.repo.ListIncludingDeleted(ctx, sp.ID)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), []string{"test-including-deleted-active", "test-including-deleted-deleted"}, linkTypeNames(linkTypes))
+
+	// Test unknown link type
+	_, err = s.repo.LoadIncludingDeleted(ctx, satoriuuid.NewV4())
+	require.IsType(s.T(), errors.NotFoundError{}, errs.Cause(err))
+}
+
+func (s *workItemLinkTypeRepoBlackBoxTest) TestRestore() {
+	ctx := context.Background()
+	categoryID := s.createLinkCategory("test-restore-category")
+	linkTypeID := s.createLinkType("test-restore", "test-restore-fwd", "test-restore-rev", link.TopologyNetwork, workitem.SystemBug, workitem.SystemBug, categoryID)
+
+	// Test restoring an active link type is rejected
+	_, err := s.repo.Restore(ctx, linkTypeID)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+
+	// Test restoring a deleted link type
+	before, err := s.repo.LoadTypeFromDBByID(ctx, linkTypeID)
+	require.Nil(s.T(), err)
+	require.Nil(s.T(), s.repo.Delete(ctx, linkTypeID))
+	restored, err := s.repo.Restore(ctx, linkTypeID)
+	require.Nil(s.T(), err)
+	require.Nil(s.T(), restored.DeletedAt)
+	require.Equal(s.T(), before.Version+1, restored.Version)
+	loaded, err := s.repo.LoadTypeFromDBByID(ctx, linkTypeID)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), "test-restore", loaded.Name)
+	require.Equal(s.T(), restored.Version, loaded.Version)
+
+	// Test unknown link type
+	_, err = s.repo.Restore(ctx, satoriuuid.NewV4())
+	require.IsType(s.T(), errors.NotFoundError{}, errs.Cause(err))
+}
+
+func (s *workItemLinkTypeRepoBlackBoxTest) TestFindSemanticDuplicates() {
+	ctx := context.Background()
+	sp, err := space.NewRepository(s.DB).Create(ctx, &space.Space{
+		Name: satoriuuid.NewV4().String(),
+	})
+	require.Nil(s.T(), err)
+	categoryID := s.createLinkCategory("test-duplicates-category")
+	otherCategoryID := s.createLinkCategory("test-duplicates-other-category")
+	createLinkType := func(name, topology string, categoryID satoriuuid.UUID) satoriuuid.UUID {
+		lt, err := s.repo.Create(ctx, name, nil, workitem.SystemBug, workitem.SystemPlannerItem, name+"-fwd", name+"-rev", topology, categoryID, sp.ID)
+		require.Nil(s.T(), err)
+		return *lt.Data.ID
+	}
+
+	// Test distinct link types
+	createLinkType("test-duplicates-network", link.TopologyNetwork, categoryID)
+	createLinkType("test-duplicates-dependency", link.TopologyDependency, categoryID)
+	createLinkType("test-duplicates-other", link.TopologyNetwork, otherCategoryID)
+	duplicates, err := s.repo.FindSemanticDuplicates(ctx, sp.ID)
+	require.Nil(s.T(), err)
+	require.Empty(s.T(), duplicates)
+
+	// Test a duplicate pair
+	firstID := createLinkType("test-duplicates-first", link.TopologyTree, categoryID)
+	secondID := createLinkType("test-duplicates-second", link.TopologyTree, categoryID)
+	duplicates, err = s.repo.FindSemanticDuplicates(ctx, sp.ID)
+	require.Nil(s.T(), err)
+	require.Len(s.T(), duplicates, 1)
+	require.Equal(s.T(), []satoriuuid.UUID{firstID, secondID}, duplicates[0])
+}
+
+func (s *workItemLinkTypeRepoBlackBoxTest) TestCheckTypeIDsExist() {
+	ctx := context.Background()
+	witRepo := workitem.NewWorkItemTypeRepository(s.DB)
+
+	// Test all valid IDs
+	err := link.CheckTypeIDsExist(ctx, []satoriuuid.UUID{workitem.SystemBug, workitem.SystemPlannerItem, workitem.SystemBug}, space.SystemSpace, witRepo)
+	require.Nil(s.T(), err)
+
+	// Test a mix of valid and nonexistent IDs
+	unknownID := satoriuuid.NewV4()
+	err = link.CheckTypeIDsExist(ctx, []satoriuuid.UUID{workitem.SystemBug, unknownID}, space.SystemSpace, witRepo)
+	require.IsType(s.T(), errors.ReferencedEntityNotFoundError{}, errs.Cause(err))
+	require.Equal(s.T(), link.ReferencedKindWorkItemType, errs.Cause(err).(errors.ReferencedEntityNotFoundError).Kind)
+	require.Contains(s.T(), err.Error(), unknownID.String())
+	require.NotContains(s.T(), err.Error(), workitem.SystemBug.String())
+
+	// Test types of the same space and of another space
+	otherSpace, err := space.NewRepository(s.DB).Create(ctx, &space.Space{Name: satoriuuid.NewV4().String()})
+	require.Nil(s.T(), err)
+	createInSpace := func(name string, spaceID satoriuuid.UUID) satoriuuid.UUID {
+		id := satoriuuid.NewV4()
+		wit := workitem.WorkItemType{ID: id, Name: name, Path: workitem.LtreeSafeID(id), Fields: workitem.FieldDefinitions{}, SpaceID: spaceID}
+		require.Nil(s.T(), s.DB.Create(&wit).Error)
+		return id
+	}
+	matchingID := createInSpace("test-type-in-space-matching", s.spaceID)
+	mismatchedID := createInSpace("test-type-in-space-mismatched", otherSpace.ID)
+	err = link.CheckTypeIDsExist(ctx, []satoriuuid.UUID{workitem.SystemBug, matchingID}, s.spaceID, witRepo)
+	require.Nil(s.T(), err)
+	err = link.CheckTypeIDsExist(ctx, []satoriuuid.UUID{workitem.SystemBug, mismatchedID}, s.spaceID, witRepo)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+
+	// Test that missing types are reported before types of another space
+	err = link.CheckTypeIDsExist(ctx, []satoriuuid.UUID{mismatchedID, unknownID}, s.spaceID, witRepo)
+	require.IsType(s.T(), errors.ReferencedEntityNotFoundError{}, errs.Cause(err))
+
+	// Test creating a link type with a type of another space
+	categoryID := s.createLinkCategory("test-type-in-space-category")
+	_, err = s.repo.Create(ctx, "test-type-in-space-bad", nil, workitem.SystemBug, mismatchedID, "test-fwd", "test-rev", link.TopologyNetwork, categoryID, s.spaceID)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+}
+
+func (s *workItemLinkTypeRepoBlackBoxTest) TestCheckCategoryInSpace() {
+	ctx := context.Background()
+	otherSpace, err := space.NewRepository(s.DB).Create(ctx, &space.Space{Name: satoriuuid.NewV4().String()})
+	require.Nil(s.T(), err)
+	createInSpace := func(name string, spaceID satoriuuid.UUID) satoriuuid.UUID {
+		cat := link.WorkItemLinkCategory{Name: name, SpaceID: spaceID}
+		require.Nil(s.T(), s.DB.Create(&cat).Error)
+		return cat.ID
+	}
+	sharedID := s.createLinkCategory("test-category-in-space-shared")
+	matchingID := createInSpace("test-category-in-space-matching", s.spaceID)
+	mismatchedID := createInSpace("test-category-in-space-mismatched", otherSpace.ID)
+
+	// Test a category of the system space and one of the same space
+	require.Nil(s.T(), link.CheckCategoryInSpace(ctx, sharedID, s.spaceID, s.categoryRepo))
+	require.Nil(s.T(), link.CheckCategoryInSpace(ctx, matchingID, s.spaceID, s.categoryRepo))
+
+	// Test a category of another space
+	err = link.CheckCategoryInSpace(ctx, mismatchedID, s.spaceID, s.categoryRepo)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+
+	// Test a nonexistent category
+	err = link.CheckCategoryInSpace(ctx, satoriuuid.NewV4(), s.spaceID, s.categoryRepo)
+	require.IsType(s.T(), errors.ReferencedEntityNotFoundError{}, errs.Cause(err))
+
+	// Test creating link types with a matching and a mismatched category
+	_, err = s.repo.Create(ctx, "test-category-in-space-ok", nil, workitem.SystemBug, workitem.SystemBug, "test-fwd", "test-rev", link.TopologyNetwork, matchingID, s.spaceID)
+	require.Nil(s.T(), err)
+	_, err = s.repo.Create(ctx, "test-category-in-space-bad", nil, workitem.SystemBug, workitem.SystemBug, "test-fwd", "test-rev", link.TopologyNetwork, mismatchedID, s.spaceID)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+}
+
+func (s *workItemLinkTypeRepoBlackBoxTest) TestCreateWithMissingReference() {
+	ctx := context.Background()
+	categoryID := s.createLinkCategory("test-missing-reference-category")
+	unknownID := satoriuuid.NewV4()
+
+	// checkMissing asserts that err reports the given kind and ID as missing
+	checkMissing := func(err error, kind string, id satoriuuid.UUID) {
+		require.IsType(s.T(), errors.ReferencedEntityNotFoundError{}, errs.Cause(err))
+		notFound := errs.Cause(err).(errors.ReferencedEntityNotFoundError)
+		require.Equal(s.T(), kind, notFound.Kind)
+		require.Equal(s.T(), id.String(), notFound.ID)
+	}
+
+	// Test nonexistent target type
+	_, err := s.repo.Create(ctx, "test-missing-type", nil, workitem.SystemBug, unknownID, "test-fwd", "test-rev", link.TopologyNetwork, categoryID, s.spaceID)
+	checkMissing(err, link.ReferencedKindWorkItemType, unknownID)
+
+	// Test nonexistent link category
+	_, err = s.repo.Create(ctx, "test-missing-category", nil, workitem.SystemBug, workitem.SystemBug, "test-fwd", "test-rev", link.TopologyNetwork, unknownID, s.spaceID)
+	checkMissing(err, link.ReferencedKindLinkCategory, unknownID)
+
+	// Test nonexistent space
+	_, err = s.repo.Create(ctx, "test-missing-space", nil, workitem.SystemBug, workitem.SystemBug, "test-fwd", "test-rev", link.TopologyNetwork, categoryID, unknownID)
+	checkMissing(err, link.ReferencedKindSpace, unknownID)
+}
+
+func (s *workItemLinkTypeRepoBlackBoxTest) TestLoadSystemLinkType() {
+	ctx := context.Background()
+	require.Nil(s.T(), migration.BootstrapWorkItemLinking(ctx, s.categoryRepo, space.NewRepository(s.DB), s.repo))
+
+	// Test loading "Bug blocker"
+	lt, err := s.repo.LoadSystemLinkType(ctx, link.SystemWorkItemLinkTypeBugBlocker, space.SystemSpace)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), link.SystemWorkItemLinkTypeBugBlocker, lt.Name)
+	require.Equal(s.T(), workitem.SystemBug, lt.SourceTypeID)
+
+	// Test loading "Related planner item"
+	lt, err = s.repo.LoadSystemLinkType(ctx, link.SystemWorkItemLinkPlannerItemRelated, space.SystemSpace)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), link.SystemWorkItemLinkPlannerItemRelated, lt.Name)
+	require.Equal(s.T(), workitem.SystemPlannerItem, lt.SourceTypeID)
+
+	// Test rejecting an unknown name
+	_, err = s.repo.LoadSystemLinkType(ctx, "test-unknown", space.SystemSpace)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+
+	// Test a space without system link types
+	_, err = s.repo.LoadSystemLinkType(ctx, link.SystemWorkItemLinkTypeBugBlocker, satoriuuid.NewV4())
+	require.IsType(s.T(), errors.NotFoundError{}, errs.Cause(err))
+}
+
+func (s *workItemLinkTypeRepoBlackBoxTest) TestLoadVersion() {
+	ctx := context.Background()
+	categoryID := s.createLinkCategory("test-load-version-category")
+	linkTypeID := s.createLinkType("test-load-version", "test-fwd-0", "test-rev", link.TopologyDependency, workitem.SystemBug, workitem.SystemBug, categoryID)
+	update := func(forwardName string) {
+		lt, err := s.repo.Load(ctx, linkTypeID)
+		require.Nil(s.T(), err)
+		lt.Data.Attributes.ForwardName = &forwardName
+		_, err = s.repo.Save(ctx, *lt)
+		require.Nil(s.T(), err)
+	}
+	// given two updates
+	update("test-fwd-1")
+	update("test-fwd-2")
+
+	// Test the initial and the intermediate version
+	lt, err := s.repo.LoadVersion(ctx, linkTypeID, 0)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), 0, lt.Version)
+	require.Equal(s.T(), "test-fwd-0", lt.ForwardName)
+	lt, err = s.repo.LoadVersion(ctx, linkTypeID, 1)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), linkTypeID, lt.ID)
+	require.Equal(s.T(), 1, lt.Version)
+	require.Equal(s.T(), "test-fwd-1", lt.ForwardName)
+	require.Equal(s.T(), "test-rev", lt.ReverseName)
+
+	// Test the current version
+	lt, err = s.repo.LoadVersion(ctx, linkTypeID, 2)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), "test-fwd-2", lt.ForwardName)
+
+	// Test versions that don't exist
+	_, err = s.repo.LoadVersion(ctx, linkTypeID, 3)
+	require.IsType(s.T(), errors.NotFoundError{}, errs.Cause(err))
+	_, err = s.repo.LoadVersion(ctx, satoriuuid.NewV4(), 0)
+	require.IsType(s.T(), errors.NotFoundError{}, errs.Cause(err))
+}
+
+func (s *workItemLinkTypeRepoBlackBoxTest) TestSaveMaxCounts() {
+	ctx := context.Background()
+	categoryID := s.createLinkCategory("test-save-max-counts-category")
+	linkTypeID := s.createLinkType("test-save-max-counts", "test-fwd", "test-rev", link.TopologyDependency, workitem.SystemBug, workitem.SystemBug, categoryID)
+	save := func(maxSourceCount, maxTargetCount *int) error {
+		lt, err := s.repo.Load(ctx, linkTypeID)
+		require.Nil(s.T(), err)
+		lt.Data.Attributes.MaxSourceCount = maxSourceCount
+		lt.Data.Attributes.MaxTargetCount = maxTargetCount
+		_, err = s.repo.Save(ctx, *lt)
+		return err
+	}
+	zero := 0
+	one := 1
+
+	// Test valid limits
+	require.Nil(s.T(), save(&one, &one))
+
+	// Test limits below one
+	err := save(&zero, nil)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+	require.Contains(s.T(), err.Error(), "max_source_count")
+	err = save(nil, &zero)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+	require.Contains(s.T(), err.Error(), "max_target_count")
+}
+
+func (s *workItemLinkTypeRepoBlackBoxTest) TestCreateMany() {
+	ctx := context.Background()
+	categoryID := s.createLinkCategory("test-create-many-category")
+	newLinkType := func(name string) *link.WorkItemLinkType {
+		return &link.WorkItemLinkType{
+			Name:           name,
+			SourceTypeID:   workitem.SystemBug,
+			TargetTypeID:   workitem.SystemBug,
+			ForwardName:    "test-fwd",
+			ReverseName:    "test-rev",
+			Topology:       link.TopologyNetwork,
+			LinkCategoryID: categoryID,
+			SpaceID:        s.spaceID,
+		}
+	}
+
+	// Test all-valid batch
+	created, err := s.repo.CreateMany(ctx, []*link.WorkItemLinkType{newLinkType("test-many-a"), newLinkType("test-many-b")})
+	require.Nil(s.T(), err)
+	require.Len(s.T(), created, 2)
+	for _, lt := range created {
+		loaded, err := s.repo.LoadTypeFromDBByID(ctx, lt.ID)
+		require.Nil(s.T(), err)
+		require.Equal(s.T(), lt.Name, loaded.Name)
+	}
+
+	// Test batch with an invalid element in the middle
+	invalid := newLinkType("test-many-invalid")
+	invalid.Topology = "foo"
+	_, err = s.repo.CreateMany(ctx, []*link.WorkItemLinkType{newLinkType("test-many-c"), invalid, newLinkType("test-many-d")})
+	require.IsType(s.T(), link.CreateManyError{}, err)
+	require.Equal(s.T(), 1, err.(link.CreateManyError).Index)
+	require.NotNil(s.T(), errs.Cause(err))
+	// nothing of the failed batch was committed
+	var count int
+	db := s.DB.Model(&link.WorkItemLinkType{}).Where("name IN (?)", []string{"test-many-c", "test-many-invalid", "test-many-d"}).Count(&count)
+	require.Nil(s.T(), db.Error)
+	require.Equal(s.T(), 0, count)
+}
+
+func (s *workItemLinkTypeRepoBlackBoxTest) TestCreateInSystemSpace() {
+	ctx := context.Background()
+	categoryID := s.createLinkCategory("test-system-space-category")
+
+	// Test that Create rejects the system space
+	_, err := s.repo.Create(ctx, "test-system-space", nil, workitem.SystemBug, workitem.SystemBug, "test-fwd", "test-rev", link.TopologyNetwork, categoryID, space.SystemSpace)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+	require.Contains(s.T(), err.Error(), "space_id")
+
+	// Test that CreateMany rejects the system space
+	_, err = s.repo.CreateMany(ctx, []*link.WorkItemLinkType{{
+		Name:           "test-system-space-many",
+		SourceTypeID:   workitem.SystemBug,
+		TargetTypeID:   workitem.SystemBug,
+		ForwardName:    "test-fwd",
+		ReverseName:    "test-rev",
+		Topology:       link.TopologyNetwork,
+		LinkCategoryID: categoryID,
+		SpaceID:        space.SystemSpace,
+		System:         true,
+	}})
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+
+	// Test that a system link type can be created in the system space
+	lt := link.WorkItemLinkType{
+		Name:           "test-system-space-system",
+		SourceTypeID:   workitem.SystemBug,
+		TargetTypeID:   workitem.SystemBug,
+		ForwardName:    "test-fwd",
+		ReverseName:    "test-rev",
+		Topology:       link.TopologyNetwork,
+		LinkCategoryID: categoryID,
+		SpaceID:        space.SystemSpace,
+	}
+	require.Nil(s.T(), s.repo.CreateSystemType(ctx, &lt))
+	require.True(s.T(), lt.System)
+}
+
+func (s *workItemLinkTypeRepoBlackBoxTest) TestDefaultLinkType() {
+	ctx := context.Background()
+	sp, err := space.NewRepository(s.DB).Create(ctx, &space.Space{Name: satoriuuid.NewV4().String()})
+	require.Nil(s.T(), err)
+	categoryID := s.createLinkCategory("test-default-category")
+	create := func(name string) satoriuuid.UUID {
+		lt, err := s.repo.Create(ctx, name, nil, workitem.SystemBug, workitem.SystemBug, "test-fwd", "test-rev", link.TopologyNetwork, categoryID, sp.ID)
+		require.Nil(s.T(), err)
+		return *lt.Data.ID
+	}
+	setDefault := func(linkTypeID satoriuuid.UUID) error {
+		lt, err := s.repo.Load(ctx, linkTypeID)
+		require.Nil(s.T(), err)
+		isDefault := true
+		lt.Data.Attributes.IsDefault = &isDefault
+		_, err = s.repo.Save(ctx, *lt)
+		return err
+	}
+	first := create("test-default-first")
+	second := create("test-default-second")
+
+	// Test no default set
+	_, err = s.repo.LoadDefault(ctx, sp.ID)
+	require.IsType(s.T(), errors.NotFoundError{}, errs.Cause(err))
+
+	// Test setting a default
+	require.Nil(s.T(), setDefault(first))
+	// saving the default again is fine
+	require.Nil(s.T(), setDefault(first))
+
+	// Test attempting a second default on update and on creation
+	err = setDefault(second)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+	_, err = s.repo.CreateMany(ctx, []*link.WorkItemLinkType{{
+		Name:           "test-default-third",
+		SourceTypeID:   workitem.SystemBug,
+		TargetTypeID:   workitem.SystemBug,
+		ForwardName:    "test-fwd",
+		ReverseName:    "test-rev",
+		Topology:       link.TopologyNetwork,
+		LinkCategoryID: categoryID,
+		SpaceID:        sp.ID,
+		IsDefault:      true,
+	}})
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+
+	// Test loading the default
+	lt, err := s.repo.LoadDefault(ctx, sp.ID)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), first, lt.ID)
+	require.True(s.T(), lt.IsDefault)
+
+	// Test restoring the deleted default while another default exists
+	require.Nil(s.T(), s.repo.Delete(ctx, first))
+	require.Nil(s.T(), setDefault(second))
+	_, err = s.repo.Restore(ctx, first)
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+	lt, err = s.repo.LoadDefault(ctx, sp.ID)
+	require.Nil(s.T(), err)
+	require.Equal(s.T(), second, lt.ID)
+}
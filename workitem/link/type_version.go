@@ -0,0 +1,62 @@
+package link
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/almighty/almighty-core/errors"
+
+	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
+	satoriuuid "github.com/satori/go.uuid"
+)
+
+// WorkItemLinkTypeVersion is a past version of a work item link type. A new
+// entry is written with the state of the link type right before its version
+// is incremented (see LoadVersion).
+type WorkItemLinkTypeVersion struct {
+	LinkTypeID satoriuuid.UUID `sql:"type:uuid" gorm:"primary_key"`
+	Version    int             `gorm:"primary_key"`
+	// the timestamp at which this version was replaced by the next one
+	Time time.Time `gorm:"column:version_time"`
+	// the state of the link type at this version
+	LinkType linkTypeSnapshot `gorm:"column:link_type" sql:"type:jsonb"`
+}
+
+// TableName implements gorm.tabler
+func (v WorkItemLinkTypeVersion) TableName() string {
+	return "work_item_link_type_versions"
+}
+
+// linkTypeSnapshot stores a work item link type as JSON
+type linkTypeSnapshot WorkItemLinkType
+
+// Value implements driver.Valuer
+func (s linkTypeSnapshot) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+// Scan implements sql.Scanner
+func (s *linkTypeSnapshot) Scan(src interface{}) error {
+	b, ok := src.([]byte)
+	if !ok {
+		return errs.Errorf("scan source of link type snapshot was not []byte but %T", src)
+	}
+	return json.Unmarshal(b, s)
+}
+
+// createVersion stores the given link type as a past version
+func createVersion(db *gorm.DB, lt WorkItemLinkType) error {
+	v := WorkItemLinkTypeVersion{
+		LinkTypeID: lt.ID,
+		Version:    lt.Version,
+		Time:       time.Now(),
+		LinkType:   linkTypeSnapshot(lt),
+	}
+	if err := db.Create(&v).Error; err != nil {
+		return errors.NewInternalError(fmt.Sprintf("failed to store version %d of work item link type %s: %s", lt.Version, lt.ID, err.Error()))
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package link
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/almighty/almighty-core/gormsupport"
+	"github.com/almighty/almighty-core/resource"
+	"github.com/goadesign/goa"
+	satoriuuid "github.com/satori/go.uuid"
+)
+
+// roundTrip converts the given link type to its REST representation and back
+// and returns an error if the result differs from the input. The lifecycle
+// and the flags that are only consulted on creation are not part of the REST
+// representation and are therefore ignored.
+func roundTrip(t WorkItemLinkType) (WorkItemLinkType, error) {
+	req := &goa.RequestData{
+		Request: &http.Request{Host: "api.service.domain.org"},
+	}
+	converted := ConvertLinkTypeFromModel(req, t)
+	result := WorkItemLinkType{}
+	if err := ConvertLinkTypeToModel(converted, &result); err != nil {
+		return result, err
+	}
+	expected := t
+	expected.Lifecycle = gormsupport.Lifecycle{}
+	expected.AllowSameType = false
+	expected.System = false
+	if !expected.Equal(result) {
+		return result, fmt.Errorf("round trip of %+v resulted in %+v", expected, result)
+	}
+	return result, nil
+}
+
+func TestConvertLinkTypeRoundTrip(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	description := "An example description"
+	a := WorkItemLinkType{
+		ID:             satoriuuid.FromStringOrNil("0e671e36-871b-43a6-9166-0c4bd573e231"),
+		Name:           "Example work item link type",
+		Version:        3,
+		ForwardName:    "blocks",
+		ReverseName:    "blocked by",
+		SourceTypeID:   satoriuuid.FromStringOrNil("26787039-b68f-4e28-8814-c2f93be1ef4e"),
+		TargetTypeID:   satoriuuid.FromStringOrNil("bbf35418-04b6-426c-a60b-7f80beb0b624"),
+		LinkCategoryID: satoriuuid.FromStringOrNil("0e671e36-871b-43a6-9166-0c4bd573eAAA"),
+		SpaceID:        satoriuuid.FromStringOrNil("6ba7b810-9dad-11d1-80b4-00c04fd430c8"),
+		Lifecycle:      gormsupport.Lifecycle{CreatedAt: time.Now()},
+		System:         true,
+	}
+
+	// Check all topologies with and without a description
+	for _, topology := range validTopologies {
+		a.Topology = topology
+		a.Description = nil
+		if _, err := roundTrip(a); err != nil {
+			t.Error(err.Error())
+		}
+		a.Description = &description
+		if _, err := roundTrip(a); err != nil {
+			t.Error(err.Error())
+		}
+	}
+
+	// Check generated link types
+	property := func(n uint16, withDescription, deprecated bool, maxSourceCount, maxTargetCount uint8) bool {
+		lt := WorkItemLinkType{
+			ID:             satoriuuid.NewV4(),
+			Name:           fmt.Sprintf("link type %d", n),
+			Version:        int(n),
+			Topology:       validTopologies[int(n)%len(validTopologies)],
+			ForwardName:    fmt.Sprintf("forward %d", n),
+			ReverseName:    fmt.Sprintf("reverse %d", n),
+			SourceTypeID:   satoriuuid.NewV4(),
+			TargetTypeID:   satoriuuid.NewV4(),
+			LinkCategoryID: satoriuuid.NewV4(),
+			SpaceID:        satoriuuid.NewV4(),
+		}
+		if withDescription {
+			desc := fmt.Sprintf("description %d", n)
+			lt.Description = &desc
+		}
+		if deprecated {
+			deprecatedAt := time.Unix(int64(n), 0)
+			lt.DeprecatedAt = &deprecatedAt
+		}
+		if maxSourceCount > 0 {
+			count := int(maxSourceCount)
+			lt.MaxSourceCount = &count
+		}
+		if maxTargetCount > 0 {
+			count := int(maxTargetCount)
+			lt.MaxTargetCount = &count
+		}
+		_, err := roundTrip(lt)
+		return err == nil
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err.Error())
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"reflect"
 
 	"github.com/almighty/almighty-core/convert"
+	"github.com/almighty/almighty-core/errors"
+	uuid "github.com/satori/go.uuid"
 )
 
 //ListType describes a list of SimpleType values
@@ -38,19 +40,48 @@ func (fieldType ListType) ConvertToModel(value interface{}) (interface{}, error)
 
 }
 
-// ConvertFromModel implements the FieldType interface
+// ConvertFromModel implements the FieldType interface. Every element of the
+// list is validated against the list's component type; a ConversionError
+// identifying the first bad element is returned if an element is invalid.
 func (fieldType ListType) ConvertFromModel(value interface{}) (interface{}, error) {
 	// the assumption is that work item types do not change over time...only new ones can be created
-	return convertList(func(fieldType FieldType, value interface{}) (interface{}, error) {
+	converted, err := convertList(func(fieldType FieldType, value interface{}) (interface{}, error) {
+		if err := checkListElement(fieldType.GetKind(), value); err != nil {
+			return nil, err
+		}
 		return fieldType.ConvertFromModel(value)
 	}, fieldType.ComponentType, value)
+	if err != nil {
+		return nil, errors.NewConversionError(err.Error())
+	}
+	return converted, nil
+}
+
+// checkListElement returns an error if the given list element is not a
+// valid value of the given component kind.
+func checkListElement(kind Kind, value interface{}) error {
+	switch kind {
+	case KindString, KindIteration, KindArea:
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("value %v should be %s, but is %s", value, "string", reflect.TypeOf(value))
+		}
+	case KindUser:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("value %v should be %s, but is %s", value, "string", reflect.TypeOf(value))
+		}
+		if _, err := uuid.FromString(s); err != nil {
+			return fmt.Errorf("value %v should be %s", value, "a user ID")
+		}
+	}
+	return nil
 }
 
 type converter func(FieldType, interface{}) (interface{}, error)
 
 const (
 	stErrorNotArrayOrSlice = "value %v should be array/slice, but is %s"
-	stErrorConvertingList  = "error converting list value: %s"
+	stErrorConvertingList  = "error converting list value at index %d: %s"
 )
 
 func convertList(converter converter, componentType SimpleType, value interface{}) ([]interface{}, error) {
@@ -70,7 +101,7 @@ func convertList(converter converter, componentType SimpleType, value interface{
 		// valueArray index value must be converted to Interface else it has TYPE=Value
 		converted[i], err = converter(componentType, valueArray.Index(i).Interface())
 		if err != nil {
-			return nil, fmt.Errorf(stErrorConvertingList, err.Error())
+			return nil, fmt.Errorf(stErrorConvertingList, i, err.Error())
 		}
 	}
 	return converted, nil
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/almighty/almighty-core/convert"
+	"github.com/almighty/almighty-core/errors"
 	"github.com/almighty/almighty-core/resource"
 	. "github.com/almighty/almighty-core/workitem"
 	"github.com/stretchr/testify/assert"
@@ -43,3 +44,29 @@ func TestListType_Equal(t *testing.T) {
 	assert.True(t, d.Equal(a))
 	assert.True(t, a.Equal(d)) // test the inverse
 }
+
+func TestListTypeConvertFromModelValidatesElements(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	assignees := ListType{
+		SimpleType:    SimpleType{Kind: KindList},
+		ComponentType: SimpleType{Kind: KindUser},
+	}
+
+	// Test all elements valid
+	res, err := assignees.ConvertFromModel([]interface{}{"bbf35418-04b6-426c-a60b-7f80beb0b624", "26787039-b68f-4e28-8814-c2f93be1ef4e"})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"bbf35418-04b6-426c-a60b-7f80beb0b624", "26787039-b68f-4e28-8814-c2f93be1ef4e"}, res)
+
+	// Test one bad element
+	res, err = assignees.ConvertFromModel([]interface{}{"bbf35418-04b6-426c-a60b-7f80beb0b624", "not a user"})
+	assert.Nil(t, res)
+	assert.IsType(t, errors.ConversionError{}, err)
+	assert.Contains(t, err.Error(), "index 1")
+
+	// Test empty list
+	res, err = assignees.ConvertFromModel([]interface{}{})
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{}, res)
+}
@@ -2,15 +2,18 @@ package workitem
 
 import (
 	"fmt"
+	"net/url"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/almighty/almighty-core/codebase"
 	"github.com/almighty/almighty-core/convert"
+	"github.com/almighty/almighty-core/errors"
 	"github.com/almighty/almighty-core/rendering"
 	"github.com/asaskevich/govalidator"
-	"github.com/pkg/errors"
+	errs "github.com/pkg/errors"
 )
 
 // SimpleType is an unstructured FieldType
@@ -60,14 +63,41 @@ func (fieldType SimpleType) ConvertToModel(value interface{}) (interface{}, erro
 			return nil, fmt.Errorf("value %v should be %s, but is %s", value, "float64", valueType.Name())
 		}
 		return value, nil
-	case KindInteger, KindDuration:
+	case KindInteger:
 		if valueType.Kind() != reflect.Int {
 			return nil, fmt.Errorf("value %v should be %s, but is %s", value, "int", valueType.Name())
 		}
 		return value, nil
+	case KindBoolean:
+		return convertBooleanFromModel(value)
+	case KindDuration:
+		// a duration is either given as a number of seconds or as a
+		// duration string (e.g. "3h30m")
+		if valueType.Kind() == reflect.Int {
+			return value, nil
+		}
+		if valueType.Kind() == reflect.String {
+			d, err := time.ParseDuration(value.(string))
+			if err != nil {
+				return nil, fmt.Errorf("value %v should be %s, but is %s", value, "duration", valueType.Name())
+			}
+			return d.String(), nil
+		}
+		return nil, fmt.Errorf("value %v should be %s, but is %s", value, "int or duration string", valueType.Name())
+	case KindDateTime:
+		// a date time is given as an RFC3339 string, a time.Time or a Unix
+		// timestamp and always stored as RFC3339 string in UTC
+		if valueType == timeType {
+			return value.(time.Time).UTC().Format(time.RFC3339), nil
+		}
+		converted, err := convertDateTimeFromModel(value)
+		if err != nil {
+			return nil, fmt.Errorf("value %v should be %s, but is %s", value, "RFC3339 date time or Unix timestamp", valueType.Name())
+		}
+		return converted, nil
 	case KindInstant:
 		// instant == milliseconds
-		if !valueType.Implements(timeType) {
+		if valueType != timeType {
 			return nil, fmt.Errorf("value %v should be %s, but is %s", value, "time.Time", valueType.Name())
 		}
 		return value.(time.Time).UnixNano(), nil
@@ -76,7 +106,7 @@ func (fieldType SimpleType) ConvertToModel(value interface{}) (interface{}, erro
 			return nil, fmt.Errorf("value %v should be %s, but is %s", value, "string", valueType.Name())
 		}
 		idValue, err := strconv.Atoi(value.(string))
-		return idValue, errors.WithStack(err)
+		return idValue, errs.WithStack(err)
 	case KindList:
 		if (valueType.Kind() != reflect.Array) && (valueType.Kind() != reflect.Slice) {
 			return nil, fmt.Errorf("value %v should be %s, but is %s,", value, "array/slice", valueType.Kind())
@@ -93,7 +123,7 @@ func (fieldType SimpleType) ConvertToModel(value interface{}) (interface{}, erro
 			markupContent := value.(rendering.MarkupContent)
 			return markupContent.ToMap(), nil
 		default:
-			return nil, errors.Errorf("value %v should be %s, but is %s", value, "MarkupContent", valueType)
+			return nil, errs.Errorf("value %v should be %s, but is %s", value, "MarkupContent", valueType)
 		}
 	case KindCodebase:
 		switch value.(type) {
@@ -101,22 +131,35 @@ func (fieldType SimpleType) ConvertToModel(value interface{}) (interface{}, erro
 			cb := value.(codebase.CodebaseContent)
 			return cb.ToMap(), nil
 		default:
-			return nil, errors.Errorf("value %v should be %s, but is %s", value, "CodebaseContent", valueType)
+			return nil, errs.Errorf("value %v should be %s, but is %s", value, "CodebaseContent", valueType)
 		}
 	default:
-		return nil, errors.Errorf("unexpected type constant: '%s'", fieldType.GetKind())
+		return nil, errs.Errorf("unexpected type constant: '%s'", fieldType.GetKind())
 	}
 }
 
 // ConvertFromModel implements the FieldType interface
 func (fieldType SimpleType) ConvertFromModel(value interface{}) (interface{}, error) {
 	if value == nil {
+		if fieldType.GetKind() == KindBoolean {
+			// an unset boolean is false unless the field definition
+			// specifies a different default
+			return false, nil
+		}
 		return nil, nil
 	}
 	valueType := reflect.TypeOf(value)
 	switch fieldType.GetKind() {
-	case KindString, KindURL, KindUser, KindInteger, KindFloat, KindDuration, KindIteration, KindArea:
+	case KindString, KindUser, KindInteger, KindFloat, KindIteration, KindArea:
 		return value, nil
+	case KindURL:
+		return convertURLFromModel(value, nil)
+	case KindBoolean:
+		return convertBooleanFromModel(value)
+	case KindDuration:
+		return convertDurationFromModel(value)
+	case KindDateTime:
+		return convertDateTimeFromModel(value)
 	case KindInstant:
 		return time.Unix(0, value.(int64)), nil
 	case KindWorkitemReference:
@@ -126,20 +169,177 @@ func (fieldType SimpleType) ConvertFromModel(value interface{}) (interface{}, er
 		return strconv.FormatUint(value.(uint64), 10), nil
 	case KindMarkup:
 		if valueType.Kind() != reflect.Map {
-			return nil, errors.Errorf("value %v should be %s, but is %s", value, reflect.Map, valueType.Name())
+			return nil, errs.Errorf("value %v should be %s, but is %s", value, reflect.Map, valueType.Name())
 		}
 		markupContent := rendering.NewMarkupContentFromMap(value.(map[string]interface{}))
 		return markupContent, nil
 	case KindCodebase:
-		if valueType.Kind() != reflect.Map {
-			return nil, errors.Errorf("value %v should be %s, but is %s", value, reflect.Map, valueType.Name())
+		return convertCodebaseFromModel(value)
+	default:
+		return nil, errs.Errorf("unexpected field type: %s", fieldType.GetKind())
+	}
+}
+
+// convertDurationFromModel converts a stored duration into its canonical
+// string representation (e.g. "3h30m0s"). The stored value is either a
+// duration string or a number of seconds.
+func convertDurationFromModel(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, errors.NewConversionError(fmt.Sprintf("value %v is not a valid duration: %s", value, err.Error()))
 		}
-		cb, err := codebase.NewCodebaseContent(value.(map[string]interface{}))
+		return d.String(), nil
+	case int:
+		return (time.Duration(v) * time.Second).String(), nil
+	case int64:
+		return (time.Duration(v) * time.Second).String(), nil
+	case float64:
+		// numbers read from the JSON fields of a work item are float64
+		return (time.Duration(v * float64(time.Second))).String(), nil
+	default:
+		return nil, errors.NewConversionError(fmt.Sprintf("value %v should be %s, but is %s", value, "duration", reflect.TypeOf(value).Name()))
+	}
+}
+
+// convertDateTimeFromModel converts a stored date time into its canonical
+// RFC3339 representation in UTC (e.g. "2017-03-01T12:00:00Z"). The stored
+// value is either an RFC3339 string or a Unix timestamp in seconds.
+func convertDateTimeFromModel(value interface{}) (interface{}, error) {
+	var t time.Time
+	switch v := value.(type) {
+	case string:
+		parsed, err := time.Parse(time.RFC3339, v)
 		if err != nil {
-			return nil, err
+			return nil, errors.NewConversionError(fmt.Sprintf("value %v is not a valid RFC3339 date time: %s", value, err.Error()))
 		}
-		return cb, nil
+		t = parsed
+	case int:
+		t = time.Unix(int64(v), 0)
+	case int64:
+		t = time.Unix(v, 0)
+	case float64:
+		// numbers read from the JSON fields of a work item are float64
+		t = time.Unix(int64(v), 0)
 	default:
-		return nil, errors.Errorf("unexpected field type: %s", fieldType.GetKind())
+		return nil, errors.NewConversionError(fmt.Sprintf("value %v should be %s, but is %s", value, "date time", reflect.TypeOf(value).Name()))
+	}
+	return t.UTC().Format(time.RFC3339), nil
+}
+
+// convertCodebaseFromModel converts a stored codebase into a
+// codebase.CodebaseContent. The stored value must be a map that contains the
+// repository, branch, file name and line number; the repository must not be
+// empty and the line number must be a non-negative integer.
+func convertCodebaseFromModel(value interface{}) (interface{}, error) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.NewConversionError(fmt.Sprintf("value %v should be %s, but is %s", value, "codebase", reflect.TypeOf(value).Name()))
+	}
+	cb := codebase.CodebaseContent{}
+	for key, target := range map[string]*string{
+		codebase.RepositoryKey: &cb.Repository,
+		codebase.BranchKey:     &cb.Branch,
+		codebase.FileNameKey:   &cb.FileName,
+	} {
+		v, ok := m[key]
+		if !ok {
+			return nil, errors.NewConversionError(fmt.Sprintf("codebase %v is missing the %s", value, key))
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.NewConversionError(fmt.Sprintf("codebase %s %v should be %s, but is %s", key, v, "string", reflect.TypeOf(v)))
+		}
+		*target = s
+	}
+	if cb.Repository == "" {
+		return nil, errors.NewConversionError(fmt.Sprintf("codebase %v has an empty %s", value, codebase.RepositoryKey))
+	}
+	v, ok := m[codebase.LineNumberKey]
+	if !ok {
+		return nil, errors.NewConversionError(fmt.Sprintf("codebase %v is missing the %s", value, codebase.LineNumberKey))
+	}
+	switch n := v.(type) {
+	case int:
+		cb.LineNumber = n
+	case int64:
+		cb.LineNumber = int(n)
+	case float64:
+		// numbers read from the JSON fields of a work item are float64
+		if n != float64(int(n)) {
+			return nil, errors.NewConversionError(fmt.Sprintf("codebase %s %v is not an integer", codebase.LineNumberKey, v))
+		}
+		cb.LineNumber = int(n)
+	default:
+		return nil, errors.NewConversionError(fmt.Sprintf("codebase %s %v should be %s, but is %s", codebase.LineNumberKey, v, "integer", reflect.TypeOf(v)))
+	}
+	if cb.LineNumber < 0 {
+		return nil, errors.NewConversionError(fmt.Sprintf("codebase %s %d must not be negative", codebase.LineNumberKey, cb.LineNumber))
+	}
+	return cb, nil
+}
+
+// DefaultAllowedURLSchemes contains the URL schemes that are allowed for a
+// field of kind "url" if its field definition doesn't specify any.
+var DefaultAllowedURLSchemes = []string{"http", "https"}
+
+// convertURLFromModel parses a stored URL and returns its normalized string
+// representation. A ConversionError is returned if the value is not an
+// absolute URL or if its scheme is not one of the allowed schemes. If no
+// allowed schemes are given, DefaultAllowedURLSchemes is used.
+func convertURLFromModel(value interface{}, allowedSchemes []string) (interface{}, error) {
+	str, ok := value.(string)
+	if !ok {
+		return nil, errors.NewConversionError(fmt.Sprintf("value %v should be %s, but is %s", value, "string", reflect.TypeOf(value).Name()))
+	}
+	u, err := url.Parse(str)
+	if err != nil {
+		return nil, errors.NewConversionError(fmt.Sprintf("value %v is not a valid URL: %s", value, err.Error()))
+	}
+	if !u.IsAbs() || u.Host == "" {
+		return nil, errors.NewConversionError(fmt.Sprintf("value %v is not an absolute URL", value))
+	}
+	if len(allowedSchemes) == 0 {
+		allowedSchemes = DefaultAllowedURLSchemes
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	for _, scheme := range allowedSchemes {
+		if strings.ToLower(scheme) == u.Scheme {
+			return u.String(), nil
+		}
+	}
+	return nil, errors.NewConversionError(fmt.Sprintf("value %v has scheme %s, but only %s are allowed", value, u.Scheme, strings.Join(allowedSchemes, ", ")))
+}
+
+// convertBooleanFromModel coerces a stored boolean into a Go bool. Besides
+// real booleans, the strings "true" and "false" as well as the numbers 1 and
+// 0 are accepted; any other value results in a ConversionError.
+func convertBooleanFromModel(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "1":
+			return true, nil
+		case "false", "0":
+			return false, nil
+		}
+	case int:
+		if v == 0 || v == 1 {
+			return v == 1, nil
+		}
+	case int64:
+		if v == 0 || v == 1 {
+			return v == 1, nil
+		}
+	case float64:
+		// numbers read from the JSON fields of a work item are float64
+		if v == 0 || v == 1 {
+			return v == 1, nil
+		}
 	}
+	return nil, errors.NewConversionError(fmt.Sprintf("value %v should be %s, but is %s", value, "boolean", reflect.TypeOf(value).Name()))
 }
@@ -2,8 +2,11 @@ package workitem_test
 
 import (
 	"testing"
+	"time"
 
+	"github.com/almighty/almighty-core/codebase"
 	"github.com/almighty/almighty-core/convert"
+	"github.com/almighty/almighty-core/errors"
 	"github.com/almighty/almighty-core/resource"
 	. "github.com/almighty/almighty-core/workitem"
 	"github.com/stretchr/testify/assert"
@@ -38,3 +41,285 @@ func TestConvertToModel(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Nil(t, res)
 }
+
+func TestDurationConvertFromModel(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	a := SimpleType{Kind: KindDuration}
+
+	// Test valid duration string
+	res, err := a.ConvertFromModel("3h30m")
+	assert.Nil(t, err)
+	assert.Equal(t, "3h30m0s", res)
+
+	// Test number of seconds
+	res, err = a.ConvertFromModel(90)
+	assert.Nil(t, err)
+	assert.Equal(t, "1m30s", res)
+	res, err = a.ConvertFromModel(float64(90))
+	assert.Nil(t, err)
+	assert.Equal(t, "1m30s", res)
+
+	// Test invalid duration string
+	res, err = a.ConvertFromModel("three hours")
+	assert.Nil(t, res)
+	assert.IsType(t, errors.ConversionError{}, err)
+
+	// Test duration string input is normalized
+	res, err = a.ConvertToModel("90m")
+	assert.Nil(t, err)
+	assert.Equal(t, "1h30m0s", res)
+}
+
+func TestDateTimeConvertFromModel(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	a := SimpleType{Kind: KindDateTime}
+
+	// Test RFC3339 string is normalized to UTC
+	res, err := a.ConvertFromModel("2017-03-01T14:30:00+02:00")
+	assert.Nil(t, err)
+	assert.Equal(t, "2017-03-01T12:30:00Z", res)
+
+	// Test Unix epoch integer
+	res, err = a.ConvertFromModel(1488371400)
+	assert.Nil(t, err)
+	assert.Equal(t, "2017-03-01T12:30:00Z", res)
+	res, err = a.ConvertFromModel(float64(1488371400))
+	assert.Nil(t, err)
+	assert.Equal(t, "2017-03-01T12:30:00Z", res)
+
+	// Test unparseable string
+	res, err = a.ConvertFromModel("next tuesday")
+	assert.Nil(t, res)
+	assert.IsType(t, errors.ConversionError{}, err)
+
+	// Test nil
+	res, err = a.ConvertFromModel(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, res)
+}
+
+func TestDateTimeConvertToModel(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	a := SimpleType{Kind: KindDateTime}
+
+	// Test RFC3339 string is stored in UTC
+	res, err := a.ConvertToModel("2017-03-01T14:30:00+02:00")
+	assert.Nil(t, err)
+	assert.Equal(t, "2017-03-01T12:30:00Z", res)
+
+	// Test Unix epoch integer
+	res, err = a.ConvertToModel(1488371400)
+	assert.Nil(t, err)
+	assert.Equal(t, "2017-03-01T12:30:00Z", res)
+
+	// Test time.Time
+	res, err = a.ConvertToModel(time.Date(2017, 3, 1, 14, 30, 0, 0, time.FixedZone("CEST", 2*60*60)))
+	assert.Nil(t, err)
+	assert.Equal(t, "2017-03-01T12:30:00Z", res)
+
+	// Test unparseable string
+	res, err = a.ConvertToModel("next tuesday")
+	assert.Nil(t, res)
+	assert.NotNil(t, err)
+}
+
+func TestInstantConvertToModel(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	a := SimpleType{Kind: KindInstant}
+
+	// Test time.Time
+	now := time.Now()
+	res, err := a.ConvertToModel(now)
+	assert.Nil(t, err)
+	assert.Equal(t, now.UnixNano(), res)
+
+	// Test other type
+	res, err = a.ConvertToModel("2017-03-01T12:30:00Z")
+	assert.Nil(t, res)
+	assert.NotNil(t, err)
+}
+
+func TestURLConvertFromModel(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	a := SimpleType{Kind: KindURL}
+
+	// Test valid https URL
+	res, err := a.ConvertFromModel("https://GitHub.com/almighty/almighty-core")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://github.com/almighty/almighty-core", res)
+
+	// Test disallowed scheme
+	res, err = a.ConvertFromModel("ftp://ftp.example.com/file")
+	assert.IsType(t, errors.ConversionError{}, err)
+	assert.Nil(t, res)
+
+	// Test malformed URL
+	res, err = a.ConvertFromModel("http://[::1")
+	assert.IsType(t, errors.ConversionError{}, err)
+	assert.Nil(t, res)
+
+	// Test relative URL
+	res, err = a.ConvertFromModel("almighty/almighty-core")
+	assert.IsType(t, errors.ConversionError{}, err)
+	assert.Nil(t, res)
+
+	// Test schemes configured on the field definition
+	def := FieldDefinition{Type: a, AllowedSchemes: []string{"ftp"}}
+	res, err = def.ConvertFromModel("repo", "ftp://ftp.example.com/file")
+	assert.Nil(t, err)
+	assert.Equal(t, "ftp://ftp.example.com/file", res)
+	res, err = def.ConvertFromModel("repo", "https://github.com")
+	assert.IsType(t, errors.ConversionError{}, err)
+	assert.Nil(t, res)
+}
+
+func TestURLFieldConvertToModel(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	def := FieldDefinition{Type: SimpleType{Kind: KindURL}}
+
+	// Test valid https URL is stored normalized
+	res, err := def.ConvertToModel("repo", "https://GitHub.com/almighty/almighty-core")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://github.com/almighty/almighty-core", res)
+
+	// Test disallowed scheme is rejected on write
+	res, err = def.ConvertToModel("repo", "ftp://ftp.example.com/file")
+	assert.IsType(t, errors.ConversionError{}, err)
+	assert.Nil(t, res)
+
+	// Test URL without scheme is rejected on write
+	res, err = def.ConvertToModel("repo", "github.com/almighty/almighty-core")
+	assert.NotNil(t, err)
+	assert.Nil(t, res)
+
+	// Test schemes configured on the field definition
+	def.AllowedSchemes = []string{"ftp"}
+	res, err = def.ConvertToModel("repo", "ftp://ftp.example.com/file")
+	assert.Nil(t, err)
+	assert.Equal(t, "ftp://ftp.example.com/file", res)
+	res, err = def.ConvertToModel("repo", "https://github.com")
+	assert.IsType(t, errors.ConversionError{}, err)
+	assert.Nil(t, res)
+}
+
+func TestCodebaseConvertFromModel(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	a := SimpleType{Kind: KindCodebase}
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			codebase.RepositoryKey: "https://github.com/almighty/almighty-core",
+			codebase.BranchKey:     "master",
+			codebase.FileNameKey:   "main.go",
+			codebase.LineNumberKey: float64(200),
+		}
+	}
+
+	// Test well-formed codebase as read from the JSON fields
+	res, err := a.ConvertFromModel(valid())
+	assert.Nil(t, err)
+	assert.Equal(t, codebase.CodebaseContent{
+		Repository: "https://github.com/almighty/almighty-core",
+		Branch:     "master",
+		FileName:   "main.go",
+		LineNumber: 200,
+	}, res)
+
+	// Test value that isn't a map
+	res, err = a.ConvertFromModel("https://github.com/almighty/almighty-core")
+	assert.IsType(t, errors.ConversionError{}, err)
+	assert.Nil(t, res)
+
+	// Test missing sub-keys
+	for _, key := range []string{codebase.RepositoryKey, codebase.BranchKey, codebase.FileNameKey, codebase.LineNumberKey} {
+		m := valid()
+		delete(m, key)
+		res, err = a.ConvertFromModel(m)
+		assert.IsType(t, errors.ConversionError{}, err, key)
+		assert.Nil(t, res)
+	}
+
+	// Test empty repository
+	m := valid()
+	m[codebase.RepositoryKey] = ""
+	res, err = a.ConvertFromModel(m)
+	assert.IsType(t, errors.ConversionError{}, err)
+	assert.Nil(t, res)
+
+	// Test sub-keys of the wrong type
+	m = valid()
+	m[codebase.BranchKey] = 42
+	res, err = a.ConvertFromModel(m)
+	assert.IsType(t, errors.ConversionError{}, err)
+	assert.Nil(t, res)
+	m = valid()
+	m[codebase.LineNumberKey] = "200"
+	res, err = a.ConvertFromModel(m)
+	assert.IsType(t, errors.ConversionError{}, err)
+	assert.Nil(t, res)
+
+	// Test invalid line numbers
+	m = valid()
+	m[codebase.LineNumberKey] = 1.5
+	res, err = a.ConvertFromModel(m)
+	assert.IsType(t, errors.ConversionError{}, err)
+	assert.Nil(t, res)
+	m = valid()
+	m[codebase.LineNumberKey] = -1
+	res, err = a.ConvertFromModel(m)
+	assert.IsType(t, errors.ConversionError{}, err)
+	assert.Nil(t, res)
+}
+
+func TestBooleanConvertFromModel(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	a := SimpleType{Kind: KindBoolean}
+
+	// Test real booleans
+	res, err := a.ConvertFromModel(true)
+	assert.Nil(t, err)
+	assert.Equal(t, true, res)
+	res, err = a.ConvertFromModel(false)
+	assert.Nil(t, err)
+	assert.Equal(t, false, res)
+
+	// Test string coercion
+	res, err = a.ConvertFromModel("true")
+	assert.Nil(t, err)
+	assert.Equal(t, true, res)
+	res, err = a.ConvertFromModel("False")
+	assert.Nil(t, err)
+	assert.Equal(t, false, res)
+
+	// Test numeric coercion
+	res, err = a.ConvertFromModel(1)
+	assert.Nil(t, err)
+	assert.Equal(t, true, res)
+	res, err = a.ConvertFromModel(float64(0))
+	assert.Nil(t, err)
+	assert.Equal(t, false, res)
+
+	// Test uncoercible values
+	res, err = a.ConvertFromModel("yes please")
+	assert.IsType(t, errors.ConversionError{}, err)
+	assert.Nil(t, res)
+	res, err = a.ConvertFromModel(2)
+	assert.IsType(t, errors.ConversionError{}, err)
+	assert.Nil(t, res)
+
+	// Test nil without and with default
+	res, err = a.ConvertFromModel(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, false, res)
+	def := FieldDefinition{Type: a, DefaultValue: true}
+	res, err = def.ConvertFromModel("confidential", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, true, res)
+}
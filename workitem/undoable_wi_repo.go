@@ -132,3 +132,23 @@ func (r *UndoableWorkItemRepository) GetCountsPerIteration(ctx context.Context,
 func (r *UndoableWorkItemRepository) GetCountsForIteration(ctx context.Context, iterationId uuid.UUID) (map[string]WICountsPerIteration, error) {
 	return map[string]WICountsPerIteration{}, nil
 }
+
+// CountWithField implements application.WorkItemRepository
+func (r *UndoableWorkItemRepository) CountWithField(ctx context.Context, typeID uuid.UUID, fieldKey string) (int, error) {
+	return r.wrapped.CountWithField(ctx, typeID, fieldKey)
+}
+
+// CountByType implements application.WorkItemRepository
+func (r *UndoableWorkItemRepository) CountByType(ctx context.Context, typeID uuid.UUID) (int, error) {
+	return r.wrapped.CountByType(ctx, typeID)
+}
+
+// CountSubtypes implements application.WorkItemRepository
+func (r *UndoableWorkItemRepository) CountSubtypes(ctx context.Context, typeID uuid.UUID) (int, error) {
+	return r.wrapped.CountSubtypes(ctx, typeID)
+}
+
+// ConvertFieldValues implements application.WorkItemRepository
+func (r *UndoableWorkItemRepository) ConvertFieldValues(ctx context.Context, typeID uuid.UUID, fieldKey string, batchSize int, convert func(interface{}) (interface{}, error)) (int, int, error) {
+	return r.wrapped.ConvertFieldValues(ctx, typeID, fieldKey, batchSize, convert)
+}
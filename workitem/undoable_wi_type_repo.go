@@ -34,6 +34,28 @@ func (r *UndoableWorkItemTypeRepository) List(ctx context.Context, start *int, l
 	return r.wrapped.List(ctx, start, length)
 }
 
+// IsLeafType implements application.WorkItemTypeRepository
+func (r *UndoableWorkItemTypeRepository) IsLeafType(ctx context.Context, typeID uuid.UUID, spaceID uuid.UUID) (bool, error) {
+	return r.wrapped.IsLeafType(ctx, typeID, spaceID)
+}
+
+// ListDefiningField implements application.WorkItemTypeRepository
+func (r *UndoableWorkItemTypeRepository) ListDefiningField(ctx context.Context, fieldKey string) ([]WorkItemType, error) {
+	return r.wrapped.ListDefiningField(ctx, fieldKey)
+}
+
+// Delete implements application.WorkItemTypeRepository
+func (r *UndoableWorkItemTypeRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	err := r.wrapped.Delete(ctx, id)
+	if err == nil {
+		r.undo.Append(func(db *gorm.DB) error {
+			db = db.Unscoped().Model(&WorkItemType{}).Where("id = ?", id).Update("deleted_at", nil)
+			return db.Error
+		})
+	}
+	return errors.WithStack(err)
+}
+
 // Create implements application.WorkItemTypeRepository
 func (r *UndoableWorkItemTypeRepository) Create(ctx context.Context, id *uuid.UUID, extendedTypeID *uuid.UUID, name string, description *string, icon string, fields map[string]app.FieldDefinition) (*app.WorkItemTypeSingle, error) {
 	res, err := r.wrapped.Create(ctx, id, extendedTypeID, name, description, icon, fields)
@@ -1,6 +1,7 @@
 package workitem
 
 import (
+	"reflect"
 	"strconv"
 
 	"golang.org/x/net/context"
@@ -11,6 +12,7 @@ import (
 	"github.com/almighty/almighty-core/criteria"
 	"github.com/almighty/almighty-core/errors"
 	"github.com/almighty/almighty-core/log"
+	"github.com/almighty/almighty-core/models"
 	"github.com/almighty/almighty-core/rendering"
 	"github.com/jinzhu/gorm"
 	errs "github.com/pkg/errors"
@@ -27,11 +29,15 @@ type WorkItemRepository interface {
 	Fetch(ctx context.Context, criteria criteria.Expression) (*app.WorkItem, error)
 	GetCountsPerIteration(ctx context.Context, spaceID uuid.UUID) (map[string]WICountsPerIteration, error)
 	GetCountsForIteration(ctx context.Context, iterationID uuid.UUID) (map[string]WICountsPerIteration, error)
+	CountWithField(ctx context.Context, typeID uuid.UUID, fieldKey string) (int, error)
+	CountByType(ctx context.Context, typeID uuid.UUID) (int, error)
+	CountSubtypes(ctx context.Context, typeID uuid.UUID) (int, error)
+	ConvertFieldValues(ctx context.Context, typeID uuid.UUID, fieldKey string, batchSize int, convert func(interface{}) (interface{}, error)) (int, int, error)
 }
 
 // NewWorkItemRepository creates a GormWorkItemRepository
 func NewWorkItemRepository(db *gorm.DB) *GormWorkItemRepository {
-	repository := &GormWorkItemRepository{db, &GormWorkItemTypeRepository{db}, &GormRevisionRepository{db}}
+	repository := &GormWorkItemRepository{db, NewWorkItemTypeRepository(db), &GormRevisionRepository{db}}
 	return repository
 }
 
@@ -147,17 +153,27 @@ func (r *GormWorkItemRepository) Save(ctx context.Context, wi app.WorkItem, modi
 
 	res.Version = res.Version + 1
 	res.Type = wi.Type
-	res.Fields = Fields{}
+	storedFields := res.Fields
 
-	for fieldName, fieldDef := range wiType.Fields {
+	// the read-only fields keep their stored values and cannot be changed
+	fields, readOnlyFields := splitReadOnlyFields(wiType, wi.Fields)
+	for fieldName, fieldValue := range readOnlyFields {
 		if fieldName == SystemCreatedAt {
 			continue
 		}
-		fieldValue := wi.Fields[fieldName]
-		var err error
-		res.Fields[fieldName], err = fieldDef.ConvertToModel(fieldName, fieldValue)
-		if err != nil {
-			return nil, errors.NewBadParameterError(fieldName, fieldValue)
+		modelValue, err := wiType.Fields[fieldName].ConvertToModel(fieldName, fieldValue)
+		if err != nil || !reflect.DeepEqual(modelValue, storedFields[fieldName]) {
+			return nil, errors.NewBadParameterError(fieldName, fieldValue).Expected("read-only field not to be changed")
+		}
+	}
+	res.Fields, err = wiType.ConvertToModel(fields)
+	if err != nil {
+		return nil, err
+	}
+	delete(res.Fields, SystemCreatedAt)
+	for fieldName, fieldDef := range wiType.Fields {
+		if fieldDef.ReadOnly && fieldName != SystemCreatedAt {
+			res.Fields[fieldName] = storedFields[fieldName]
 		}
 	}
 
@@ -191,25 +207,30 @@ func (r *GormWorkItemRepository) Create(ctx context.Context, typeID uuid.UUID, f
 		return nil, errors.NewBadParameterError("typeID", typeID)
 	}
 	wi := WorkItem{
-		Type:   typeID,
-		Fields: Fields{},
+		Type: typeID,
 	}
 	fields[SystemCreator] = creatorID.String()
-	for fieldName, fieldDef := range wiType.Fields {
+	// the read-only fields are managed by the system, e.g. the creator above or
+	// the remote item ID set by the remote tracker, and are taken as given
+	writableFields, readOnlyFields := splitReadOnlyFields(wiType, fields)
+	wi.Fields, err = wiType.ConvertToModel(writableFields)
+	if err != nil {
+		return nil, err
+	}
+	delete(wi.Fields, SystemCreatedAt)
+	for fieldName, fieldValue := range readOnlyFields {
 		if fieldName == SystemCreatedAt {
 			continue
 		}
-		fieldValue := fields[fieldName]
-		var err error
-		wi.Fields[fieldName], err = fieldDef.ConvertToModel(fieldName, fieldValue)
+		wi.Fields[fieldName], err = wiType.Fields[fieldName].ConvertToModel(fieldName, fieldValue)
 		if err != nil {
-			return nil, errors.NewBadParameterError(fieldName, fieldValue)
+			return nil, errs.Wrap(errors.NewBadParameterError(fieldName, fieldValue), err.Error())
 		}
-		if fieldName == SystemDescription && wi.Fields[fieldName] != nil {
-			description := rendering.NewMarkupContentFromMap(wi.Fields[fieldName].(map[string]interface{}))
-			if !rendering.IsMarkupSupported(description.Markup) {
-				return nil, errors.NewBadParameterError(fieldName, fieldValue)
-			}
+	}
+	if wi.Fields[SystemDescription] != nil {
+		description := rendering.NewMarkupContentFromMap(wi.Fields[SystemDescription].(map[string]interface{}))
+		if !rendering.IsMarkupSupported(description.Markup) {
+			return nil, errors.NewBadParameterError(SystemDescription, fields[SystemDescription])
 		}
 	}
 	tx := r.db
@@ -230,6 +251,22 @@ func (r *GormWorkItemRepository) Create(ctx context.Context, typeID uuid.UUID, f
 	return witem, nil
 }
 
+// splitReadOnlyFields splits the given field values into the values of the
+// fields that can be set by a client and the values of the read-only fields of
+// the given work item type.
+func splitReadOnlyFields(wiType *WorkItemType, fields map[string]interface{}) (writable map[string]interface{}, readOnly map[string]interface{}) {
+	writable = map[string]interface{}{}
+	readOnly = map[string]interface{}{}
+	for name, value := range fields {
+		if wiType.Fields[name].ReadOnly {
+			readOnly[name] = value
+		} else {
+			writable[name] = value
+		}
+	}
+	return writable, readOnly
+}
+
 func convertWorkItemModelToApp(wiType *WorkItemType, wi *WorkItem) (*app.WorkItem, error) {
 	result, err := wiType.ConvertFromModel(*wi)
 	if err != nil {
@@ -401,3 +438,147 @@ func (r *GormWorkItemRepository) GetCountsForIteration(ctx context.Context, iter
 	}
 	return countsMap, nil
 }
+
+// CountWithField returns the number of work items of the given type (not
+// including subtypes) that have a non-null value for the given field.
+// It executes
+// SELECT count(*) FROM "work_items" WHERE type = ? AND fields->>? IS NOT NULL AND deleted_at IS NULL
+func (r *GormWorkItemRepository) CountWithField(ctx context.Context, typeID uuid.UUID, fieldKey string) (int, error) {
+	var count int
+	db := r.db.Model(&WorkItem{}).Where("type = ? AND fields->>? IS NOT NULL", typeID, fieldKey).Count(&count)
+	if db.Error != nil {
+		return 0, errors.NewInternalError(db.Error.Error())
+	}
+	return count, nil
+}
+
+// CountByType returns the number of work items of the given type (not
+// including subtypes).
+// It executes
+// SELECT count(*) FROM "work_items" WHERE type = ? AND deleted_at IS NULL
+func (r *GormWorkItemRepository) CountByType(ctx context.Context, typeID uuid.UUID) (int, error) {
+	var count int
+	db := r.db.Model(&WorkItem{}).Where("type = ?", typeID).Count(&count)
+	if db.Error != nil {
+		return 0, errors.NewInternalError(db.Error.Error())
+	}
+	return count, nil
+}
+
+// CountSubtypes returns the number of work item types that directly or
+// indirectly extend the given type.
+// returns InternalError
+func (r *GormWorkItemRepository) CountSubtypes(ctx context.Context, typeID uuid.UUID) (int, error) {
+	return r.witr.countSubtypes(typeID)
+}
+
+// CountItemsWithField returns the number of work items of the given type that
+// have data in the given field, i.e. the number of work items affected if
+// the field is removed from the work item type.
+// returns BadParameterError or InternalError
+func CountItemsWithField(ctx context.Context, typeID uuid.UUID, fieldKey string, repo WorkItemRepository) (int, error) {
+	if fieldKey == "" {
+		return 0, errors.NewBadParameterError("fieldKey", fieldKey).Expected("not empty")
+	}
+	count, err := repo.CountWithField(ctx, typeID, fieldKey)
+	if err != nil {
+		return 0, errs.WithStack(err)
+	}
+	return count, nil
+}
+
+// ConvertFieldValues applies the given conversion function to the value of
+// the given field of every work item of the given type (not including
+// subtypes) that has a non-null value for the field. The work items are
+// processed in batches of the given size ordered by ID and every batch is
+// updated in its own transaction. Values for which the conversion fails are
+// left unchanged. The number of converted and of failed values is returned;
+// if an error occurs, the counts cover the batches committed so far.
+// returns BadParameterError or InternalError
+func (r *GormWorkItemRepository) ConvertFieldValues(ctx context.Context, typeID uuid.UUID, fieldKey string, batchSize int, convert func(interface{}) (interface{}, error)) (int, int, error) {
+	if batchSize < 1 {
+		return 0, 0, errors.NewBadParameterError("batchSize", batchSize).Expected("at least 1")
+	}
+	migrated, failed := 0, 0
+	lastID := uint64(0)
+	for {
+		var batch []WorkItem
+		batchMigrated, batchFailed := 0, 0
+		err := models.Transactional(r.db, func(tx *gorm.DB) error {
+			db := tx.Where("type = ? AND fields->>? IS NOT NULL AND id > ?", typeID, fieldKey, lastID).Order("id").Limit(batchSize).Find(&batch)
+			if db.Error != nil {
+				return errors.NewInternalError(db.Error.Error())
+			}
+			for _, wi := range batch {
+				converted, err := convert(wi.Fields[fieldKey])
+				if err != nil {
+					log.Info(ctx, map[string]interface{}{
+						"wiID":     wi.ID,
+						"fieldKey": fieldKey,
+						"err":      err,
+					}, "Failed to convert field value")
+					batchFailed++
+					continue
+				}
+				wi.Fields[fieldKey] = converted
+				db = tx.Model(&wi).UpdateColumns(map[string]interface{}{
+					"fields":  wi.Fields,
+					"version": gorm.Expr("version + 1"),
+				})
+				if db.Error != nil {
+					return errors.NewInternalError(db.Error.Error())
+				}
+				batchMigrated++
+			}
+			return nil
+		})
+		if err != nil {
+			return migrated, failed, errs.WithStack(err)
+		}
+		migrated += batchMigrated
+		failed += batchFailed
+		if len(batch) < batchSize {
+			return migrated, failed, nil
+		}
+		lastID = batch[len(batch)-1].ID
+	}
+}
+
+// fieldKindMigrationBatchSize is the number of work items MigrateFieldKind
+// converts per transaction.
+const fieldKindMigrationBatchSize = 100
+
+// MigrateFieldKind converts the stored values of the given field of all work
+// items of the given type from the given kind to the given kind using the
+// given conversion function, e.g. to turn a string field into an enum. The
+// work item type itself is not modified. If the target kind is a simple
+// kind, converted values that are not valid for it count as failed. The
+// number of migrated and of failed values is returned.
+// returns BadParameterError or InternalError
+func MigrateFieldKind(ctx context.Context, typeID uuid.UUID, fieldKey string, from, to Kind, convert func(interface{}) (interface{}, error), repo WorkItemRepository) (migrated int, failed int, err error) {
+	if fieldKey == "" {
+		return 0, 0, errors.NewBadParameterError("fieldKey", fieldKey).Expected("not empty")
+	}
+	if from == to {
+		return 0, 0, errors.NewBadParameterError("to", to).Expected(fmt.Sprintf("a kind other than %s", from))
+	}
+	if convert == nil {
+		return 0, 0, errors.NewBadParameterError("convert", nil).Expected("not <nil>")
+	}
+	checked := convert
+	if to.isSimpleType() {
+		targetType := SimpleType{Kind: to}
+		checked = func(value interface{}) (interface{}, error) {
+			converted, err := convert(value)
+			if err != nil {
+				return nil, errs.WithStack(err)
+			}
+			return targetType.ConvertToModel(converted)
+		}
+	}
+	migrated, failed, err = repo.ConvertFieldValues(ctx, typeID, fieldKey, fieldKindMigrationBatchSize, checked)
+	if err != nil {
+		return migrated, failed, errs.WithStack(err)
+	}
+	return migrated, failed, nil
+}
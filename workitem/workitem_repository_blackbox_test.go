@@ -3,8 +3,10 @@ package workitem_test
 import (
 	"fmt"
 	"os"
+	"sort"
 	"testing"
 
+	"github.com/almighty/almighty-core/app"
 	"github.com/almighty/almighty-core/codebase"
 	"github.com/almighty/almighty-core/errors"
 	"github.com/almighty/almighty-core/gormsupport"
@@ -118,19 +120,24 @@ func (s *workItemRepoBlackBoxTest) TestFaiLoadZeroID() {
 
 func (s *workItemRepoBlackBoxTest) TestSaveAssignees() {
 	// given
+	assigneeA := uuid.NewV4().String()
+	assigneeB := uuid.NewV4().String()
 	wi, err := s.repo.Create(
 		context.Background(), workitem.SystemBug,
 		map[string]interface{}{
 			workitem.SystemTitle:     "Title",
 			workitem.SystemState:     workitem.SystemStateNew,
-			workitem.SystemAssignees: []string{"A", "B"},
+			workitem.SystemAssignees: []string{assigneeA, assigneeB},
 		}, s.creatorID)
 	require.Nil(s.T(), err, "Could not create workitem")
 	// when
 	wi, err = s.repo.Load(context.Background(), wi.ID)
 	// then
 	require.Nil(s.T(), err)
-	assert.Equal(s.T(), "A", wi.Fields[workitem.SystemAssignees].([]interface{})[0])
+	// list values are returned in canonical order
+	expectedAssignees := []string{assigneeA, assigneeB}
+	sort.Strings(expectedAssignees)
+	assert.Equal(s.T(), []interface{}{expectedAssignees[0], expectedAssignees[1]}, wi.Fields[workitem.SystemAssignees])
 }
 
 func (s *workItemRepoBlackBoxTest) TestSaveForUnchangedCreatedDate() {
@@ -151,6 +158,28 @@ func (s *workItemRepoBlackBoxTest) TestSaveForUnchangedCreatedDate() {
 	assert.Equal(s.T(), wi.Fields[workitem.SystemCreatedAt], wiNew.Fields[workitem.SystemCreatedAt])
 }
 
+func (s *workItemRepoBlackBoxTest) TestSaveReadOnlyField() {
+	// given
+	wi, err := s.repo.Create(
+		context.Background(), workitem.SystemBug,
+		map[string]interface{}{
+			workitem.SystemTitle: "Title",
+			workitem.SystemState: workitem.SystemStateNew,
+		}, s.creatorID)
+	require.Nil(s.T(), err, "Could not create workitem")
+	// when
+	wi.Fields[workitem.SystemTitle] = "Updated Title"
+	wiNew, err := s.repo.Save(context.Background(), *wi, s.creatorID)
+	// then the unchanged read-only creator is accepted
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), s.creatorID.String(), wiNew.Fields[workitem.SystemCreator])
+	// when
+	wiNew.Fields[workitem.SystemCreator] = uuid.NewV4().String()
+	_, err = s.repo.Save(context.Background(), *wiNew, s.creatorID)
+	// then the changed read-only creator is rejected
+	require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+}
+
 func (s *workItemRepoBlackBoxTest) TestCreateWorkItemWithDescriptionNoMarkup() {
 	// given
 	wi, err := s.repo.Create(
@@ -304,3 +333,138 @@ func (s *workItemRepoBlackBoxTest) TestCodebaseAttributes() {
 	assert.Equal(s.T(), file, cb.FileName)
 	assert.Equal(s.T(), line, cb.LineNumber)
 }
+
+func (s *workItemRepoBlackBoxTest) TestCountItemsWithField() {
+	ctx := context.Background()
+	// given a work item type with a custom field
+	typeID := uuid.NewV4()
+	_, err := workitem.NewWorkItemTypeRepository(s.DB).Create(ctx, &typeID, &workitem.SystemBug, "test-count-with-field", nil, "fa-bug", map[string]app.FieldDefinition{
+		"effort": {Type: &app.FieldType{Kind: "float"}},
+	})
+	require.Nil(s.T(), err)
+	createWorkItem := func(effort interface{}) {
+		fields := map[string]interface{}{
+			workitem.SystemTitle: "Title",
+			workitem.SystemState: workitem.SystemStateNew,
+		}
+		if effort != nil {
+			fields["effort"] = effort
+		}
+		_, err := s.repo.Create(ctx, typeID, fields, s.creatorID)
+		require.Nil(s.T(), err)
+	}
+	createWorkItem(3.5)
+	createWorkItem(1.0)
+	createWorkItem(nil)
+	// when
+	count, err := workitem.CountItemsWithField(ctx, typeID, "effort", s.repo)
+	// then only the work items with an effort are counted
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), 2, count)
+	// and a field that is always set is counted for every work item
+	count, err = workitem.CountItemsWithField(ctx, typeID, workitem.SystemTitle, s.repo)
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), 3, count)
+	// and an empty field key is rejected
+	_, err = workitem.CountItemsWithField(ctx, typeID, "", s.repo)
+	assert.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+}
+
+func (s *workItemRepoBlackBoxTest) TestDeleteType() {
+	ctx := context.Background()
+	witRepo := workitem.NewWorkItemTypeRepository(s.DB)
+	// given a base type with a subtype and a work item of the subtype; the
+	// base type extends the planner item to inherit the system fields
+	base, err := witRepo.Create(ctx, nil, &workitem.SystemPlannerItem, "test-delete-base", nil, "fa-bug", map[string]app.FieldDefinition{})
+	require.Nil(s.T(), err)
+	used, err := witRepo.Create(ctx, nil, base.Data.ID, "test-delete-used", nil, "fa-bug", map[string]app.FieldDefinition{})
+	require.Nil(s.T(), err)
+	_, err = s.repo.Create(ctx, *used.Data.ID, map[string]interface{}{
+		workitem.SystemTitle: "Title",
+		workitem.SystemState: workitem.SystemStateNew,
+	}, s.creatorID)
+	require.Nil(s.T(), err)
+
+	// Check a type with work items
+	ok, count, err := workitem.CanDeleteType(ctx, *used.Data.ID, s.repo)
+	require.Nil(s.T(), err)
+	assert.False(s.T(), ok)
+	assert.Equal(s.T(), 1, count)
+	err = witRepo.Delete(ctx, *used.Data.ID)
+	require.IsType(s.T(), errors.InUseError{}, errs.Cause(err))
+	assert.Equal(s.T(), 1, errs.Cause(err).(errors.InUseError).Count)
+
+	// Check a type with subtypes but no work items of its own
+	ok, count, err = workitem.CanDeleteType(ctx, *base.Data.ID, s.repo)
+	require.Nil(s.T(), err)
+	assert.False(s.T(), ok)
+	assert.Equal(s.T(), 0, count)
+	err = witRepo.Delete(ctx, *base.Data.ID)
+	require.IsType(s.T(), errors.InUseError{}, errs.Cause(err))
+	assert.Equal(s.T(), 1, errs.Cause(err).(errors.InUseError).Count)
+
+	// Check an unused leaf type
+	unused, err := witRepo.Create(ctx, nil, base.Data.ID, "test-delete-unused", nil, "fa-bug", map[string]app.FieldDefinition{})
+	require.Nil(s.T(), err)
+	ok, count, err = workitem.CanDeleteType(ctx, *unused.Data.ID, s.repo)
+	require.Nil(s.T(), err)
+	assert.True(s.T(), ok)
+	assert.Equal(s.T(), 0, count)
+	require.Nil(s.T(), witRepo.Delete(ctx, *unused.Data.ID))
+	_, err = witRepo.LoadTypeFromDB(ctx, *unused.Data.ID)
+	require.IsType(s.T(), errors.NotFoundError{}, errs.Cause(err))
+}
+
+func (s *workItemRepoBlackBoxTest) TestMigrateFieldKind() {
+	ctx := context.Background()
+	// given a work item type with a string field
+	typeID := uuid.NewV4()
+	_, err := workitem.NewWorkItemTypeRepository(s.DB).Create(ctx, &typeID, &workitem.SystemBug, "test-migrate-field-kind", nil, "fa-bug", map[string]app.FieldDefinition{
+		"severity": {Type: &app.FieldType{Kind: "string"}},
+	})
+	require.Nil(s.T(), err)
+	createWorkItem := func(severity string) string {
+		wi, err := s.repo.Create(ctx, typeID, map[string]interface{}{
+			workitem.SystemTitle: "Title",
+			workitem.SystemState: workitem.SystemStateNew,
+			"severity":           severity,
+		}, s.creatorID)
+		require.Nil(s.T(), err)
+		return *wi.ID
+	}
+	highID := createWorkItem("high")
+	lowID := createWorkItem("low")
+	bogusID := createWorkItem("bogus")
+	enumValues := map[string]string{"high": "High", "low": "Low"}
+	toEnum := func(value interface{}) (interface{}, error) {
+		enumValue, ok := enumValues[value.(string)]
+		if !ok {
+			return nil, fmt.Errorf("no enum value for %v", value)
+		}
+		return enumValue, nil
+	}
+	// when
+	migrated, failed, err := workitem.MigrateFieldKind(ctx, typeID, "severity", workitem.KindString, workitem.KindEnum, toEnum, s.repo)
+	// then the convertible values are migrated and the other one is kept
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), 2, migrated)
+	assert.Equal(s.T(), 1, failed)
+	severity := func(id string) interface{} {
+		wi, err := s.repo.Load(ctx, id)
+		require.Nil(s.T(), err)
+		return wi.Fields["severity"]
+	}
+	assert.Equal(s.T(), "High", severity(highID))
+	assert.Equal(s.T(), "Low", severity(lowID))
+	assert.Equal(s.T(), "bogus", severity(bogusID))
+	// and converting in batches of one covers every work item
+	migrated, failed, err = s.repo.ConvertFieldValues(ctx, typeID, "severity", 1, func(value interface{}) (interface{}, error) {
+		return value, nil
+	})
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), 3, migrated)
+	assert.Equal(s.T(), 0, failed)
+	// and migrating to the same kind is rejected
+	_, _, err = workitem.MigrateFieldKind(ctx, typeID, "severity", workitem.KindString, workitem.KindString, toEnum, s.repo)
+	assert.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+}
@@ -1,13 +1,24 @@
 package workitem
 
 import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"fmt"
+	"html"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
+	"golang.org/x/net/context"
+
 	"github.com/almighty/almighty-core/app"
 	"github.com/almighty/almighty-core/convert"
+	"github.com/almighty/almighty-core/errors"
 	"github.com/almighty/almighty-core/gormsupport"
-	"github.com/pkg/errors"
+	"github.com/almighty/almighty-core/rendering"
+	errs "github.com/pkg/errors"
 	satoriuuid "github.com/satori/go.uuid"
 )
 
@@ -29,6 +40,9 @@ const (
 	SystemArea                = "system.area"
 	SystemCodebase            = "system.codebase"
 
+	// systemFieldPrefix is the prefix of the names of all system fields
+	systemFieldPrefix = "system."
+
 	SystemStateOpen       = "open"
 	SystemStateNew        = "new"
 	SystemStateInProgress = "in progress"
@@ -49,6 +63,28 @@ var (
 	SystemBug              = satoriuuid.FromStringOrNil("26787039-b68f-4e28-8814-c2f93be1ef4e") // "bug"
 )
 
+// DefaultIcon is the icon of a work item type for which neither the type
+// itself nor any of the types it extends defines an icon (see ResolveIcon).
+const DefaultIcon = "fa-question"
+
+// DefaultMaxFieldCount is the maximum number of fields a work item type may
+// have unless a different limit is configured (see CheckFieldCount).
+const DefaultMaxFieldCount = 100
+
+// readOnlyFields contains the names of the fields that are managed by the
+// system and hence are marked read-only by default.
+var readOnlyFields = map[string]bool{
+	SystemCreatedAt:    true,
+	SystemCreator:      true,
+	SystemRemoteItemID: true,
+}
+
+// IsReadOnlyByDefault returns true if the field with the given name is
+// managed by the system and is therefore read-only by default.
+func IsReadOnlyByDefault(fieldName string) bool {
+	return readOnlyFields[fieldName]
+}
+
 // WorkItemType represents a work item type as it is stored in the db
 type WorkItemType struct {
 	gormsupport.Lifecycle
@@ -66,6 +102,9 @@ type WorkItemType struct {
 	Path string
 	// definitions of the fields this work item type supports
 	Fields FieldDefinitions `sql:"type:jsonb"`
+	// the space this work item type belongs to; types of the system space
+	// are shared by all spaces
+	SpaceID satoriuuid.UUID `sql:"type:uuid"`
 }
 
 // GetTypePathSeparator returns the work item type's path separator "."
@@ -137,41 +176,306 @@ func (wit WorkItemType) Equal(u convert.Equaler) bool {
 	if wit.Path != other.Path {
 		return false
 	}
-	if len(wit.Fields) != len(other.Fields) {
+	if !satoriuuid.Equal(wit.SpaceID, other.SpaceID) {
+		return false
+	}
+	return wit.Fields.Equal(other.Fields)
+}
+
+// EqualNormalized returns true if two WorkItemType objects are equal when
+// ignoring whitespace differences in their descriptions; otherwise false is
+// returned. Surrounding whitespace of the descriptions is trimmed and
+// internal runs of whitespace are collapsed into a single space before
+// comparing them. All other fields are compared exactly as in Equal.
+func (wit WorkItemType) EqualNormalized(u convert.Equaler) bool {
+	other, ok := u.(WorkItemType)
+	if !ok {
 		return false
 	}
-	for witKey, witVal := range wit.Fields {
-		otherVal, keyFound := other.Fields[witKey]
-		if !keyFound {
-			return false
+	wit.Description = normalizeWhitespace(wit.Description)
+	other.Description = normalizeWhitespace(other.Description)
+	return wit.Equal(other)
+}
+
+// normalizeWhitespace returns a copy of the given string with surrounding
+// whitespace trimmed and internal runs of whitespace collapsed into a single
+// space. A nil pointer is returned as is.
+func normalizeWhitespace(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	normalized := strings.Join(strings.Fields(*s), " ")
+	return &normalized
+}
+
+// ConvertFromModel converts a workItem from the persistence layer into a workItem of the API layer.
+// Hidden fields are omitted.
+func (wit WorkItemType) ConvertFromModel(workItem WorkItem) (*app.WorkItem, error) {
+	return wit.ConvertFromModelWithProjection(workItem, false)
+}
+
+// ConvertFromModelWithProjection converts a workItem from the persistence
+// layer into a workItem of the API layer. Hidden fields are only included if
+// includeHidden is true. Fields are converted in the order given by
+// FieldDefinitions.OrderedNames; as the resulting fields are a map, clients
+// restore that order from the positions in the work item type.
+func (wit WorkItemType) ConvertFromModelWithProjection(workItem WorkItem, includeHidden bool) (*app.WorkItem, error) {
+	return wit.convertFromModelFields(workItem, wit.projectedFieldNames(includeHidden))
+}
+
+// ConvertManyFromModel converts the given work items of this type from the
+// persistence layer into work items of the API layer. The result is the same
+// as calling ConvertFromModel for each item but the fields to convert are
+// only determined once. The first conversion error is returned together with
+// the ID of the offending work item.
+func (wit WorkItemType) ConvertManyFromModel(items []WorkItem) ([]*app.WorkItem, error) {
+	names := wit.projectedFieldNames(false)
+	result := make([]*app.WorkItem, len(items))
+	for i, item := range items {
+		converted, err := wit.convertFromModelFields(item, names)
+		if err != nil {
+			return nil, errs.Wrapf(err, "failed to convert work item %d", item.ID)
 		}
-		if !witVal.Equal(otherVal) {
-			return false
+		result[i] = converted
+	}
+	return result, nil
+}
+
+// projectedFieldNames returns the ordered names of the fields that are
+// converted from the model. Hidden fields are only included if includeHidden
+// is true.
+func (wit WorkItemType) projectedFieldNames(includeHidden bool) []string {
+	var names []string
+	for _, name := range wit.Fields.OrderedNames() {
+		if name == SystemCreatedAt {
+			continue
 		}
+		if wit.Fields[name].Hidden && !includeHidden {
+			continue
+		}
+		names = append(names, name)
 	}
-	return true
+	return names
 }
 
-// ConvertFromModel converts a workItem from the persistence layer into a workItem of the API layer
-func (wit WorkItemType) ConvertFromModel(workItem WorkItem) (*app.WorkItem, error) {
+// convertFromModelFields converts the given fields of a workItem from the
+// persistence layer into a workItem of the API layer.
+func (wit WorkItemType) convertFromModelFields(workItem WorkItem, names []string) (*app.WorkItem, error) {
 	result := app.WorkItem{
 		ID:      strconv.FormatUint(workItem.ID, 10),
 		Type:    workItem.Type,
 		Version: workItem.Version,
 		Fields:  map[string]interface{}{}}
 
+	for _, name := range names {
+		var err error
+		result.Fields[name], err = wit.Fields[name].ConvertFromModel(name, workItem.Fields[name])
+		if err != nil {
+			return nil, errs.WithStack(err)
+		}
+	}
+
+	return &result, nil
+}
+
+// ConvertToModel converts the field values sent by a client into the field
+// values of the persistence layer. Setting a read-only field is not allowed
+// and results in a BadParameterError.
+func (wit WorkItemType) ConvertToModel(fields map[string]interface{}) (Fields, error) {
+	result := Fields{}
 	for name, field := range wit.Fields {
+		value, isSet := fields[name]
+		if field.ReadOnly {
+			if isSet {
+				return nil, errors.NewBadParameterError(name, value).Expected("read-only field not to be set")
+			}
+			continue
+		}
 		var err error
-		if name == SystemCreatedAt {
+		result[name], err = field.ConvertToModel(name, value)
+		if err != nil {
+			return nil, errs.Wrap(errors.NewBadParameterError(name, value), err.Error())
+		}
+	}
+	return result, nil
+}
+
+// UserFields returns a new map holding the given field values except for the
+// system fields, i.e. the fields whose name starts with "system.". The given
+// map is not modified.
+func (wit WorkItemType) UserFields(fields map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for name, value := range fields {
+		if !strings.HasPrefix(name, systemFieldPrefix) {
+			result[name] = value
+		}
+	}
+	return result
+}
+
+// ActiveFields returns the field definitions of the work item type that are
+// not deprecated. Use it to decide which fields to offer when creating a work
+// item; deprecated fields are still part of the type and converted as usual.
+func (wit WorkItemType) ActiveFields() FieldDefinitions {
+	result := FieldDefinitions{}
+	for name, def := range wit.Fields {
+		if !def.Deprecated {
+			result[name] = def
+		}
+	}
+	return result
+}
+
+// FieldChange describes how the value of a single field differs between two
+// versions of a work item.
+type FieldChange struct {
+	Name     string
+	OldValue interface{}
+	NewValue interface{}
+}
+
+// FieldChanges returns the changed field values between oldFields and
+// newFields sorted by field name. Only the fields of the work item type are
+// compared and the fields managed by the system (see IsReadOnlyByDefault) are
+// skipped. Values are compared according to the kind of their field: numbers
+// are equal if their values are equal regardless of their Go type and lists
+// are equal if they contain the same elements regardless of their order.
+// returns ConversionError if a value doesn't match the kind of its field
+func (wit WorkItemType) FieldChanges(oldFields, newFields map[string]interface{}) ([]FieldChange, error) {
+	names := make([]string, 0, len(wit.Fields))
+	for name := range wit.Fields {
+		if !IsReadOnlyByDefault(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []FieldChange
+	for _, name := range names {
+		oldValue, newValue := oldFields[name], newFields[name]
+		equal, err := fieldValuesEqual(wit.Fields[name].Type, oldValue, newValue)
+		if err != nil {
+			return nil, errors.NewConversionError(fmt.Sprintf("failed to compare values of field %s: %s", name, err.Error()))
+		}
+		if !equal {
+			changes = append(changes, FieldChange{Name: name, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+	return changes, nil
+}
+
+// fieldValuesEqual returns true if the given values of a field of the given
+// type are equal.
+func fieldValuesEqual(fieldType FieldType, l, r interface{}) (bool, error) {
+	if l == nil || r == nil {
+		return l == nil && r == nil, nil
+	}
+	if listType, ok := fieldType.(ListType); ok {
+		return listValuesEqual(listType.ComponentType, l, r)
+	}
+	switch fieldType.GetKind() {
+	case KindInteger, KindFloat, KindDuration:
+		lf, ok := toFloat64(l)
+		if !ok {
+			return false, fmt.Errorf("value %v should be a number, but is %s", l, reflect.TypeOf(l))
+		}
+		rf, ok := toFloat64(r)
+		if !ok {
+			return false, fmt.Errorf("value %v should be a number, but is %s", r, reflect.TypeOf(r))
+		}
+		return lf == rf, nil
+	}
+	return reflect.DeepEqual(l, r), nil
+}
+
+// listValuesEqual returns true if the given lists contain the same elements
+// regardless of their order.
+func listValuesEqual(componentType SimpleType, l, r interface{}) (bool, error) {
+	identity := func(_ FieldType, value interface{}) (interface{}, error) {
+		return value, nil
+	}
+	left, err := convertList(identity, componentType, l)
+	if err != nil {
+		return false, err
+	}
+	right, err := convertList(identity, componentType, r)
+	if err != nil {
+		return false, err
+	}
+	if len(left) != len(right) {
+		return false, nil
+	}
+	matched := make([]bool, len(right))
+	for _, lv := range left {
+		found := false
+		for i, rv := range right {
+			if matched[i] {
+				continue
+			}
+			equal, err := fieldValuesEqual(componentType, lv, rv)
+			if err != nil {
+				return false, err
+			}
+			if equal {
+				matched[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// EffectiveFields returns the fields of the given work item type including
+// the fields of all its ancestors as referenced by the type's Path. Fields
+// are merged from the root type down to the given type so that a subtype's
+// field definition overrides the one of an ancestor with the same name.
+// An error is returned if an ancestor cannot be loaded.
+func EffectiveFields(ctx context.Context, wit WorkItemType, loader WorkItemTypeLoader) (FieldDefinitions, error) {
+	result := FieldDefinitions{}
+	ancestors, err := pathIDs(wit.Path)
+	if err != nil {
+		return nil, errs.WithStack(err)
+	}
+	for _, id := range ancestors {
+		if satoriuuid.Equal(id, wit.ID) {
 			continue
 		}
-		result.Fields[name], err = field.ConvertFromModel(name, workItem.Fields[name])
+		ancestor, err := loader.LoadTypeFromDB(ctx, id)
 		if err != nil {
-			return nil, errors.WithStack(err)
+			return nil, errs.Wrapf(err, "failed to load ancestor %s of work item type %s", id, wit.ID)
+		}
+		for name, def := range ancestor.Fields {
+			result[name] = def
 		}
 	}
+	for name, def := range wit.Fields {
+		result[name] = def
+	}
+	return result, nil
+}
 
-	return &result, nil
+// pathIDs returns the work item type IDs of the given path, starting with the
+// root. An empty path describes a root type that is not stored with its own
+// ID and therefore has no IDs. A ConversionError is returned if a segment of
+// the path is not an ltree safe ID (see LtreeSafeID).
+func pathIDs(path string) ([]satoriuuid.UUID, error) {
+	if path == "" {
+		return nil, nil
+	}
+	segments := strings.Split(path, pathSep)
+	ids := make([]satoriuuid.UUID, len(segments))
+	for i, segment := range segments {
+		id, err := satoriuuid.FromString(strings.Replace(segment, "_", "-", -1))
+		if err != nil {
+			return nil, errors.NewConversionError(fmt.Sprintf("invalid work item type ID %s in path %s", segment, path))
+		}
+		ids[i] = id
+	}
+	return ids, nil
 }
 
 // IsTypeOrSubtypeOf returns true if the work item type with the given type ID,
@@ -182,3 +486,430 @@ func (wit WorkItemType) IsTypeOrSubtypeOf(typeID satoriuuid.UUID) bool {
 	// and for suffix (e.g. ".cake" is the suffix of "foo.bar.cake").
 	return satoriuuid.Equal(wit.ID, typeID) || strings.Contains(wit.Path, LtreeSafeID(typeID)+pathSep)
 }
+
+// LowestCommonAncestor returns the ID of the deepest work item type that both
+// given types are or extend, as determined by comparing their paths from the
+// root. If one type is an ancestor of the other, the ancestor's ID is
+// returned. If the types share no ancestor, false is returned.
+func LowestCommonAncestor(a, b WorkItemType) (satoriuuid.UUID, bool) {
+	pathA, err := pathIDs(a.Path)
+	if err != nil {
+		return satoriuuid.Nil, false
+	}
+	pathB, err := pathIDs(b.Path)
+	if err != nil {
+		return satoriuuid.Nil, false
+	}
+	common, found := satoriuuid.Nil, false
+	for i := 0; i < len(pathA) && i < len(pathB) && satoriuuid.Equal(pathA[i], pathB[i]); i++ {
+		common, found = pathA[i], true
+	}
+	return common, found
+}
+
+// CheckNoInheritanceCycle returns a BadParameterError if the path of the
+// given work item type describes a cycle in the type hierarchy, i.e. if a
+// segment of the path occurs more than once or if the type's own ID occurs
+// anywhere but at the end of the path.
+func CheckNoInheritanceCycle(wit WorkItemType) error {
+	segments := strings.Split(wit.Path, pathSep)
+	ownID := wit.LtreeSafeID()
+	seen := map[string]struct{}{}
+	for i, segment := range segments {
+		if _, ok := seen[segment]; ok {
+			return errors.NewBadParameterError("path", wit.Path).Expected(fmt.Sprintf("a path in which %s occurs only once", segment))
+		}
+		seen[segment] = struct{}{}
+		if segment == ownID && i != len(segments)-1 {
+			return errors.NewBadParameterError("path", wit.Path).Expected(fmt.Sprintf("a path in which the type's own ID %s is the last segment", ownID))
+		}
+	}
+	return nil
+}
+
+// CheckPathImmutable returns a BadParameterError if the path of the work item
+// type differs from the path of the given existing (stored) version of it.
+// The path establishes the type hierarchy that IsTypeOrSubtypeOf relies on
+// and must therefore never change after creation. Other attributes like the
+// name may be changed freely.
+func (wit WorkItemType) CheckPathImmutable(existing WorkItemType) error {
+	if wit.Path != existing.Path {
+		return errors.NewBadParameterError("path", wit.Path).Expected(existing.Path)
+	}
+	return nil
+}
+
+// CheckFieldCount returns a BadParameterError if the work item type has more
+// than maxFieldCount fields. Inherited and system fields count toward the
+// limit.
+func (wit WorkItemType) CheckFieldCount(maxFieldCount int) error {
+	if len(wit.Fields) > maxFieldCount {
+		return errors.NewBadParameterError("fields", len(wit.Fields)).Expected(fmt.Sprintf("at most %d fields", maxFieldCount))
+	}
+	return nil
+}
+
+// CheckFieldKinds returns a BadParameterError listing the kinds used by the
+// fields of the work item type (including the component kinds of lists and
+// the base kinds of enums) that are not in SupportedFieldKinds.
+func (wit WorkItemType) CheckFieldKinds() error {
+	unknown := map[string]bool{}
+	check := func(kind Kind) {
+		if !SupportedFieldKinds.IsSupported(string(kind)) {
+			unknown[string(kind)] = true
+		}
+	}
+	for _, def := range wit.Fields {
+		if def.Type == nil {
+			continue
+		}
+		check(def.Type.GetKind())
+		switch t := def.Type.(type) {
+		case ListType:
+			check(t.ComponentType.Kind)
+		case EnumType:
+			check(t.BaseType.Kind)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	kinds := make([]string, 0, len(unknown))
+	for kind := range unknown {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return errors.NewBadParameterError("fields", strings.Join(kinds, ", ")).Expected("supported field kinds")
+}
+
+// mandatorySystemFields lists the system fields every work item type must
+// define (see CheckHasRequiredSystemFields).
+var mandatorySystemFields = []string{SystemTitle, SystemState}
+
+// CheckHasRequiredSystemFields returns a BadParameterError listing the
+// mandatory system fields (e.g. system.title and system.state) that the work
+// item type doesn't define. Work items of such a type would be unusable.
+func (wit WorkItemType) CheckHasRequiredSystemFields() error {
+	var missing []string
+	for _, name := range mandatorySystemFields {
+		if _, ok := wit.Fields[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.NewBadParameterError("fields", strings.Join(missing, ", ")).Expected("definitions of the mandatory system fields")
+	}
+	return nil
+}
+
+// ValidStates returns the allowed values of the "system.state" field of the
+// work item type in the order in which they are defined. A BadParameterError
+// is returned if the type has no state field or if the field is not an enum
+// of strings.
+func (wit WorkItemType) ValidStates() ([]string, error) {
+	def, ok := wit.Fields[SystemState]
+	if !ok {
+		return nil, errors.NewBadParameterError("fields", SystemState).Expected("a definition of the state field")
+	}
+	enumType, ok := def.Type.(EnumType)
+	if !ok {
+		return nil, errors.NewBadParameterError(SystemState, def.Type.GetKind()).Expected(KindEnum)
+	}
+	states := make([]string, len(enumType.Values))
+	for i, value := range enumType.Values {
+		state, ok := value.(string)
+		if !ok {
+			return nil, errors.NewBadParameterError(SystemState, value).Expected("string value")
+		}
+		states[i] = state
+	}
+	return states, nil
+}
+
+// NewWorkItem returns the field values of a new work item of this type. Every
+// field is set to its default value or, if it has none, to the zero value of
+// its kind (see zeroValue). Required fields without a default value are set
+// to nil so that they show up as missing. Fields managed by the system (see
+// IsReadOnlyByDefault) are left out.
+// returns BadParameterError if a default value is invalid
+func (wit WorkItemType) NewWorkItem() (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	for name, def := range wit.Fields {
+		if IsReadOnlyByDefault(name) {
+			continue
+		}
+		if def.DefaultValue != nil {
+			if err := def.ValidateDefault(); err != nil {
+				return nil, errs.Wrapf(err, "invalid default value of field %s", name)
+			}
+			fields[name] = def.DefaultValue
+			continue
+		}
+		if def.Required {
+			fields[name] = nil
+			continue
+		}
+		fields[name] = zeroValue(def.Type.GetKind())
+	}
+	return fields, nil
+}
+
+// zeroValue returns the value an unset field of the given kind is
+// initialized with: an empty string for text, zero for numbers, false for
+// booleans, an empty list for lists and nil for all other kinds.
+func zeroValue(kind Kind) interface{} {
+	switch kind {
+	case KindString, KindMarkup:
+		return ""
+	case KindInteger:
+		return 0
+	case KindFloat:
+		return 0.0
+	case KindBoolean:
+		return false
+	case KindList:
+		return []interface{}{}
+	}
+	return nil
+}
+
+// CheckStateTransition returns a BadParameterError if the state transitions
+// of the "system.state" field of the work item type don't allow changing the
+// state from the given state to the given state. All changes are allowed if
+// the type defines no state transitions; keeping the state is always allowed.
+func (wit WorkItemType) CheckStateTransition(from, to string) error {
+	def, ok := wit.Fields[SystemState]
+	if !ok || len(def.StateTransitions) == 0 || from == to {
+		return nil
+	}
+	allowed := def.StateTransitions[from]
+	for _, state := range allowed {
+		if state == to {
+			return nil
+		}
+	}
+	return errors.NewBadParameterError(SystemState, to).Expected(fmt.Sprintf("one of %v after state %s", allowed, from))
+}
+
+// SpaceMembershipRepository tells whether an identity is a member of a space
+type SpaceMembershipRepository interface {
+	IsMember(ctx context.Context, spaceID satoriuuid.UUID, identityID satoriuuid.UUID) (bool, error)
+}
+
+// ValidateAssignees returns a BadParameterError listing the given assignees
+// that are not members of the given space. An empty list of assignees is
+// always valid.
+// returns BadParameterError or the errors of the given repository
+func (wit WorkItemType) ValidateAssignees(ctx context.Context, assignees []satoriuuid.UUID, spaceID satoriuuid.UUID, repo SpaceMembershipRepository) error {
+	var nonMembers []string
+	for _, assignee := range assignees {
+		member, err := repo.IsMember(ctx, spaceID, assignee)
+		if err != nil {
+			return errs.WithStack(err)
+		}
+		if !member {
+			nonMembers = append(nonMembers, assignee.String())
+		}
+	}
+	if len(nonMembers) > 0 {
+		return errors.NewBadParameterError(SystemAssignees, strings.Join(nonMembers, ", ")).Expected(fmt.Sprintf("members of space %s", spaceID))
+	}
+	return nil
+}
+
+// CheckFieldKeyUniqueness returns a BadParameterError listing the field keys
+// of the work item type that only differ in case (e.g. "priority" and
+// "Priority"). System fields are always lowercase and are therefore exempt.
+func (wit WorkItemType) CheckFieldKeyUniqueness() error {
+	names := make([]string, 0, len(wit.Fields))
+	for name := range wit.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	keysByLower := map[string][]string{}
+	var lowerKeys []string
+	for _, name := range names {
+		if strings.HasPrefix(name, systemFieldPrefix) {
+			continue
+		}
+		lower := strings.ToLower(name)
+		if _, ok := keysByLower[lower]; !ok {
+			lowerKeys = append(lowerKeys, lower)
+		}
+		keysByLower[lower] = append(keysByLower[lower], name)
+	}
+	var collisions []string
+	for _, lower := range lowerKeys {
+		if keys := keysByLower[lower]; len(keys) > 1 {
+			collisions = append(collisions, strings.Join(keys, "/"))
+		}
+	}
+	if len(collisions) > 0 {
+		return errors.NewBadParameterError("fields", strings.Join(collisions, ", ")).Expected("field keys that differ in more than case")
+	}
+	return nil
+}
+
+// CheckValidForCreation returns an error if the work item type cannot be
+// created because one of its field definitions is invalid (see
+// FieldDefinition.Validate) or because CheckFieldCount with the given
+// maxFieldCount, CheckHasRequiredSystemFields, CheckFieldKinds or
+// CheckFieldKeyUniqueness fails for it.
+func (wit WorkItemType) CheckValidForCreation(maxFieldCount int) error {
+	names := make([]string, 0, len(wit.Fields))
+	for name := range wit.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := wit.Fields[name].Validate(); err != nil {
+			return errs.WithStack(err)
+		}
+	}
+	if err := wit.CheckFieldCount(maxFieldCount); err != nil {
+		return errs.WithStack(err)
+	}
+	if err := wit.CheckHasRequiredSystemFields(); err != nil {
+		return errs.WithStack(err)
+	}
+	if err := wit.CheckFieldKinds(); err != nil {
+		return errs.WithStack(err)
+	}
+	return errs.WithStack(wit.CheckFieldKeyUniqueness())
+}
+
+// CheckCompatibleWithParent returns a BadParameterError if the work item type
+// changes the kind of a field it inherits from the given parent type. For
+// list and enum fields the component and base kinds must match as well.
+// Adding new fields or overriding the label, description or default value of
+// an inherited field is allowed.
+func (wit WorkItemType) CheckCompatibleWithParent(parent WorkItemType) error {
+	names := make([]string, 0, len(parent.Fields))
+	for name := range parent.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		def, overridden := wit.Fields[name]
+		if !overridden {
+			continue
+		}
+		parentKind := describeKind(parent.Fields[name].Type)
+		if kind := describeKind(def.Type); kind != parentKind {
+			return errors.NewBadParameterError(name, kind).Expected(fmt.Sprintf("kind %s inherited from work item type %s", parentKind, parent.Name))
+		}
+	}
+	return nil
+}
+
+// describeKind returns the kind of the given field type including the
+// component kind of a list and the base kind of an enum, e.g. "list<string>".
+func describeKind(t FieldType) string {
+	switch t2 := t.(type) {
+	case ListType:
+		return fmt.Sprintf("%s<%s>", t2.GetKind(), t2.ComponentType.GetKind())
+	case EnumType:
+		return fmt.Sprintf("%s<%s>", t2.GetKind(), t2.BaseType.GetKind())
+	}
+	return string(t.GetKind())
+}
+
+// ValidateDescriptionConsistency returns a BadParameterError if the
+// description related values in the given fields contradict each other: the
+// value of SystemDescriptionMarkup must be a supported markup and match the
+// markup of the description itself, and SystemDescriptionRendered must be
+// the description rendered with that markup. An absent description is
+// always valid.
+func (wit WorkItemType) ValidateDescriptionConsistency(fields map[string]interface{}) error {
+	description := rendering.NewMarkupContentFromValue(fields[SystemDescription])
+	if description == nil {
+		return nil
+	}
+	markup := description.Markup
+	if value, ok := fields[SystemDescriptionMarkup]; ok && value != nil {
+		m, isString := value.(string)
+		if !isString || !rendering.IsMarkupSupported(m) {
+			return errors.NewBadParameterError(SystemDescriptionMarkup, value).Expected(fmt.Sprintf("%s or %s", rendering.SystemMarkupPlainText, rendering.SystemMarkupMarkdown))
+		}
+		if m != description.Markup {
+			return errors.NewBadParameterError(SystemDescriptionMarkup, m).Expected(description.Markup)
+		}
+		markup = m
+	}
+	if value, ok := fields[SystemDescriptionRendered]; ok && value != nil {
+		expected := rendering.RenderMarkupToHTML(html.EscapeString(description.Content), markup)
+		if rendered, isString := value.(string); !isString || rendered != expected {
+			return errors.NewBadParameterError(SystemDescriptionRendered, value).Expected(fmt.Sprintf("description rendered as %s", markup))
+		}
+	}
+	return nil
+}
+
+func init() {
+	// register the concrete field types so that they can be encoded as
+	// FieldType interface values by MarshalBinary
+	gob.Register(SimpleType{})
+	gob.Register(EnumType{})
+	gob.Register(ListType{})
+}
+
+// wireWorkItemType holds all persisted fields of a WorkItemType for the
+// binary encoding. It exists to avoid MarshalBinary calling itself.
+type wireWorkItemType struct {
+	Lifecycle   gormsupport.Lifecycle
+	ID          satoriuuid.UUID
+	Name        string
+	Description *string
+	Icon        string
+	Version     int
+	Path        string
+	Fields      FieldDefinitions
+	SpaceID     satoriuuid.UUID
+}
+
+// Ensure WorkItemType implements the encoding.BinaryMarshaler and
+// encoding.BinaryUnmarshaler interfaces
+var _ encoding.BinaryMarshaler = WorkItemType{}
+var _ encoding.BinaryUnmarshaler = (*WorkItemType)(nil)
+
+// MarshalBinary implements encoding.BinaryMarshaler. It returns a compact
+// binary encoding of the work item type that can be used for caching.
+func (wit WorkItemType) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(wireWorkItemType{
+		Lifecycle:   wit.Lifecycle,
+		ID:          wit.ID,
+		Name:        wit.Name,
+		Description: wit.Description,
+		Icon:        wit.Icon,
+		Version:     wit.Version,
+		Path:        wit.Path,
+		Fields:      wit.Fields,
+		SpaceID:     wit.SpaceID,
+	})
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed to encode work item type %s", wit.ID)
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. It replaces the
+// work item type with the one encoded in the given data by MarshalBinary.
+func (wit *WorkItemType) UnmarshalBinary(data []byte) error {
+	var w wireWorkItemType
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&w); err != nil {
+		return errs.Wrap(err, "failed to decode work item type")
+	}
+	*wit = WorkItemType{
+		Lifecycle:   w.Lifecycle,
+		ID:          w.ID,
+		Name:        w.Name,
+		Description: w.Description,
+		Icon:        w.Icon,
+		Version:     w.Version,
+		Path:        w.Path,
+		Fields:      w.Fields,
+		SpaceID:     w.SpaceID,
+	}
+	return nil
+}
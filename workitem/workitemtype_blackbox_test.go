@@ -8,12 +8,18 @@ import (
 
 	"time"
 
+	"golang.org/x/net/context"
+
 	"github.com/almighty/almighty-core/convert"
+	"github.com/almighty/almighty-core/errors"
 	"github.com/almighty/almighty-core/gormsupport"
+	"github.com/almighty/almighty-core/rendering"
 	"github.com/almighty/almighty-core/resource"
 	"github.com/almighty/almighty-core/workitem"
+	errs "github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestJsonMarshalListType constructs a work item type, writes it to JSON (marshalling),
@@ -130,6 +136,11 @@ func TestWorkItemType_Equal(t *testing.T) {
 	f.Path = "foobar"
 	assert.False(t, a.Equal(f))
 
+	// Test space
+	fs := a
+	fs.SpaceID = uuid.NewV4()
+	assert.False(t, a.Equal(fs))
+
 	// Test field array length
 	g := a
 	g.Fields = map[string]workitem.FieldDefinition{}
@@ -240,3 +251,962 @@ func TestWorkItemTypeIsTypeOrSubtypeOf(t *testing.T) {
 	assert.False(t, workitem.WorkItemType{ID: id3, Path: node1 + "." + node2 + "." + node3}.IsTypeOrSubtypeOf(id4))
 	assert.False(t, workitem.WorkItemType{ID: id1, Path: node1}.IsTypeOrSubtypeOf(id4))
 }
+
+func TestLowestCommonAncestor(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	// Prepare a hierarchy: root -> a -> {b, c} and an unrelated type
+	root := uuid.FromStringOrNil("68e90fa9-dba1-4448-99a4-ae70fb2b45f9")
+	a := uuid.FromStringOrNil("aa6ef831-36db-4e99-9e33-6f793472f769")
+	b := uuid.FromStringOrNil("3566837f-aa98-4792-bce1-75c995d4e98c")
+	c := uuid.FromStringOrNil("c88e6669-53f9-4aa1-be98-877b850daf88")
+	unrelated := uuid.FromStringOrNil("0e671e36-871b-43a6-9166-0c4bd573e231")
+	rootType := workitem.WorkItemType{ID: root, Path: workitem.LtreeSafeID(root)}
+	aType := workitem.WorkItemType{ID: a, Path: rootType.Path + "." + workitem.LtreeSafeID(a)}
+	bType := workitem.WorkItemType{ID: b, Path: aType.Path + "." + workitem.LtreeSafeID(b)}
+	cType := workitem.WorkItemType{ID: c, Path: aType.Path + "." + workitem.LtreeSafeID(c)}
+	unrelatedType := workitem.WorkItemType{ID: unrelated, Path: workitem.LtreeSafeID(unrelated)}
+
+	// Test siblings
+	id, ok := workitem.LowestCommonAncestor(bType, cType)
+	assert.True(t, ok)
+	assert.Equal(t, a, id)
+
+	// Test ancestor/descendant pairs in both orders
+	id, ok = workitem.LowestCommonAncestor(rootType, bType)
+	assert.True(t, ok)
+	assert.Equal(t, root, id)
+	id, ok = workitem.LowestCommonAncestor(bType, aType)
+	assert.True(t, ok)
+	assert.Equal(t, a, id)
+
+	// Test the same type
+	id, ok = workitem.LowestCommonAncestor(bType, bType)
+	assert.True(t, ok)
+	assert.Equal(t, b, id)
+
+	// Test unrelated types
+	_, ok = workitem.LowestCommonAncestor(bType, unrelatedType)
+	assert.False(t, ok)
+
+	// Test a type with an empty path
+	_, ok = workitem.LowestCommonAncestor(bType, workitem.WorkItemType{ID: unrelated})
+	assert.False(t, ok)
+}
+
+func TestCheckNoInheritanceCycle(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	root := uuid.FromStringOrNil("68e90fa9-dba1-4448-99a4-ae70fb2b45f9")
+	a := uuid.FromStringOrNil("aa6ef831-36db-4e99-9e33-6f793472f769")
+	b := uuid.FromStringOrNil("3566837f-aa98-4792-bce1-75c995d4e98c")
+	rootID := workitem.LtreeSafeID(root)
+	aID := workitem.LtreeSafeID(a)
+	bID := workitem.LtreeSafeID(b)
+
+	// Test valid paths
+	assert.Nil(t, workitem.CheckNoInheritanceCycle(workitem.WorkItemType{ID: root, Path: rootID}))
+	assert.Nil(t, workitem.CheckNoInheritanceCycle(workitem.WorkItemType{ID: b, Path: rootID + "." + aID + "." + bID}))
+
+	// Test repeated segment
+	err := workitem.CheckNoInheritanceCycle(workitem.WorkItemType{ID: b, Path: rootID + "." + aID + "." + rootID + "." + bID})
+	assert.IsType(t, errors.BadParameterError{}, err)
+
+	// Test self-referencing cycle
+	err = workitem.CheckNoInheritanceCycle(workitem.WorkItemType{ID: a, Path: rootID + "." + aID + "." + bID})
+	assert.IsType(t, errors.BadParameterError{}, err)
+}
+
+func TestWorkItemTypeConvertReadOnlyFields(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	wit := workitem.WorkItemType{
+		Name: "foo",
+		Fields: map[string]workitem.FieldDefinition{
+			workitem.SystemTitle: {
+				Type:     workitem.SimpleType{Kind: workitem.KindString},
+				Required: true,
+			},
+			workitem.SystemCreator: {
+				Type:     workitem.SimpleType{Kind: workitem.KindUser},
+				ReadOnly: true,
+			},
+		},
+	}
+
+	// Test that setting a read-only field on input is rejected
+	_, err := wit.ConvertToModel(map[string]interface{}{
+		workitem.SystemTitle:   "some title",
+		workitem.SystemCreator: "me",
+	})
+	require.NotNil(t, err)
+	require.IsType(t, errors.BadParameterError{}, err)
+
+	// Test that the other fields are converted and the read-only field is skipped
+	fields, err := wit.ConvertToModel(map[string]interface{}{
+		workitem.SystemTitle: "some title",
+	})
+	require.Nil(t, err)
+	assert.Equal(t, "some title", fields[workitem.SystemTitle])
+	_, found := fields[workitem.SystemCreator]
+	assert.False(t, found)
+
+	// Test that a read-only field is still present on output
+	result, err := wit.ConvertFromModel(workitem.WorkItem{
+		ID: 1,
+		Fields: workitem.Fields{
+			workitem.SystemTitle:   "some title",
+			workitem.SystemCreator: "me",
+		},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, "me", result.Fields[workitem.SystemCreator])
+}
+
+func TestIsReadOnlyByDefault(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	assert.True(t, workitem.IsReadOnlyByDefault(workitem.SystemCreatedAt))
+	assert.True(t, workitem.IsReadOnlyByDefault(workitem.SystemCreator))
+	assert.True(t, workitem.IsReadOnlyByDefault(workitem.SystemRemoteItemID))
+	assert.False(t, workitem.IsReadOnlyByDefault(workitem.SystemTitle))
+}
+
+func TestEffectiveFields(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	parent := workitem.WorkItemType{
+		ID:   uuid.FromStringOrNil("0b6e6a6e-6d49-4ee5-8b0c-2a2f8f0f1a01"),
+		Name: "parent",
+		Fields: map[string]workitem.FieldDefinition{
+			"inherited":  {Type: workitem.SimpleType{Kind: workitem.KindString}, Label: "inherited"},
+			"overridden": {Type: workitem.SimpleType{Kind: workitem.KindString}, Label: "parent label"},
+		},
+	}
+	parent.Path = parent.LtreeSafeID()
+	child := workitem.WorkItemType{
+		ID:   uuid.FromStringOrNil("0b6e6a6e-6d49-4ee5-8b0c-2a2f8f0f1a02"),
+		Name: "child",
+		Fields: map[string]workitem.FieldDefinition{
+			"overridden": {Type: workitem.SimpleType{Kind: workitem.KindString}, Label: "child label"},
+			"own":        {Type: workitem.SimpleType{Kind: workitem.KindInteger}, Label: "own"},
+		},
+	}
+	child.Path = parent.Path + workitem.GetTypePathSeparator() + child.LtreeSafeID()
+	loader := mapLoader{parent.ID: parent}
+
+	// Test two-level hierarchy
+	fields, err := workitem.EffectiveFields(context.Background(), child, loader)
+	require.Nil(t, err)
+	require.Len(t, fields, 3)
+	assert.Equal(t, "inherited", fields["inherited"].Label)
+	assert.Equal(t, "child label", fields["overridden"].Label)
+	assert.Equal(t, "own", fields["own"].Label)
+
+	// Test root type has only its own fields
+	fields, err = workitem.EffectiveFields(context.Background(), parent, loader)
+	require.Nil(t, err)
+	assert.True(t, parent.Fields.Equal(fields))
+
+	// Test a type with an empty path is treated as a root type
+	noPath := parent
+	noPath.Path = ""
+	fields, err = workitem.EffectiveFields(context.Background(), noPath, mapLoader{})
+	require.Nil(t, err)
+	assert.True(t, parent.Fields.Equal(fields))
+
+	// Test invalid path segment
+	invalidPath := child
+	invalidPath.Path = "not_a_uuid." + child.LtreeSafeID()
+	_, err = workitem.EffectiveFields(context.Background(), invalidPath, loader)
+	require.IsType(t, errors.ConversionError{}, errs.Cause(err))
+
+	// Test missing ancestor
+	_, err = workitem.EffectiveFields(context.Background(), child, mapLoader{})
+	require.NotNil(t, err)
+}
+
+// mapLoader is a WorkItemTypeLoader that loads the work item types from a map
+type mapLoader map[uuid.UUID]workitem.WorkItemType
+
+func (l mapLoader) LoadTypeFromDB(ctx context.Context, id uuid.UUID) (*workitem.WorkItemType, error) {
+	wit, ok := l[id]
+	if !ok {
+		return nil, errors.NewNotFoundError("work item type", id.String())
+	}
+	return &wit, nil
+}
+
+func TestWorkItemTypeCheckPathImmutable(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	id1 := uuid.FromStringOrNil("68e90fa9-dba1-4448-99a4-ae70fb2b45f9")
+	id2 := uuid.FromStringOrNil("aa6ef831-36db-4e99-9e33-6f793472f769")
+	existing := workitem.WorkItemType{
+		ID:   id2,
+		Name: "foo",
+		Path: workitem.LtreeSafeID(id1) + "." + workitem.LtreeSafeID(id2),
+	}
+
+	// Test unchanged type
+	updated := existing
+	assert.Nil(t, updated.CheckPathImmutable(existing))
+
+	// Test permitted name change
+	updated.Name = "bar"
+	assert.Nil(t, updated.CheckPathImmutable(existing))
+
+	// Test attempted path change
+	updated.Path = workitem.LtreeSafeID(id2)
+	err := updated.CheckPathImmutable(existing)
+	assert.IsType(t, errors.BadParameterError{}, err)
+}
+
+func TestWorkItemTypeValidateDescriptionConsistency(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+	wit := workitem.WorkItemType{Name: "foo"}
+
+	// Test absent description
+	assert.Nil(t, wit.ValidateDescriptionConsistency(map[string]interface{}{}))
+
+	// Test consistent Markdown
+	fields := map[string]interface{}{
+		workitem.SystemDescription:         rendering.NewMarkupContent("# title", rendering.SystemMarkupMarkdown),
+		workitem.SystemDescriptionMarkup:   rendering.SystemMarkupMarkdown,
+		workitem.SystemDescriptionRendered: rendering.RenderMarkupToHTML("# title", rendering.SystemMarkupMarkdown),
+	}
+	assert.Nil(t, wit.ValidateDescriptionConsistency(fields))
+
+	// Test Markdown rendered as PlainText
+	fields[workitem.SystemDescriptionRendered] = "# title"
+	assert.IsType(t, errors.BadParameterError{}, wit.ValidateDescriptionConsistency(fields))
+
+	// Test consistent PlainText
+	fields = map[string]interface{}{
+		workitem.SystemDescription:         rendering.NewMarkupContent("some text", rendering.SystemMarkupPlainText),
+		workitem.SystemDescriptionMarkup:   rendering.SystemMarkupPlainText,
+		workitem.SystemDescriptionRendered: "some text",
+	}
+	assert.Nil(t, wit.ValidateDescriptionConsistency(fields))
+
+	// Test markup that differs from the description's markup
+	fields[workitem.SystemDescriptionMarkup] = rendering.SystemMarkupMarkdown
+	assert.IsType(t, errors.BadParameterError{}, wit.ValidateDescriptionConsistency(fields))
+
+	// Test unknown markup
+	fields[workitem.SystemDescriptionMarkup] = "foo"
+	assert.IsType(t, errors.BadParameterError{}, wit.ValidateDescriptionConsistency(fields))
+}
+
+func TestWorkItemTypeMarshalBinary(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	now := time.Now()
+	a := workitem.WorkItemType{
+		Lifecycle: gormsupport.Lifecycle{CreatedAt: now, UpdatedAt: now},
+		ID:        uuid.FromStringOrNil("68e90fa9-dba1-4448-99a4-ae70fb2b45f9"),
+		Name:      "foo",
+		Icon:      "fa-bug",
+		Version:   3,
+		Path:      workitem.LtreeSafeID(uuid.FromStringOrNil("68e90fa9-dba1-4448-99a4-ae70fb2b45f9")),
+		SpaceID:   uuid.FromStringOrNil("2e0698d8-753e-4cef-bb7c-f027634824a2"),
+		Fields: workitem.FieldDefinitions{
+			"title": {Required: true, Label: "Title", Type: workitem.SimpleType{Kind: workitem.KindString}},
+			"state": {
+				Required: true,
+				Type: workitem.EnumType{
+					SimpleType: workitem.SimpleType{Kind: workitem.KindEnum},
+					BaseType:   workitem.SimpleType{Kind: workitem.KindString},
+					Values:     []interface{}{"new", "closed"},
+				},
+			},
+			"labels": {
+				Type: workitem.ListType{
+					SimpleType:    workitem.SimpleType{Kind: workitem.KindList},
+					ComponentType: workitem.SimpleType{Kind: workitem.KindString},
+				},
+			},
+		},
+	}
+
+	// Test round-trip with nil description
+	data, err := a.MarshalBinary()
+	require.Nil(t, err)
+	b := workitem.WorkItemType{}
+	require.Nil(t, b.UnmarshalBinary(data))
+	assert.Nil(t, b.Description)
+	assert.True(t, a.Equal(b))
+
+	// Test round-trip with description
+	description := "bar"
+	a.Description = &description
+	data, err = a.MarshalBinary()
+	require.Nil(t, err)
+	b = workitem.WorkItemType{}
+	require.Nil(t, b.UnmarshalBinary(data))
+	assert.True(t, a.Equal(b))
+
+	// Test invalid data
+	assert.NotNil(t, b.UnmarshalBinary([]byte("foo")))
+}
+
+func TestWorkItemTypeEqualNormalized(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	description := "A description\nwith  two lines"
+	a := workitem.WorkItemType{
+		ID:          uuid.FromStringOrNil("68e90fa9-dba1-4448-99a4-ae70fb2b45f9"),
+		Name:        "foo",
+		Description: &description,
+		Fields: workitem.FieldDefinitions{
+			"title": {Required: true, Type: workitem.SimpleType{Kind: workitem.KindString}},
+		},
+	}
+
+	// Test types differing only by description whitespace
+	otherDescription := "  A description with\ttwo lines \n"
+	b := a
+	b.Description = &otherDescription
+	assert.False(t, a.Equal(b))
+	assert.True(t, a.EqualNormalized(b))
+	assert.Equal(t, "A description\nwith  two lines", *a.Description)
+
+	// Test descriptions differing by content
+	otherDescription = "A different description"
+	assert.False(t, a.EqualNormalized(b))
+
+	// Test nil description
+	b.Description = nil
+	assert.False(t, a.EqualNormalized(b))
+	a.Description = nil
+	assert.True(t, a.EqualNormalized(b))
+
+	// Test other fields are still compared exactly
+	b.Name = "foo "
+	assert.False(t, a.EqualNormalized(b))
+
+	// Test types
+	assert.False(t, a.EqualNormalized(convert.DummyEqualer{}))
+}
+
+func TestWorkItemTypeFieldChanges(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	wit := workitem.WorkItemType{
+		Fields: workitem.FieldDefinitions{
+			workitem.SystemTitle:   {Type: workitem.SimpleType{Kind: workitem.KindString}},
+			workitem.SystemCreator: {Type: workitem.SimpleType{Kind: workitem.KindUser}, ReadOnly: true},
+			"estimate":             {Type: workitem.SimpleType{Kind: workitem.KindInteger}},
+			"labels": {Type: workitem.ListType{
+				SimpleType:    workitem.SimpleType{Kind: workitem.KindList},
+				ComponentType: workitem.SimpleType{Kind: workitem.KindString},
+			}},
+		},
+	}
+	oldFields := map[string]interface{}{
+		workitem.SystemTitle:   "old title",
+		workitem.SystemCreator: "jdoe",
+		"estimate":             5,
+		"labels":               []interface{}{"a", "b"},
+	}
+
+	// Test a changed scalar
+	newFields := map[string]interface{}{
+		workitem.SystemTitle:   "new title",
+		workitem.SystemCreator: "jdoe",
+		"estimate":             5,
+		"labels":               []interface{}{"a", "b"},
+	}
+	changes, err := wit.FieldChanges(oldFields, newFields)
+	require.Nil(t, err)
+	require.Equal(t, []workitem.FieldChange{{Name: workitem.SystemTitle, OldValue: "old title", NewValue: "new title"}}, changes)
+
+	// Test unchanged fields, a reordered list, a number of another Go type and
+	// a changed system-managed field
+	newFields = map[string]interface{}{
+		workitem.SystemTitle:   "old title",
+		workitem.SystemCreator: "someone else",
+		"estimate":             float64(5),
+		"labels":               []interface{}{"b", "a"},
+	}
+	changes, err = wit.FieldChanges(oldFields, newFields)
+	require.Nil(t, err)
+	require.Empty(t, changes)
+
+	// Test a changed list and a removed value
+	newFields = map[string]interface{}{
+		workitem.SystemTitle: "old title",
+		"labels":             []interface{}{"a", "a"},
+	}
+	changes, err = wit.FieldChanges(oldFields, newFields)
+	require.Nil(t, err)
+	require.Equal(t, []workitem.FieldChange{
+		{Name: "estimate", OldValue: 5, NewValue: nil},
+		{Name: "labels", OldValue: []interface{}{"a", "b"}, NewValue: []interface{}{"a", "a"}},
+	}, changes)
+
+	// Test a value that doesn't match the kind of its field
+	newFields = map[string]interface{}{
+		"estimate": "five",
+	}
+	_, err = wit.FieldChanges(oldFields, newFields)
+	require.IsType(t, errors.ConversionError{}, err)
+}
+
+func TestWorkItemTypeConvertFromModelWithProjection(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	wit := workitem.WorkItemType{
+		Fields: workitem.FieldDefinitions{
+			workitem.SystemTitle: {Type: workitem.SimpleType{Kind: workitem.KindString}},
+			"metadata":           {Type: workitem.SimpleType{Kind: workitem.KindString}, Hidden: true},
+		},
+	}
+	wi := workitem.WorkItem{
+		ID: 42,
+		Fields: workitem.Fields{
+			workitem.SystemTitle: "title",
+			"metadata":           "internal",
+		},
+	}
+
+	// Test hidden fields are omitted by default
+	result, err := wit.ConvertFromModel(wi)
+	require.Nil(t, err)
+	require.Equal(t, "title", result.Fields[workitem.SystemTitle])
+	require.NotContains(t, result.Fields, "metadata")
+	result, err = wit.ConvertFromModelWithProjection(wi, false)
+	require.Nil(t, err)
+	require.NotContains(t, result.Fields, "metadata")
+
+	// Test hidden fields are included when requested
+	result, err = wit.ConvertFromModelWithProjection(wi, true)
+	require.Nil(t, err)
+	require.Equal(t, "title", result.Fields[workitem.SystemTitle])
+	require.Equal(t, "internal", result.Fields["metadata"])
+}
+
+// newConvertManyTestData returns a work item type and n work items of it
+func newConvertManyTestData(n int) (workitem.WorkItemType, []workitem.WorkItem) {
+	wit := workitem.WorkItemType{
+		Fields: workitem.FieldDefinitions{
+			workitem.SystemTitle: {Type: workitem.SimpleType{Kind: workitem.KindString}, Required: true, Position: 1},
+			workitem.SystemState: {Type: workitem.SimpleType{Kind: workitem.KindString}, Position: 2},
+			"estimate":           {Type: workitem.SimpleType{Kind: workitem.KindFloat}},
+			"metadata":           {Type: workitem.SimpleType{Kind: workitem.KindString}, Hidden: true},
+		},
+	}
+	items := make([]workitem.WorkItem, n)
+	for i := range items {
+		items[i] = workitem.WorkItem{
+			ID:      uint64(i + 1),
+			Version: i,
+			Fields: workitem.Fields{
+				workitem.SystemTitle: fmt.Sprintf("title %d", i),
+				workitem.SystemState: workitem.SystemStateNew,
+				"estimate":           float64(i),
+				"metadata":           "internal",
+			},
+		}
+	}
+	return wit, items
+}
+
+func TestWorkItemTypeConvertManyFromModel(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	wit, items := newConvertManyTestData(5)
+
+	// Test batch conversion matches individual conversions
+	result, err := wit.ConvertManyFromModel(items)
+	require.Nil(t, err)
+	require.Len(t, result, len(items))
+	for i, item := range items {
+		expected, err := wit.ConvertFromModel(item)
+		require.Nil(t, err)
+		require.Equal(t, expected, result[i])
+	}
+
+	// Test empty batch
+	result, err = wit.ConvertManyFromModel(nil)
+	require.Nil(t, err)
+	require.Empty(t, result)
+
+	// Test the first conversion error names the offending work item
+	delete(items[3].Fields, workitem.SystemTitle)
+	_, err = wit.ConvertManyFromModel(items)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "work item 4")
+}
+
+func BenchmarkWorkItemTypeConvertManyFromModel(b *testing.B) {
+	wit, items := newConvertManyTestData(100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := wit.ConvertManyFromModel(items); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestWorkItemTypeActiveFields(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	wit := workitem.WorkItemType{
+		Fields: workitem.FieldDefinitions{
+			workitem.SystemTitle: {Type: workitem.SimpleType{Kind: workitem.KindString}},
+			"legacy":             {Type: workitem.SimpleType{Kind: workitem.KindString}, Deprecated: true},
+		},
+	}
+	wi := workitem.WorkItem{
+		ID: 42,
+		Fields: workitem.Fields{
+			workitem.SystemTitle: "title",
+			"legacy":             "old value",
+		},
+	}
+
+	// Test deprecated fields are still converted
+	result, err := wit.ConvertFromModel(wi)
+	require.Nil(t, err)
+	require.Equal(t, "old value", result.Fields["legacy"])
+
+	// Test deprecated fields are excluded from the active fields
+	active := wit.ActiveFields()
+	require.Len(t, active, 1)
+	require.Contains(t, active, workitem.SystemTitle)
+	require.NotContains(t, active, "legacy")
+}
+
+func TestFieldDefinitionsOrderedNames(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	wit := workitem.WorkItemType{
+		ID:   uuid.NewV4(),
+		Name: "ordered",
+		Fields: workitem.FieldDefinitions{
+			workitem.SystemTitle: {Type: workitem.SimpleType{Kind: workitem.KindString}, Position: 1},
+			workitem.SystemState: {Type: workitem.SimpleType{Kind: workitem.KindString}, Position: 2},
+			"effort":             {Type: workitem.SimpleType{Kind: workitem.KindFloat}, Position: 2},
+			"zeta":               {Type: workitem.SimpleType{Kind: workitem.KindString}},
+			"alpha":              {Type: workitem.SimpleType{Kind: workitem.KindString}},
+		},
+	}
+	expected := []string{workitem.SystemTitle, "effort", workitem.SystemState, "alpha", "zeta"}
+
+	// Test positioned fields come first and unpositioned fields are sorted by name
+	require.Equal(t, expected, wit.Fields.OrderedNames())
+
+	// Test the order survives the JSON storage of the fields
+	value, err := wit.Fields.Value()
+	require.Nil(t, err)
+	loadedFields := workitem.FieldDefinitions{}
+	require.Nil(t, loadedFields.Scan(value))
+	require.True(t, wit.Fields.Equal(loadedFields))
+	require.Equal(t, expected, loadedFields.OrderedNames())
+
+	// Test the order survives the binary encoding
+	data, err := wit.MarshalBinary()
+	require.Nil(t, err)
+	loaded := workitem.WorkItemType{}
+	require.Nil(t, loaded.UnmarshalBinary(data))
+	require.Equal(t, expected, loaded.Fields.OrderedNames())
+
+	// Test a changed position makes the definitions differ
+	changed := workitem.FieldDefinitions{}
+	for name, def := range wit.Fields {
+		changed[name] = def
+	}
+	def := changed["alpha"]
+	def.Position = 3
+	changed["alpha"] = def
+	require.False(t, wit.Fields.Equal(changed))
+}
+
+func TestWorkItemTypeCheckCompatibleWithParent(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	parent := workitem.WorkItemType{
+		Name: "parent",
+		Fields: workitem.FieldDefinitions{
+			workitem.SystemTitle: {Label: "Title", Type: workitem.SimpleType{Kind: workitem.KindString}},
+			"labels": {Type: workitem.ListType{
+				SimpleType:    workitem.SimpleType{Kind: workitem.KindList},
+				ComponentType: workitem.SimpleType{Kind: workitem.KindString},
+			}},
+		},
+	}
+
+	// Test compatible override of the label and a brand-new field
+	child := workitem.WorkItemType{
+		Name: "child",
+		Fields: workitem.FieldDefinitions{
+			workitem.SystemTitle: {Label: "Summary", Type: workitem.SimpleType{Kind: workitem.KindString}, DefaultValue: "untitled"},
+			"effort":             {Type: workitem.SimpleType{Kind: workitem.KindFloat}},
+		},
+	}
+	require.Nil(t, child.CheckCompatibleWithParent(parent))
+
+	// Test incompatible kind change
+	child.Fields[workitem.SystemTitle] = workitem.FieldDefinition{Label: "Title", Type: workitem.SimpleType{Kind: workitem.KindMarkup}}
+	err := child.CheckCompatibleWithParent(parent)
+	require.IsType(t, errors.BadParameterError{}, err)
+	require.Contains(t, err.Error(), workitem.SystemTitle)
+
+	// Test incompatible component kind change of a list
+	child.Fields[workitem.SystemTitle] = parent.Fields[workitem.SystemTitle]
+	child.Fields["labels"] = workitem.FieldDefinition{Type: workitem.ListType{
+		SimpleType:    workitem.SimpleType{Kind: workitem.KindList},
+		ComponentType: workitem.SimpleType{Kind: workitem.KindInteger},
+	}}
+	err = child.CheckCompatibleWithParent(parent)
+	require.IsType(t, errors.BadParameterError{}, err)
+	require.Contains(t, err.Error(), "labels")
+}
+
+func TestWorkItemTypeCheckFieldCount(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	wit := workitem.WorkItemType{Fields: workitem.FieldDefinitions{}}
+	wit.Fields[workitem.SystemTitle] = workitem.FieldDefinition{Type: workitem.SimpleType{Kind: workitem.KindString}}
+	for i := 1; i < workitem.DefaultMaxFieldCount; i++ {
+		wit.Fields[fmt.Sprintf("field%d", i)] = workitem.FieldDefinition{Type: workitem.SimpleType{Kind: workitem.KindString}}
+	}
+
+	// Test exactly at the limit, counting the system field
+	require.Len(t, wit.Fields, workitem.DefaultMaxFieldCount)
+	require.Nil(t, wit.CheckFieldCount(workitem.DefaultMaxFieldCount))
+
+	// Test one field over the limit
+	wit.Fields["one too many"] = workitem.FieldDefinition{Type: workitem.SimpleType{Kind: workitem.KindString}}
+	err := wit.CheckFieldCount(workitem.DefaultMaxFieldCount)
+	require.IsType(t, errors.BadParameterError{}, err)
+}
+
+func TestWorkItemTypeUserFields(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	wit := workitem.WorkItemType{}
+	fields := map[string]interface{}{
+		workitem.SystemTitle:   "title",
+		workitem.SystemCreator: "jdoe",
+		"effort":               5,
+		"systematic":           true,
+	}
+
+	// Test system fields are removed and user fields preserved
+	userFields := wit.UserFields(fields)
+	require.Equal(t, map[string]interface{}{"effort": 5, "systematic": true}, userFields)
+
+	// Test the original map is unmodified
+	require.Len(t, fields, 4)
+	require.Equal(t, "title", fields[workitem.SystemTitle])
+	userFields["effort"] = 8
+	require.Equal(t, 5, fields["effort"])
+}
+
+func TestWorkItemTypeCheckHasRequiredSystemFields(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	stringType := workitem.SimpleType{Kind: workitem.KindString}
+
+	// Test complete type
+	wit := workitem.WorkItemType{
+		Fields: workitem.FieldDefinitions{
+			workitem.SystemTitle: {Type: stringType},
+			workitem.SystemState: {Type: stringType},
+			"effort":             {Type: workitem.SimpleType{Kind: workitem.KindFloat}},
+		},
+	}
+	require.Nil(t, wit.CheckHasRequiredSystemFields())
+
+	// Test type missing the title
+	delete(wit.Fields, workitem.SystemTitle)
+	err := wit.CheckHasRequiredSystemFields()
+	require.IsType(t, errors.BadParameterError{}, err)
+	require.Contains(t, err.Error(), workitem.SystemTitle)
+	require.NotContains(t, err.Error(), workitem.SystemState)
+
+	// Test empty field set
+	wit.Fields = workitem.FieldDefinitions{}
+	err = wit.CheckHasRequiredSystemFields()
+	require.IsType(t, errors.BadParameterError{}, err)
+	require.Contains(t, err.Error(), workitem.SystemTitle)
+	require.Contains(t, err.Error(), workitem.SystemState)
+}
+
+func TestWorkItemTypeCheckFieldKinds(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	stringType := workitem.SimpleType{Kind: workitem.KindString}
+
+	// Test all-supported kinds
+	wit := workitem.WorkItemType{
+		Fields: workitem.FieldDefinitions{
+			workitem.SystemTitle: {Type: stringType},
+			workitem.SystemState: {Type: workitem.EnumType{SimpleType: workitem.SimpleType{Kind: workitem.KindEnum}, BaseType: stringType, Values: []interface{}{"new"}}},
+			"labels":             {Type: workitem.ListType{SimpleType: workitem.SimpleType{Kind: workitem.KindList}, ComponentType: stringType}},
+		},
+	}
+	require.Nil(t, wit.CheckFieldKinds())
+	require.True(t, workitem.SupportedFieldKinds.IsSupported("codebase"))
+	require.False(t, workitem.SupportedFieldKinds.IsSupported("foo"))
+
+	// Test type with unknown kinds
+	wit.Fields["effort"] = workitem.FieldDefinition{Type: workitem.SimpleType{Kind: "decimal"}}
+	wit.Fields["tags"] = workitem.FieldDefinition{Type: workitem.ListType{SimpleType: workitem.SimpleType{Kind: workitem.KindList}, ComponentType: workitem.SimpleType{Kind: "tag"}}}
+	err := wit.CheckFieldKinds()
+	require.IsType(t, errors.BadParameterError{}, err)
+	require.Contains(t, err.Error(), "decimal, tag")
+}
+
+func TestWorkItemTypeCheckFieldKeyUniqueness(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	stringType := workitem.SimpleType{Kind: workitem.KindString}
+
+	// Test clean set of keys
+	wit := workitem.WorkItemType{
+		Fields: workitem.FieldDefinitions{
+			workitem.SystemTitle: {Type: stringType},
+			workitem.SystemState: {Type: stringType},
+			"priority":           {Type: stringType},
+			"effort":             {Type: workitem.SimpleType{Kind: workitem.KindFloat}},
+		},
+	}
+	require.Nil(t, wit.CheckFieldKeyUniqueness())
+
+	// Test keys that only differ in case
+	wit.Fields["Priority"] = workitem.FieldDefinition{Type: stringType}
+	err := wit.CheckFieldKeyUniqueness()
+	require.IsType(t, errors.BadParameterError{}, err)
+	require.Contains(t, err.Error(), "Priority/priority")
+	require.NotContains(t, err.Error(), "effort")
+}
+
+func TestWorkItemTypeValidStates(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	stringType := workitem.SimpleType{Kind: workitem.KindString}
+
+	// Test type with a state enum
+	wit := workitem.WorkItemType{
+		Fields: workitem.FieldDefinitions{
+			workitem.SystemTitle: {Type: stringType},
+			workitem.SystemState: {
+				Type: workitem.EnumType{
+					SimpleType: workitem.SimpleType{Kind: workitem.KindEnum},
+					BaseType:   stringType,
+					Values:     []interface{}{workitem.SystemStateNew, workitem.SystemStateOpen, workitem.SystemStateClosed},
+				},
+			},
+		},
+	}
+	states, err := wit.ValidStates()
+	require.Nil(t, err)
+	require.Equal(t, []string{workitem.SystemStateNew, workitem.SystemStateOpen, workitem.SystemStateClosed}, states)
+
+	// Test type with a state field that is not an enum
+	wit.Fields[workitem.SystemState] = workitem.FieldDefinition{Type: stringType}
+	_, err = wit.ValidStates()
+	require.IsType(t, errors.BadParameterError{}, err)
+
+	// Test type without a state field
+	delete(wit.Fields, workitem.SystemState)
+	_, err = wit.ValidStates()
+	require.IsType(t, errors.BadParameterError{}, err)
+}
+
+func TestWorkItemTypeCheckStateTransition(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	stateDef := workitem.FieldDefinition{
+		Type: workitem.EnumType{
+			SimpleType: workitem.SimpleType{Kind: workitem.KindEnum},
+			BaseType:   workitem.SimpleType{Kind: workitem.KindString},
+			Values:     []interface{}{workitem.SystemStateNew, workitem.SystemStateInProgress, workitem.SystemStateClosed},
+		},
+	}
+
+	// Test type without state transitions allows all changes
+	wit := workitem.WorkItemType{
+		Fields: workitem.FieldDefinitions{workitem.SystemState: stateDef},
+	}
+	require.Nil(t, wit.CheckStateTransition(workitem.SystemStateClosed, workitem.SystemStateInProgress))
+
+	// Test allowed transition
+	stateDef.StateTransitions = map[string][]string{
+		workitem.SystemStateNew:        {workitem.SystemStateInProgress, workitem.SystemStateClosed},
+		workitem.SystemStateInProgress: {workitem.SystemStateClosed},
+		workitem.SystemStateClosed:     {workitem.SystemStateNew},
+	}
+	require.Nil(t, stateDef.ValidateStateTransitions())
+	wit.Fields[workitem.SystemState] = stateDef
+	require.Nil(t, wit.CheckStateTransition(workitem.SystemStateNew, workitem.SystemStateInProgress))
+	require.Nil(t, wit.CheckStateTransition(workitem.SystemStateClosed, workitem.SystemStateClosed))
+
+	// Test disallowed transition
+	err := wit.CheckStateTransition(workitem.SystemStateClosed, workitem.SystemStateInProgress)
+	require.IsType(t, errors.BadParameterError{}, err)
+	require.Contains(t, err.Error(), workitem.SystemStateInProgress)
+
+	// Test transitions are part of the equality of types
+	other := workitem.WorkItemType{
+		Fields: workitem.FieldDefinitions{workitem.SystemState: stateDef},
+	}
+	require.True(t, wit.Equal(other))
+	otherDef := stateDef
+	otherDef.StateTransitions = nil
+	other.Fields[workitem.SystemState] = otherDef
+	require.False(t, wit.Equal(other))
+
+	// Test transition to a value that is not an enum value
+	stateDef.StateTransitions = map[string][]string{workitem.SystemStateNew: {"foo"}}
+	require.IsType(t, errors.BadParameterError{}, stateDef.ValidateStateTransitions())
+}
+
+func TestWorkItemTypeNewWorkItem(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	stringType := workitem.SimpleType{Kind: workitem.KindString}
+	wit := workitem.WorkItemType{
+		Fields: workitem.FieldDefinitions{
+			workitem.SystemTitle:     {Required: true, Type: stringType},
+			workitem.SystemCreator:   {Type: workitem.SimpleType{Kind: workitem.KindUser}},
+			workitem.SystemCreatedAt: {Type: workitem.SimpleType{Kind: workitem.KindInstant}},
+			workitem.SystemState: {
+				Required:     true,
+				DefaultValue: workitem.SystemStateNew,
+				Type: workitem.EnumType{
+					SimpleType: workitem.SimpleType{Kind: workitem.KindEnum},
+					BaseType:   stringType,
+					Values:     []interface{}{workitem.SystemStateNew, workitem.SystemStateClosed},
+				},
+			},
+			"effort":    {Type: workitem.SimpleType{Kind: workitem.KindFloat}},
+			"flagged":   {Type: workitem.SimpleType{Kind: workitem.KindBoolean}, DefaultValue: true},
+			"component": {Type: stringType},
+		},
+	}
+
+	fields, err := wit.NewWorkItem()
+	require.Nil(t, err)
+
+	// Test defaults are applied
+	require.Equal(t, workitem.SystemStateNew, fields[workitem.SystemState])
+	require.Equal(t, true, fields["flagged"])
+
+	// Test zero values for optional fields without default
+	require.Equal(t, 0.0, fields["effort"])
+	require.Equal(t, "", fields["component"])
+
+	// Test required field without default is present but nil
+	title, ok := fields[workitem.SystemTitle]
+	require.True(t, ok)
+	require.Nil(t, title)
+
+	// Test system-managed fields are absent
+	require.NotContains(t, fields, workitem.SystemCreator)
+	require.NotContains(t, fields, workitem.SystemCreatedAt)
+
+	// Test invalid default value
+	def := wit.Fields[workitem.SystemState]
+	def.DefaultValue = "foo"
+	wit.Fields[workitem.SystemState] = def
+	_, err = wit.NewWorkItem()
+	require.IsType(t, errors.BadParameterError{}, errs.Cause(err))
+}
+
+// staticMembership is a SpaceMembershipRepository with a fixed set of members
+type staticMembership map[uuid.UUID][]uuid.UUID
+
+func (m staticMembership) IsMember(ctx context.Context, spaceID uuid.UUID, identityID uuid.UUID) (bool, error) {
+	for _, member := range m[spaceID] {
+		if uuid.Equal(member, identityID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestWorkItemTypeValidateAssignees(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	ctx := context.Background()
+	spaceID := uuid.NewV4()
+	alice := uuid.NewV4()
+	bob := uuid.NewV4()
+	stranger := uuid.NewV4()
+	repo := staticMembership{spaceID: {alice, bob}}
+	wit := workitem.WorkItemType{Name: "bug"}
+
+	// Test all assignees are members
+	assert.Nil(t, wit.ValidateAssignees(ctx, []uuid.UUID{alice, bob}, spaceID, repo))
+
+	// Test a non-member is listed
+	err := wit.ValidateAssignees(ctx, []uuid.UUID{alice, stranger}, spaceID, repo)
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(err))
+	assert.Contains(t, err.Error(), stranger.String())
+	assert.NotContains(t, err.Error(), alice.String())
+
+	// Test members of another space are not members
+	err = wit.ValidateAssignees(ctx, []uuid.UUID{alice}, uuid.NewV4(), repo)
+	assert.IsType(t, errors.BadParameterError{}, errs.Cause(err))
+
+	// Test empty list
+	assert.Nil(t, wit.ValidateAssignees(ctx, nil, spaceID, repo))
+	assert.Nil(t, wit.ValidateAssignees(ctx, []uuid.UUID{}, spaceID, repo))
+}
+
+func TestWorkItemTypeCheckValidForCreation(t *testing.T) {
+	t.Parallel()
+	resource.Require(t, resource.UnitTest)
+
+	wit := workitem.WorkItemType{
+		Name: "foo",
+		Fields: workitem.FieldDefinitions{
+			workitem.SystemTitle: {Type: workitem.SimpleType{Kind: workitem.KindString}, Required: true},
+			workitem.SystemState: {Type: workitem.SimpleType{Kind: workitem.KindString}, Required: true},
+			"effort":             {Type: workitem.SimpleType{Kind: workitem.KindFloat}, Unit: "hours"},
+		},
+	}
+
+	// Test a valid type
+	require.Nil(t, wit.CheckValidForCreation(workitem.DefaultMaxFieldCount))
+
+	// Test an invalid field definition
+	wit.Fields["estimate"] = workitem.FieldDefinition{Type: workitem.SimpleType{Kind: workitem.KindString}, Unit: "hours"}
+	err := wit.CheckValidForCreation(workitem.DefaultMaxFieldCount)
+	require.IsType(t, errors.BadParameterError{}, errs.Cause(err))
+	delete(wit.Fields, "estimate")
+
+	// Test a missing mandatory system field
+	delete(wit.Fields, workitem.SystemState)
+	err = wit.CheckValidForCreation(workitem.DefaultMaxFieldCount)
+	require.IsType(t, errors.BadParameterError{}, errs.Cause(err))
+	require.Contains(t, err.Error(), workitem.SystemState)
+}
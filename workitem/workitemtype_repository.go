@@ -9,6 +9,7 @@ import (
 	"github.com/almighty/almighty-core/app"
 	"github.com/almighty/almighty-core/errors"
 	"github.com/almighty/almighty-core/log"
+	"github.com/almighty/almighty-core/space"
 
 	"github.com/jinzhu/gorm"
 	errs "github.com/pkg/errors"
@@ -17,21 +18,46 @@ import (
 
 var cache = NewWorkItemTypeCache()
 
+// WorkItemTypeLoader loads a work item type from the underlying storage
+type WorkItemTypeLoader interface {
+	LoadTypeFromDB(ctx context.Context, id uuid.UUID) (*WorkItemType, error)
+}
+
 // WorkItemTypeRepository encapsulates storage & retrieval of work item types
 type WorkItemTypeRepository interface {
 	Load(ctx context.Context, id uuid.UUID) (*app.WorkItemTypeSingle, error)
 	Create(ctx context.Context, id *uuid.UUID, extendedTypeID *uuid.UUID, name string, description *string, icon string, fields map[string]app.FieldDefinition) (*app.WorkItemTypeSingle, error)
 	List(ctx context.Context, start *int, length *int) (*app.WorkItemTypeList, error)
+	IsLeafType(ctx context.Context, typeID uuid.UUID, spaceID uuid.UUID) (bool, error)
+	ListDefiningField(ctx context.Context, fieldKey string) ([]WorkItemType, error)
+	Delete(ctx context.Context, id uuid.UUID) error
 }
 
 // NewWorkItemTypeRepository creates a wi type repository based on gorm
 func NewWorkItemTypeRepository(db *gorm.DB) *GormWorkItemTypeRepository {
-	return &GormWorkItemTypeRepository{db}
+	return NewWorkItemTypeRepositoryWithMaxFieldCount(db, DefaultMaxFieldCount)
+}
+
+// NewWorkItemTypeRepositoryWithMaxFieldCount creates a wi type repository
+// based on gorm that creates work item types with at most maxFieldCount
+// fields. Values smaller than 1 fall back to DefaultMaxFieldCount.
+func NewWorkItemTypeRepositoryWithMaxFieldCount(db *gorm.DB, maxFieldCount int) *GormWorkItemTypeRepository {
+	if maxFieldCount < 1 {
+		maxFieldCount = DefaultMaxFieldCount
+	}
+	return &GormWorkItemTypeRepository{db: db, maxFieldCount: maxFieldCount}
 }
 
 // GormWorkItemTypeRepository implements WorkItemTypeRepository using gorm
 type GormWorkItemTypeRepository struct {
-	db *gorm.DB
+	db            *gorm.DB
+	maxFieldCount int
+}
+
+// MaxFieldCount returns the maximum number of fields of the work item types
+// created by this repository
+func (r *GormWorkItemTypeRepository) MaxFieldCount() int {
+	return r.maxFieldCount
 }
 
 // Load returns the work item for the given id
@@ -111,16 +137,10 @@ func (r *GormWorkItemTypeRepository) Create(ctx context.Context, id *uuid.UUID,
 	// now process new fields, checking whether they are ok to add.
 	for field, definition := range fields {
 		existing, exists := allFields[field]
-		ct, err := convertFieldTypeToModels(*definition.Type)
+		converted, err := convertFieldDefinitionToModel(field, definition)
 		if err != nil {
 			return nil, errs.WithStack(err)
 		}
-		converted := FieldDefinition{
-			Label:       definition.Label,
-			Description: definition.Description,
-			Required:    definition.Required,
-			Type:        ct,
-		}
 		if exists && !compatibleFields(existing, converted) {
 			return nil, fmt.Errorf("incompatible change for field %s", field)
 		}
@@ -135,6 +155,10 @@ func (r *GormWorkItemTypeRepository) Create(ctx context.Context, id *uuid.UUID,
 		Icon:        icon,
 		Path:        path,
 		Fields:      allFields,
+		SpaceID:     space.SystemSpace,
+	}
+	if err := created.CheckValidForCreation(r.maxFieldCount); err != nil {
+		return nil, errs.WithStack(err)
 	}
 
 	if err := r.db.Create(&created).Error; err != nil {
@@ -177,6 +201,148 @@ func (r *GormWorkItemTypeRepository) List(ctx context.Context, start *int, limit
 	return result, nil
 }
 
+// IsLeafType returns true if no other work item type extends the work item
+// type with the given id; otherwise false is returned. Work item types are
+// currently shared by all spaces, so the space is only checked for existence.
+// returns NotFoundError or InternalError
+func (r *GormWorkItemTypeRepository) IsLeafType(ctx context.Context, typeID uuid.UUID, spaceID uuid.UUID) (bool, error) {
+	if _, err := space.NewRepository(r.db).Load(ctx, spaceID); err != nil {
+		return false, errs.WithStack(err)
+	}
+	if _, err := r.LoadTypeFromDB(ctx, typeID); err != nil {
+		return false, errs.WithStack(err)
+	}
+	count, err := r.countSubtypes(typeID)
+	if err != nil {
+		return false, errs.WithStack(err)
+	}
+	return count == 0, nil
+}
+
+// countSubtypes returns the number of direct and indirect subtypes of the
+// given work item type.
+// returns InternalError
+func (r *GormWorkItemTypeRepository) countSubtypes(typeID uuid.UUID) (int, error) {
+	// The lquery matches all paths that contain the type's ID followed by at
+	// least one more label, i.e. all direct and indirect subtypes.
+	var count int
+	query := "*." + LtreeSafeID(typeID) + ".*{1,}"
+	db := r.db.Model(&WorkItemType{}).Where("path ~ ?", query).Count(&count)
+	if db.Error != nil {
+		return 0, errors.NewInternalError(db.Error.Error())
+	}
+	return count, nil
+}
+
+// Delete soft-deletes the work item type with the given ID. A type that is
+// extended by other types or that still has work items can't be deleted.
+// returns NotFoundError, InUseError or InternalError
+func (r *GormWorkItemTypeRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	if _, err := r.LoadTypeFromDB(ctx, id); err != nil {
+		return errs.WithStack(err)
+	}
+	ok, count, err := CanDeleteType(ctx, id, NewWorkItemRepository(r.db))
+	if err != nil {
+		return errs.WithStack(err)
+	}
+	if !ok && count > 0 {
+		return errors.NewInUseError("work item type", id.String(), count, "work items")
+	}
+	if !ok {
+		subtypes, err := r.countSubtypes(id)
+		if err != nil {
+			return errs.WithStack(err)
+		}
+		return errors.NewInUseError("work item type", id.String(), subtypes, "subtypes")
+	}
+	db := r.db.Delete(&WorkItemType{ID: id})
+	if db.Error != nil {
+		return errors.NewInternalError(db.Error.Error())
+	}
+	if db.RowsAffected == 0 {
+		return errors.NewNotFoundError("work item type", id.String())
+	}
+	cache.Remove(id)
+	return nil
+}
+
+// CanDeleteType returns whether the given work item type has neither work
+// items nor subtypes left and can therefore be deleted, together with the
+// number of existing work items of the type.
+// returns InternalError
+func CanDeleteType(ctx context.Context, typeID uuid.UUID, repo WorkItemRepository) (bool, int, error) {
+	count, err := repo.CountByType(ctx, typeID)
+	if err != nil {
+		return false, 0, errs.WithStack(err)
+	}
+	if count > 0 {
+		return false, count, nil
+	}
+	subtypes, err := repo.CountSubtypes(ctx, typeID)
+	if err != nil {
+		return false, 0, errs.WithStack(err)
+	}
+	return subtypes == 0, count, nil
+}
+
+// ListDefiningField returns all work item types whose fields contain the
+// given field key, ordered by name.
+// returns InternalError
+func (r *GormWorkItemTypeRepository) ListDefiningField(ctx context.Context, fieldKey string) ([]WorkItemType, error) {
+	res := []WorkItemType{}
+	db := r.db.Where("fields -> ? IS NOT NULL", fieldKey).Order("name").Find(&res)
+	if db.Error != nil {
+		return nil, errors.NewInternalError(db.Error.Error())
+	}
+	return res, nil
+}
+
+// TypesDefiningField returns every work item type available in the given
+// space that defines a field with the given key, i.e. the types of the space
+// and the ones of the system space, which are shared by all spaces.
+// returns the errors of the given repository
+func TypesDefiningField(ctx context.Context, spaceID uuid.UUID, fieldKey string, repo WorkItemTypeRepository) ([]WorkItemType, error) {
+	wits, err := repo.ListDefiningField(ctx, fieldKey)
+	if err != nil {
+		return nil, errs.WithStack(err)
+	}
+	res := []WorkItemType{}
+	for _, wit := range wits {
+		if uuid.Equal(wit.SpaceID, space.SystemSpace) || uuid.Equal(wit.SpaceID, spaceID) {
+			res = append(res, wit)
+		}
+	}
+	return res, nil
+}
+
+// ResolveIcon returns the icon of the given work item type. If the type has
+// no icon of its own, the icon of the nearest ancestor on the type's path
+// that has one is returned, or DefaultIcon if there is none.
+// returns ConversionError or the errors of the given repository
+func ResolveIcon(ctx context.Context, wit WorkItemType, repo WorkItemTypeRepository) (string, error) {
+	if wit.Icon != "" {
+		return wit.Icon, nil
+	}
+	ancestors, err := pathIDs(wit.Path)
+	if err != nil {
+		return "", errs.WithStack(err)
+	}
+	// Skip the type itself and walk towards the root.
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		if uuid.Equal(ancestors[i], wit.ID) {
+			continue
+		}
+		ancestor, err := repo.Load(ctx, ancestors[i])
+		if err != nil {
+			return "", errs.WithStack(err)
+		}
+		if ancestor.Data.Attributes.Icon != "" {
+			return ancestor.Data.Attributes.Icon, nil
+		}
+	}
+	return DefaultIcon, nil
+}
+
 // compatibleFields returns true if the existing and new field are compatible;
 // otherwise false is returned. It does so by comparing all members of the field
 // definition except for the label and description.
@@ -187,6 +353,12 @@ func compatibleFields(existing FieldDefinition, new FieldDefinition) bool {
 	return reflect.DeepEqual(existing.Type, new.Type)
 }
 
+// ConvertTypeFromModel converts a work item type from model to REST
+// representation
+func ConvertTypeFromModel(t WorkItemType) app.WorkItemTypeData {
+	return convertTypeFromModels(&t)
+}
+
 // converts from models to app representation
 func convertTypeFromModels(t *WorkItemType) app.WorkItemTypeData {
 	id := t.ID
@@ -209,6 +381,36 @@ func convertTypeFromModels(t *WorkItemType) app.WorkItemTypeData {
 			Description: def.Description,
 			Type:        &ct,
 		}
+		if def.Unit != "" {
+			unit := def.Unit
+			converted.Attributes.Fields[name].Unit = &unit
+		}
+		if def.Deprecated {
+			deprecated := true
+			converted.Attributes.Fields[name].Deprecated = &deprecated
+		}
+		if def.Position != 0 {
+			position := def.Position
+			converted.Attributes.Fields[name].Position = &position
+		}
+		if def.Pattern != "" {
+			pattern := def.Pattern
+			converted.Attributes.Fields[name].Pattern = &pattern
+		}
+		if len(def.ValueLabels) > 0 {
+			converted.Attributes.Fields[name].ValueLabels = def.ValueLabels
+		}
+		if def.Precision != nil {
+			precision := *def.Precision
+			converted.Attributes.Fields[name].Precision = &precision
+		}
+		if def.Scale != nil {
+			scale := *def.Scale
+			converted.Attributes.Fields[name].Scale = &scale
+		}
+		if len(def.StateTransitions) > 0 {
+			converted.Attributes.Fields[name].StateTransitions = def.StateTransitions
+		}
 	}
 	return converted
 }
@@ -241,8 +443,7 @@ func convertAnyToKind(any interface{}) (*Kind, error) {
 
 func convertStringToKind(k string) (*Kind, error) {
 	kind := Kind(k)
-	switch kind {
-	case KindString, KindInteger, KindFloat, KindInstant, KindDuration, KindURL, KindWorkitemReference, KindUser, KindEnum, KindList, KindIteration, KindMarkup, KindArea, KindCodebase:
+	if SupportedFieldKinds.IsSupported(k) {
 		return &kind, nil
 	}
 	return nil, fmt.Errorf("Not a simple type")
@@ -290,17 +491,52 @@ func TEMPConvertFieldTypesToModel(fields map[string]app.FieldDefinition) (map[st
 
 	allFields := map[string]FieldDefinition{}
 	for field, definition := range fields {
-		ct, err := convertFieldTypeToModels(*definition.Type)
+		converted, err := convertFieldDefinitionToModel(field, definition)
 		if err != nil {
 			return nil, errs.WithStack(err)
 		}
-		converted := FieldDefinition{
-			Required:    definition.Required,
-			Label:       definition.Label,
-			Description: definition.Description,
-			Type:        ct,
+		if err := converted.Validate(); err != nil {
+			return nil, errs.WithStack(err)
 		}
 		allFields[field] = converted
 	}
 	return allFields, nil
 }
+
+// convertFieldDefinitionToModel converts the app representation of the field
+// with the given name into its model representation. The field is read-only
+// if IsReadOnlyByDefault says so.
+func convertFieldDefinitionToModel(name string, definition app.FieldDefinition) (FieldDefinition, error) {
+	ct, err := convertFieldTypeToModels(*definition.Type)
+	if err != nil {
+		return FieldDefinition{}, errs.WithStack(err)
+	}
+	converted := FieldDefinition{
+		Label:       definition.Label,
+		Description: definition.Description,
+		Required:    definition.Required,
+		ReadOnly:    IsReadOnlyByDefault(name),
+		Type:        ct,
+		Precision:   definition.Precision,
+		Scale:       definition.Scale,
+	}
+	if definition.Unit != nil {
+		converted.Unit = *definition.Unit
+	}
+	if definition.Deprecated != nil {
+		converted.Deprecated = *definition.Deprecated
+	}
+	if definition.Position != nil {
+		converted.Position = *definition.Position
+	}
+	if definition.Pattern != nil {
+		converted.Pattern = *definition.Pattern
+	}
+	if len(definition.ValueLabels) > 0 {
+		converted.ValueLabels = definition.ValueLabels
+	}
+	if len(definition.StateTransitions) > 0 {
+		converted.StateTransitions = definition.StateTransitions
+	}
+	return converted, nil
+}
@@ -8,13 +8,16 @@ import (
 	"testing"
 
 	"github.com/almighty/almighty-core/app"
+	"github.com/almighty/almighty-core/errors"
 	"github.com/almighty/almighty-core/gormsupport"
 	"github.com/almighty/almighty-core/gormsupport/cleaner"
 	"github.com/almighty/almighty-core/migration"
 	"github.com/almighty/almighty-core/models"
 	"github.com/almighty/almighty-core/resource"
+	"github.com/almighty/almighty-core/space"
 	"github.com/almighty/almighty-core/workitem"
 	"github.com/jinzhu/gorm"
+	errs "github.com/pkg/errors"
 	uuid "github.com/satori/go.uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -57,21 +60,35 @@ func (s *workItemTypeRepoBlackBoxTest) TearDownTest() {
 	s.clean()
 }
 
+// withSystemFields returns the given field definitions together with the
+// mandatory system fields that every root work item type must define.
+func withSystemFields(fields map[string]app.FieldDefinition) map[string]app.FieldDefinition {
+	stringType := app.FieldType{Kind: string(workitem.KindString)}
+	res := map[string]app.FieldDefinition{
+		workitem.SystemTitle: {Required: true, Type: &stringType},
+		workitem.SystemState: {Required: true, Type: &stringType},
+	}
+	for name, def := range fields {
+		res[name] = def
+	}
+	return res
+}
+
 func (s *workItemTypeRepoBlackBoxTest) TestCreateLoadWIT() {
 
-	wit, err := s.repo.Create(context.Background(), nil, nil, "foo_bar", nil, "fa-bomb", map[string]app.FieldDefinition{
+	wit, err := s.repo.Create(context.Background(), nil, nil, "foo_bar", nil, "fa-bomb", withSystemFields(map[string]app.FieldDefinition{
 		"foo": {
 			Required: true,
 			Type:     &app.FieldType{Kind: string(workitem.KindFloat)},
 		},
-	})
+	}))
 	require.Nil(s.T(), err)
 	require.NotNil(s.T(), wit)
 	require.NotNil(s.T(), wit.Data)
 	require.NotNil(s.T(), wit.Data.ID)
 
 	// Test that we can create a WIT with the same name as before.
-	wit3, err := s.repo.Create(context.Background(), nil, nil, "foo_bar", nil, "fa-bomb", map[string]app.FieldDefinition{})
+	wit3, err := s.repo.Create(context.Background(), nil, nil, "foo_bar", nil, "fa-bomb", withSystemFields(nil))
 	require.Nil(s.T(), err)
 	require.NotNil(s.T(), wit3)
 	require.NotNil(s.T(), wit3.Data)
@@ -93,7 +110,7 @@ func (s *workItemTypeRepoBlackBoxTest) TestCreateLoadWIT() {
 
 func (s *workItemTypeRepoBlackBoxTest) TestCreateLoadWITWithList() {
 	bt := "string"
-	wit, err := s.repo.Create(context.Background(), nil, nil, "foo_bar", nil, "fa-bomb", map[string]app.FieldDefinition{
+	wit, err := s.repo.Create(context.Background(), nil, nil, "foo_bar", nil, "fa-bomb", withSystemFields(map[string]app.FieldDefinition{
 		"foo": {
 			Required: true,
 			Type: &app.FieldType{
@@ -101,13 +118,13 @@ func (s *workItemTypeRepoBlackBoxTest) TestCreateLoadWITWithList() {
 				Kind:          string(workitem.KindList),
 			},
 		},
-	})
+	}))
 	require.Nil(s.T(), err)
 	require.NotNil(s.T(), wit)
 	require.NotNil(s.T(), wit.Data)
 	require.NotNil(s.T(), wit.Data.ID)
 
-	wit3, err := s.repo.Create(context.Background(), nil, nil, "foo_bar", nil, "fa-bomb", map[string]app.FieldDefinition{})
+	wit3, err := s.repo.Create(context.Background(), nil, nil, "foo_bar", nil, "fa-bomb", withSystemFields(nil))
 	require.Nil(s.T(), err)
 	require.NotNil(s.T(), wit3)
 	require.NotNil(s.T(), wit3.Data)
@@ -129,7 +146,7 @@ func (s *workItemTypeRepoBlackBoxTest) TestCreateLoadWITWithList() {
 func (s *workItemTypeRepoBlackBoxTest) TestCreateWITWithBaseType() {
 	bt := "string"
 	basetype := "foo.bar"
-	baseWit, err := s.repo.Create(context.Background(), nil, nil, basetype, nil, "fa-bomb", map[string]app.FieldDefinition{
+	baseWit, err := s.repo.Create(context.Background(), nil, nil, basetype, nil, "fa-bomb", withSystemFields(map[string]app.FieldDefinition{
 		"foo": {
 			Required: true,
 			Type: &app.FieldType{
@@ -137,7 +154,7 @@ func (s *workItemTypeRepoBlackBoxTest) TestCreateWITWithBaseType() {
 				Kind:          string(workitem.KindList),
 			},
 		},
-	})
+	}))
 	require.Nil(s.T(), err)
 	require.NotNil(s.T(), baseWit)
 	require.NotNil(s.T(), baseWit.Data)
@@ -158,3 +175,141 @@ func (s *workItemTypeRepoBlackBoxTest) TestDoNotCreateWITWithMissingBaseType() {
 	require.NotNil(s.T(), err)
 	require.Nil(s.T(), extendedWit)
 }
+
+func (s *workItemTypeRepoBlackBoxTest) TestIsLeafType() {
+	ctx := context.Background()
+	sp, err := space.NewRepository(s.DB).Create(ctx, &space.Space{Name: uuid.NewV4().String()})
+	require.Nil(s.T(), err)
+
+	// given a base type with a child and a grandchild
+	base, err := s.repo.Create(ctx, nil, nil, "test-leaf-base", nil, "fa-bomb", withSystemFields(nil))
+	require.Nil(s.T(), err)
+	child, err := s.repo.Create(ctx, nil, base.Data.ID, "test-leaf-child", nil, "fa-bomb", map[string]app.FieldDefinition{})
+	require.Nil(s.T(), err)
+	grandchild, err := s.repo.Create(ctx, nil, child.Data.ID, "test-leaf-grandchild", nil, "fa-bomb", map[string]app.FieldDefinition{})
+	require.Nil(s.T(), err)
+
+	// Check types with children
+	leaf, err := s.repo.IsLeafType(ctx, *base.Data.ID, sp.ID)
+	require.Nil(s.T(), err)
+	assert.False(s.T(), leaf)
+	leaf, err = s.repo.IsLeafType(ctx, *child.Data.ID, sp.ID)
+	require.Nil(s.T(), err)
+	assert.False(s.T(), leaf)
+
+	// Check leaf type
+	leaf, err = s.repo.IsLeafType(ctx, *grandchild.Data.ID, sp.ID)
+	require.Nil(s.T(), err)
+	assert.True(s.T(), leaf)
+
+	// Check unknown type and space
+	_, err = s.repo.IsLeafType(ctx, uuid.NewV4(), sp.ID)
+	require.NotNil(s.T(), err)
+	_, err = s.repo.IsLeafType(ctx, *grandchild.Data.ID, uuid.NewV4())
+	require.NotNil(s.T(), err)
+}
+
+func (s *workItemTypeRepoBlackBoxTest) TestResolveIcon() {
+	ctx := context.Background()
+	loader := workitem.NewWorkItemTypeRepository(s.DB)
+	// given a base type with an icon, a child and grandchild without one and
+	// a root type without an icon
+	base, err := s.repo.Create(ctx, nil, nil, "test-icon-base", nil, "fa-bomb", withSystemFields(nil))
+	require.Nil(s.T(), err)
+	child, err := s.repo.Create(ctx, nil, base.Data.ID, "test-icon-child", nil, "", map[string]app.FieldDefinition{})
+	require.Nil(s.T(), err)
+	grandchild, err := s.repo.Create(ctx, nil, child.Data.ID, "test-icon-grandchild", nil, "", map[string]app.FieldDefinition{})
+	require.Nil(s.T(), err)
+	root, err := s.repo.Create(ctx, nil, nil, "test-icon-root", nil, "", withSystemFields(nil))
+	require.Nil(s.T(), err)
+	resolve := func(id uuid.UUID) string {
+		wit, err := loader.LoadTypeFromDB(ctx, id)
+		require.Nil(s.T(), err)
+		icon, err := workitem.ResolveIcon(ctx, *wit, s.repo)
+		require.Nil(s.T(), err)
+		return icon
+	}
+
+	// Check type with its own icon
+	assert.Equal(s.T(), "fa-bomb", resolve(*base.Data.ID))
+
+	// Check types inheriting the icon of an ancestor
+	assert.Equal(s.T(), "fa-bomb", resolve(*child.Data.ID))
+	assert.Equal(s.T(), "fa-bomb", resolve(*grandchild.Data.ID))
+
+	// Check root type without icon
+	assert.Equal(s.T(), workitem.DefaultIcon, resolve(*root.Data.ID))
+}
+
+func (s *workItemTypeRepoBlackBoxTest) TestDoNotCreateWITWithInvalidEnum() {
+	stString := "string"
+	expectedMessages := map[string][]interface{}{
+		"at least one enum value":                        {},
+		"unique enum values, but new is given more than": {"new", "new"},
+	}
+	for expected, values := range expectedMessages {
+		wit, err := s.repo.Create(context.Background(), nil, nil, "test-invalid-enum", nil, "fa-bomb", withSystemFields(map[string]app.FieldDefinition{
+			"priority": {
+				Type: &app.FieldType{
+					BaseType: &stString,
+					Kind:     string(workitem.KindEnum),
+					Values:   values,
+				},
+			},
+		}))
+		require.NotNil(s.T(), err)
+		require.IsType(s.T(), errors.BadParameterError{}, errs.Cause(err))
+		require.Contains(s.T(), err.Error(), expected)
+		require.Nil(s.T(), wit)
+	}
+}
+
+func (s *workItemTypeRepoBlackBoxTest) TestTypesDefiningField() {
+	ctx := context.Background()
+	fieldKey := "test-types-defining-field"
+	withField := withSystemFields(map[string]app.FieldDefinition{
+		fieldKey: {Type: &app.FieldType{Kind: "string"}},
+	})
+	withoutField := withSystemFields(map[string]app.FieldDefinition{
+		"test-other-field": {Type: &app.FieldType{Kind: "string"}},
+	})
+	// given two types with the field (one of them inherits it) and one without
+	a, err := s.repo.Create(ctx, nil, nil, "test-defining-a", nil, "fa-bomb", withField)
+	require.Nil(s.T(), err)
+	b, err := s.repo.Create(ctx, nil, a.Data.ID, "test-defining-b", nil, "fa-bomb", withoutField)
+	require.Nil(s.T(), err)
+	_, err = s.repo.Create(ctx, nil, nil, "test-defining-c", nil, "fa-bomb", withoutField)
+	require.Nil(s.T(), err)
+	// and a type with the field in each of two spaces
+	createInSpace := func(name string, spaceID uuid.UUID) uuid.UUID {
+		id := uuid.NewV4()
+		wit := workitem.WorkItemType{ID: id, Name: name, Path: workitem.LtreeSafeID(id), Fields: workitem.FieldDefinitions{fieldKey: {Type: workitem.SimpleType{Kind: workitem.KindString}}}, SpaceID: spaceID}
+		require.Nil(s.T(), s.DB.Create(&wit).Error)
+		return id
+	}
+	spaceRepo := space.NewRepository(s.DB)
+	sp, err := spaceRepo.Create(ctx, &space.Space{Name: uuid.NewV4().String()})
+	require.Nil(s.T(), err)
+	otherSpace, err := spaceRepo.Create(ctx, &space.Space{Name: uuid.NewV4().String()})
+	require.Nil(s.T(), err)
+	d := createInSpace("test-defining-d", sp.ID)
+	createInSpace("test-defining-e", otherSpace.ID)
+
+	// Test types with the field
+	wits, err := workitem.TypesDefiningField(ctx, sp.ID, fieldKey, s.repo)
+	require.Nil(s.T(), err)
+	require.Len(s.T(), wits, 3)
+	assert.Equal(s.T(), *a.Data.ID, wits[0].ID)
+	assert.Equal(s.T(), *b.Data.ID, wits[1].ID)
+	assert.Equal(s.T(), d, wits[2].ID)
+
+	// Test types with the field in the system space
+	wits, err = workitem.TypesDefiningField(ctx, space.SystemSpace, fieldKey, s.repo)
+	require.Nil(s.T(), err)
+	require.Len(s.T(), wits, 2)
+
+	// Test unknown field
+	wits, err = workitem.TypesDefiningField(ctx, sp.ID, "test-unknown-field", s.repo)
+	require.Nil(s.T(), err)
+	assert.Empty(s.T(), wits)
+}
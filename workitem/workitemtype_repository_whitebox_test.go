@@ -188,7 +188,7 @@ func TestTempConvertFieldsToModels(t *testing.T) {
 		},
 	}
 
-	expectedJSON := `{"system.creator":{"Required":true,"Label":"l3","Description":"d3","Type":{"Kind":"user"}},"system.description":{"Required":false,"Label":"l2","Description":"d2","Type":{"Kind":"string"}},"system.remote_item_id":{"Required":false,"Label":"l4","Description":"d4","Type":{"Kind":"string"}},"system.state":{"Required":true,"Label":"l5","Description":"d5","Type":{"Kind":"enum","BaseType":{"Kind":"string"},"Values":["new","open","in progress","resolved","closed"]}},"system.title":{"Required":true,"Label":"l1","Description":"d1","Type":{"Kind":"string"}}}`
+	expectedJSON := `{"system.creator":{"Required":true,"ReadOnly":true,"Label":"l3","Description":"d3","Type":{"Kind":"user"}},"system.description":{"Required":false,"ReadOnly":false,"Label":"l2","Description":"d2","Type":{"Kind":"string"}},"system.remote_item_id":{"Required":false,"ReadOnly":true,"Label":"l4","Description":"d4","Type":{"Kind":"string"}},"system.state":{"Required":true,"ReadOnly":false,"Label":"l5","Description":"d5","Type":{"Kind":"enum","BaseType":{"Kind":"string"},"Values":["new","open","in progress","resolved","closed"]}},"system.title":{"Required":true,"ReadOnly":false,"Label":"l1","Description":"d1","Type":{"Kind":"string"}}}`
 
 	convertedFields, err := TEMPConvertFieldTypesToModel(newFields)
 	jsonArray, err := json.Marshal(convertedFields)